@@ -2,32 +2,71 @@ package main
 
 import (
 	"context"
-	"log"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	// Schedule timezones must resolve in images without a zoneinfo database
+	_ "time/tzdata"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/adapters/audit"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/adapters/events"
 	"github.com/AchilleasB/baby-kliniek/media-service/internal/adapters/handler"
 	"github.com/AchilleasB/baby-kliniek/media-service/internal/adapters/middleware"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/adapters/notification"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/adapters/openapi"
 	"github.com/AchilleasB/baby-kliniek/media-service/internal/adapters/repository"
 	"github.com/AchilleasB/baby-kliniek/media-service/internal/config"
 	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/services"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/health"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/keys"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/lifecycle"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/logging"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/metrics"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/tracing"
 	"github.com/redis/go-redis/v9"
 )
 
 func main() {
 
 	cfg := config.Load()
+	slog.SetDefault(logging.New(os.Stdout, cfg.LogLevel))
 	ctx := context.Background()
 
+	switch cfg.AuthMode {
+	case "standard":
+		// Without them a token the identity service minted for any other service
+		// would be accepted here
+		if len(cfg.JWTIssuers) == 0 || cfg.JWTAudience == "" {
+			fatal("refusing to start", errors.New("JWT_ISSUERS and JWT_AUDIENCE must be set unless AUTH_MODE=dev"))
+		}
+	case "dev":
+		// Dev mode accepts any token signed with a key written to local disk, so it
+		// must never be reachable in production
+		if cfg.AppEnv == "production" {
+			fatal("refusing to start", errors.New("AUTH_MODE=dev is not allowed when APP_ENV=production"))
+		}
+	default:
+		fatal("invalid auth configuration", fmt.Errorf("unknown AUTH_MODE %q", cfg.AuthMode))
+	}
+
+	traceExporter := newTraceExporter(cfg)
+	tracing.SetExporter(traceExporter)
+
 	mongoClient, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURI))
 	if err != nil {
-		log.Fatalf("failed to open database: %v", err)
+		fatal("failed to open database", err)
 	}
-	defer mongoClient.Disconnect(ctx)
 
-	mongoRepo := repository.NewMongoRepository(mongoClient)
+	mongoRepo := repository.NewInstrumentedRepository(repository.NewMongoRepository(mongoClient))
+	revisionRepo := repository.NewInstrumentedRevisionRepository(repository.NewMongoRevisionRepository(mongoClient))
+	apiKeyRepo := repository.NewInstrumentedAPIKeyRepository(repository.NewMongoAPIKeyRepository(mongoClient))
 
 	redisClient := redis.NewClient(&redis.Options{
 		Addr:     cfg.RedisAddress,
@@ -36,16 +75,136 @@ func main() {
 	})
 
 	if err := redisClient.Ping(ctx).Err(); err != nil {
-		log.Fatalf("failed to connect to redis: %v", err)
+		fatal("failed to connect to redis", err)
+	}
+	slog.Info("authenticated with redis")
+
+	var keyProvider keys.Provider
+	var jwksProvider *keys.JWKSProvider
+	var fileProvider *keys.FileProvider
+	switch {
+	case cfg.AuthMode == "dev":
+		keyProvider, err = keys.GenerateDevKey(cfg.DevSigningKeyPath)
+		if err != nil {
+			fatal("failed to generate dev signing key", err)
+		}
+		slog.Warn("AUTH_MODE=dev: accepting tokens signed with an ephemeral key, mint them with go run ./cmd/mintjwt",
+			"key_path", cfg.DevSigningKeyPath,
+			"app_env", cfg.AppEnv,
+		)
+	case cfg.JWKSURL != "":
+		jwksProvider = keys.NewJWKSProvider(cfg.JWKSURL, cfg.JWKSRefreshInterval, cfg.JWKSKeyGracePeriod)
+		jwksProvider.Start(ctx)
+		keyProvider = jwksProvider
+	default:
+		fileProvider, err = keys.NewFileProvider(cfg.PublicKeyPath, cfg.KeyReloadInterval, cfg.KeyRotationOverlap)
+		if err != nil {
+			fatal("failed to load public key", err)
+		}
+		fileProvider.Start()
+		keyProvider = fileProvider
+	}
+
+	failurePolicy, err := middleware.ParseFailurePolicy(cfg.RevocationFailurePolicy)
+	if err != nil {
+		fatal("invalid revocation configuration", err)
+	}
+	revocations := middleware.NewRevocationChecker(redisClient, middleware.RevocationConfig{
+		FailurePolicy:         failurePolicy,
+		BreakerThreshold:      cfg.RevocationBreakerThreshold,
+		BreakerCooldown:       cfg.RevocationBreakerCooldown,
+		NegativeCacheTTL:      cfg.RevocationNegativeCacheTTL,
+		NegativeCacheCapacity: cfg.AuthCacheCapacity,
+		Channel:               cfg.RevocationChannel,
+		WatermarkTTL:          cfg.RevocationWatermarkTTL,
+		SkipCachedLookups:     cfg.RevocationSkipCachedLookup,
+	})
+
+	authMiddleware := middleware.NewAuthMiddleware(keyProvider, revocations, middleware.TokenPolicy{
+		Issuers:    cfg.JWTIssuers,
+		Audience:   cfg.JWTAudience,
+		Leeway:     cfg.JWTLeeway,
+		MaxAge:     cfg.JWTMaxAge,
+		Algorithms: cfg.JWTAllowedAlgorithms,
+	}, middleware.CacheConfig{
+		Capacity: cfg.AuthCacheCapacity,
+		Shards:   cfg.AuthCacheShards,
+	})
+	if fileProvider != nil {
+		fileProvider.OnRotate(authMiddleware.FlushCache)
+	}
+	if cfg.ServiceIdentitiesFile != "" {
+		if cfg.TLSClientCAFile == "" {
+			fatal("invalid TLS configuration", errors.New("SERVICE_IDENTITIES_FILE requires TLS_CLIENT_CA_FILE"))
+		}
+		serviceIdentities, err := middleware.LoadServiceIdentities(cfg.ServiceIdentitiesFile)
+		if err != nil {
+			fatal("failed to load service identities", err)
+		}
+		authMiddleware.TrustServices(serviceIdentities)
+		slog.Info("trusting client certificates for service identities", "services", len(serviceIdentities.Services))
+	}
+	revocations.Subscribe()
+
+	accessPolicy := services.DefaultPolicy()
+	if cfg.PolicyFile != "" {
+		accessPolicy, err = services.LoadPolicy(cfg.PolicyFile)
+		if err != nil {
+			fatal("failed to load access policy", err)
+		}
+	}
+	policyEngine, err := services.NewPolicyEngine(accessPolicy)
+	if err != nil {
+		fatal("invalid access policy", err)
+	}
+	slog.Info("access policy loaded", "file", cfg.PolicyFile, "rules", len(accessPolicy.Rules))
+
+	// Audit events go to the hash-chained trail in MongoDB and to the log pipeline
+	auditTrail := audit.NewMongoAuditLog(mongoClient)
+	auditLog := audit.NewMultiAuditLog(auditTrail, audit.NewLogAuditLog(slog.Default()))
+	if cfg.ImpersonationEnabled {
+		authMiddleware.AllowImpersonation(middleware.NewImpersonation(redisClient, auditLog))
+		slog.Info("impersonation enabled", "header", middleware.ActAsHeader)
 	}
-	log.Println("Authenticated with Redis successfully")
 
-	authMiddleware := middleware.NewAuthMiddleware(cfg.JWTPublicKey, redisClient)
+	notifier := notification.NewRedisNotifier(redisClient, cfg.NotificationStream)
+	eventPublisher := events.NewRedisEventPublisher(redisClient, cfg.EventStream)
+	mediaService := services.NewVideoService(mongoRepo, revisionRepo, policyEngine, auditLog, notifier, eventPublisher)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo)
+	authMiddleware.AcceptAPIKeys(apiKeyService)
 
-	mediaService := services.NewVideoService(mongoRepo)
+	apiSpec, err := openapi.Load()
+	if err != nil {
+		fatal("failed to load OpenAPI document", err)
+	}
+	// Response validation is only enabled in test mode
+	openAPIValidator := middleware.NewOpenAPIValidator(apiSpec, cfg.AppEnv == "test", cfg.MaxRequestBodyBytes)
 
 	mediaHandler := handler.NewMediaHandler(mediaService)
-	healthHandler := handler.NewHealthHandler(mongoClient)
+	policyHandler := handler.NewPolicyHandler(policyEngine, mediaService, auditLog)
+	authAdminHandler := handler.NewAuthAdminHandler(authMiddleware, revocations, auditLog)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService, auditLog)
+	auditHandler := handler.NewAuditHandler(auditTrail, auditLog)
+	healthRegistry := health.NewRegistry(cfg.HealthCheckInterval)
+	healthRegistry.Register("database", true, cfg.HealthCheckTimeout, health.MongoCheck(mongoClient))
+	if jwksProvider != nil {
+		healthRegistry.Register("jwks", true, cfg.HealthCheckTimeout, jwksProvider.Check)
+	}
+	if fileProvider != nil {
+		healthRegistry.Register("public_key", true, cfg.HealthCheckTimeout, fileProvider.Check)
+	}
+	// Redis only backs token revocation; losing it degrades the service rather than taking it out of rotation
+	healthRegistry.Register("redis", false, cfg.HealthCheckTimeout, health.RedisCheck(redisClient))
+	healthRegistry.Register("revocation", false, cfg.HealthCheckTimeout, revocations.Check)
+	healthRegistry.Register("memory", false, cfg.HealthCheckTimeout, health.MemoryCheck())
+	healthRegistry.Register("auth_cache", false, cfg.HealthCheckTimeout, authMiddleware.CacheCheck)
+	if cfg.BlobStorageHealthURL != "" {
+		healthRegistry.Register("blob_storage", false, cfg.HealthCheckTimeout, health.HTTPCheck(cfg.BlobStorageHealthURL))
+	}
+	healthRegistry.Start()
+
+	healthHandler := handler.NewHealthHandler(healthRegistry)
+	docsHandler := handler.NewDocsHandler(openapi.Document(), openapi.DocsPage())
 
 	mux := http.NewServeMux()
 
@@ -53,25 +212,161 @@ func main() {
 	mux.HandleFunc("/health", healthHandler.Health)
 	mux.HandleFunc("/health/ready", healthHandler.Ready)
 	mux.HandleFunc("/health/live", healthHandler.Live)
+	mux.HandleFunc("/health/startup", healthHandler.Startup)
+
+	// Prometheus metrics
+	mux.Handle("GET /metrics", metrics.Handler())
+
+	// API documentation
+	mux.HandleFunc("GET /openapi.json", docsHandler.Spec)
+	mux.HandleFunc("GET /docs", docsHandler.UI)
 
 	// API endpoints
-	mux.Handle("GET /media/videos",
-		authMiddleware.RequireRole([]string{"ADMIN", "PARENT"}, http.HandlerFunc(mediaHandler.GetVideos)),
-	)
+	// VideoService authorizes against the access policy, so these only authenticate
+	mux.Handle("GET /media/videos", authMiddleware.Authenticate(mediaHandler.GetVideos))
+	mux.Handle("GET /media/videos/{id}", authMiddleware.Authenticate(mediaHandler.GetOneVideo))
+	mux.Handle("POST /media/videos", authMiddleware.Authenticate(mediaHandler.CreateVideo))
+	mux.Handle("PATCH /media/videos/{id}", authMiddleware.Authenticate(mediaHandler.UpdateVideo))
+	mux.Handle("DELETE /media/videos/{id}", authMiddleware.Authenticate(mediaHandler.DeleteVideo))
+	mux.Handle("GET /media/videos/{id}/revisions", authMiddleware.Authenticate(mediaHandler.ListRevisions))
+	mux.Handle("POST /media/videos/{id}/revisions/{rev}/restore", authMiddleware.Authenticate(mediaHandler.RestoreRevision))
+	mux.Handle("POST /media/videos/{id}/transitions", authMiddleware.Authenticate(mediaHandler.TransitionVideo))
 
-	mux.Handle("GET /media/videos/{id}",
-		authMiddleware.RequireRole([]string{"ADMIN", "PARENT"}, http.HandlerFunc(mediaHandler.GetOneVideo)),
+	// Incident response
+	mux.Handle("POST /auth/revocations/tokens",
+		authMiddleware.RequireRole([]string{"ADMIN"}, http.HandlerFunc(authAdminHandler.RevokeToken)),
+	)
+	mux.Handle("POST /auth/revocations/subjects",
+		authMiddleware.RequireRole([]string{"ADMIN"}, http.HandlerFunc(authAdminHandler.RevokeSubject)),
+	)
+	mux.Handle("GET /auth/principals",
+		authMiddleware.RequireRole([]string{"ADMIN"}, http.HandlerFunc(authAdminHandler.ListPrincipals)),
 	)
 
-	mux.Handle("POST /media/videos",
-		authMiddleware.RequireRole([]string{"ADMIN"}, http.HandlerFunc(mediaHandler.CreateVideo)),
+	// Machine client API keys
+	mux.Handle("POST /auth/api-keys",
+		authMiddleware.RequireRole([]string{"ADMIN"}, http.HandlerFunc(apiKeyHandler.CreateAPIKey)),
 	)
-	mux.Handle("DELETE /media/videos/{id}",
-		authMiddleware.RequireRole([]string{"ADMIN"}, http.HandlerFunc(mediaHandler.DeleteVideo)),
+	mux.Handle("GET /auth/api-keys",
+		authMiddleware.RequireRole([]string{"ADMIN"}, http.HandlerFunc(apiKeyHandler.ListAPIKeys)),
 	)
+	mux.Handle("DELETE /auth/api-keys/{id}",
+		authMiddleware.RequireRole([]string{"ADMIN"}, http.HandlerFunc(apiKeyHandler.DeleteAPIKey)),
+	)
+	mux.Handle("POST /auth/policy/evaluate",
+		authMiddleware.RequireRole([]string{"ADMIN"}, http.HandlerFunc(policyHandler.Evaluate)),
+	)
+
+	// Compliance
+	mux.Handle("GET /auth/audit",
+		authMiddleware.RequireRole([]string{"ADMIN"}, http.HandlerFunc(auditHandler.ListEntries)),
+	)
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		fatal("invalid TLS configuration", err)
+	}
+
+	server := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           middleware.RequestID(middleware.Tracing(middleware.AccessLog(middleware.Metrics(openAPIValidator.Handler(mux))))),
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		TLSConfig:         tlsConfig,
+	}
+
+	app := lifecycle.New(server, cfg.ShutdownGracePeriod, cfg.ShutdownDrainDelay)
+	app.OnSignal(healthHandler.MarkShuttingDown)
+	if traceExporter != nil {
+		app.Register("trace exporter", traceExporter.Shutdown)
+	}
+	app.Register("mongo", mongoClient.Disconnect)
+	app.Register("redis", func(context.Context) error { return redisClient.Close() })
+	app.Register("health checks", healthRegistry.Stop)
+	if jwksProvider != nil {
+		app.Register("jwks refresher", func(context.Context) error {
+			jwksProvider.Close()
+			return nil
+		})
+	}
+	if fileProvider != nil {
+		app.Register("public key watcher", func(context.Context) error {
+			fileProvider.Close()
+			return nil
+		})
+	}
+	app.Register("revocation subscriber", func(context.Context) error {
+		revocations.Close()
+		return nil
+	})
+	app.Register("auth cache janitor", func(context.Context) error {
+		authMiddleware.Close()
+		return nil
+	})
+	if cfg.SchedulerEnabled {
+		// Every instance runs it; optimistic revision checks stop two from
+		// moving the same video
+		scheduler := services.NewPublishScheduler(mediaService, cfg.SchedulerInterval)
+		scheduler.Start(context.Background())
+		app.Register("publish scheduler", scheduler.Close)
+	}
+
+	if err := app.Run(); err != nil {
+		fatal("server stopped with error", err)
+	}
+	slog.Info("server stopped")
+}
 
-	log.Printf("Starting server on :%s", cfg.Port)
-	if err := http.ListenAndServe(":"+cfg.Port, mux); err != nil {
-		log.Fatalf("Could not start server: %s\n", err)
+func newTraceExporter(cfg *config.Config) tracing.Exporter {
+	switch cfg.TraceExporter {
+	case "otlp":
+		slog.Info("exporting traces via OTLP/HTTP", "endpoint", cfg.OTLPEndpoint)
+		return tracing.NewOTLPExporter(cfg.OTLPEndpoint, cfg.ServiceName)
+	case "none":
+		return nil
+	default:
+		return tracing.NewLogExporter(slog.Default())
 	}
 }
+
+// newTLSConfig returns nil when TLS is not configured. With a client CA bundle,
+// certificates are verified when presented but not required, so user callers
+// without one can still authenticate with a bearer token.
+func newTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if cfg.TLSCertFile == "" && cfg.TLSKeyFile == "" {
+		if cfg.TLSClientCAFile != "" {
+			return nil, errors.New("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load server certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if cfg.TLSClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.TLSClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
@@ -0,0 +1,97 @@
+// Command auditverify walks the audit trail in MONGO_URI and reports gaps,
+// broken links and modified entries. It exits non-zero if any are found.
+//
+// Removing entries from the end of the trail, or rewriting it completely, leaves
+// a chain that is consistent on its own. To catch that, keep the head printed by
+// each run and pass it as -anchor to the next one:
+//
+//	go run ./cmd/auditverify -anchor 1042:3f1c...
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/adapters/audit"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/config"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+)
+
+func main() {
+	anchor := flag.String("anchor", "", "seq:hash of a previously verified entry that must still be in the trail unchanged")
+	timeout := flag.Duration("timeout", 10*time.Minute, "give up after this long")
+	flag.Parse()
+
+	anchorSeq, anchorHash, err := parseAnchor(*anchor)
+	if err != nil {
+		fail(err)
+	}
+
+	cfg := config.Load()
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURI))
+	if err != nil {
+		fail(fmt.Errorf("connect to database: %w", err))
+	}
+	defer client.Disconnect(context.Background())
+
+	trail := audit.NewMongoAuditLog(client)
+	result, err := trail.Verify(ctx)
+	if err != nil {
+		fail(fmt.Errorf("read audit trail: %w", err))
+	}
+	problems := result.Problems
+
+	if anchorSeq > 0 {
+		entries, err := trail.Query(ctx, domain.AuditFilter{BeforeSeq: anchorSeq + 1, Limit: 1})
+		if err != nil {
+			fail(fmt.Errorf("read anchor entry: %w", err))
+		}
+		switch {
+		case len(entries) == 0 || entries[0].Seq != anchorSeq:
+			problems = append(problems, fmt.Errorf("%w: anchor entry %d is missing", domain.ErrAuditChainBroken, anchorSeq))
+		case entries[0].Hash != anchorHash:
+			problems = append(problems, fmt.Errorf("%w: anchor entry %d has hash %s", domain.ErrAuditChainBroken, anchorSeq, entries[0].Hash))
+		}
+	}
+
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if result.Head == nil {
+		fmt.Println("audit trail is empty")
+	} else {
+		fmt.Printf("verified %d entries, head %d:%s\n", result.Entries, result.Head.Seq, result.Head.Hash)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "auditverify: %d problems found\n", len(problems))
+		os.Exit(1)
+	}
+}
+
+func parseAnchor(anchor string) (int64, string, error) {
+	if anchor == "" {
+		return 0, "", nil
+	}
+	seq, hash, ok := strings.Cut(anchor, ":")
+	n, err := strconv.ParseInt(seq, 10, 64)
+	if !ok || err != nil || n < 1 || hash == "" {
+		return 0, "", fmt.Errorf("-anchor must be seq:hash, got %q", anchor)
+	}
+	return n, hash, nil
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "auditverify:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,83 @@
+// Command mintjwt signs access tokens with the key written by the API in
+// AUTH_MODE=dev, so endpoints can be exercised locally without the identity
+// service.
+//
+//	go run ./cmd/mintjwt -sub user-1 -role ADMIN -exp 2h
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/config"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/keys"
+)
+
+func main() {
+	sub := flag.String("sub", "dev-user", "subject (sub) claim")
+	role := flag.String("role", "ADMIN", "role claim")
+	jti := flag.String("jti", "", "token ID (jti) claim; a random UUID when empty")
+	exp := flag.Duration("exp", time.Hour, "lifetime of the token")
+	iss := flag.String("iss", "", "issuer (iss) claim; required if the API sets JWT_ISSUERS")
+	aud := flag.String("aud", "", "audience (aud) claim; required if the API sets JWT_AUDIENCE")
+	sid := flag.String("sid", "", "session (sid) claim")
+	keyPath := flag.String("key", "", "dev signing key written by the API (default $DEV_SIGNING_KEY_PATH or "+config.DefaultDevSigningKeyPath()+")")
+	flag.Parse()
+
+	appEnv := os.Getenv("APP_ENV")
+	if appEnv == "" || appEnv == "production" {
+		fail(fmt.Errorf("refusing to mint tokens with APP_ENV=%q: set APP_ENV to a non-production environment", appEnv))
+	}
+	if *exp <= 0 {
+		fail(fmt.Errorf("-exp must be positive, got %s", *exp))
+	}
+
+	if *keyPath == "" {
+		*keyPath = os.Getenv("DEV_SIGNING_KEY_PATH")
+	}
+	if *keyPath == "" {
+		*keyPath = config.DefaultDevSigningKeyPath()
+	}
+	if *jti == "" {
+		*jti = uuid.NewString()
+	}
+
+	private, err := keys.LoadDevSigningKey(*keyPath)
+	if err != nil {
+		fail(fmt.Errorf("load dev signing key (is the API running with AUTH_MODE=dev?): %w", err))
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub":  *sub,
+		"role": *role,
+		"jti":  *jti,
+		"iat":  now.Unix(),
+		"exp":  now.Add(*exp).Unix(),
+	}
+	if *iss != "" {
+		claims["iss"] = *iss
+	}
+	if *aud != "" {
+		claims["aud"] = *aud
+	}
+	if *sid != "" {
+		claims["sid"] = *sid
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(private)
+	if err != nil {
+		fail(fmt.Errorf("sign token: %w", err))
+	}
+	fmt.Println(token)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "mintjwt:", err)
+	os.Exit(1)
+}
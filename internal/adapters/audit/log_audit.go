@@ -0,0 +1,42 @@
+// Package audit contains AuditLog implementations.
+package audit
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+)
+
+// LogAuditLog writes audit events as structured log records tagged audit=true,
+// so they can be routed to a separate sink by the log pipeline.
+type LogAuditLog struct {
+	logger *slog.Logger
+}
+
+var _ ports.AuditLog = (*LogAuditLog)(nil)
+
+func NewLogAuditLog(logger *slog.Logger) *LogAuditLog {
+	return &LogAuditLog{logger: logger}
+}
+
+func (l *LogAuditLog) Record(ctx context.Context, event domain.AuditEvent) error {
+	attrs := []any{
+		"audit", true,
+		"action", event.Action,
+		"actor", event.Actor,
+		"actor_role", event.ActorRole,
+		"target", event.Target,
+		"outcome", event.Outcome,
+		"event_time", event.Time,
+	}
+	if event.ActingAs != "" {
+		attrs = append(attrs, "acting_as", event.ActingAs)
+	}
+	if len(event.Details) > 0 {
+		attrs = append(attrs, "details", event.Details)
+	}
+	l.logger.InfoContext(ctx, "audit event", attrs...)
+	return nil
+}
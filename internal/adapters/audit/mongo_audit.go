@@ -0,0 +1,183 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+)
+
+// maxAppendAttempts bounds how often Record retries when another replica
+// appended the same sequence number first.
+const maxAppendAttempts = 5
+
+const (
+	defaultQueryLimit = 100
+	maxQueryLimit     = 1000
+)
+
+// MongoAuditLog stores audit events as a hash chain in the media.audit_log
+// collection. The sequence number is the document _id, so two replicas cannot
+// both append the same entry; the loser rereads the head and retries. Nothing
+// here updates or deletes entries, and the service's database user only needs
+// insert and find on the collection.
+type MongoAuditLog struct {
+	collection *mongo.Collection
+	// appendMu serializes appends from this replica so they do not conflict with each other
+	appendMu sync.Mutex
+}
+
+var _ ports.AuditTrail = (*MongoAuditLog)(nil)
+
+func NewMongoAuditLog(mongodb *mongo.Client) *MongoAuditLog {
+	return &MongoAuditLog{
+		collection: mongodb.Database("media").Collection("audit_log"),
+	}
+}
+
+func (l *MongoAuditLog) Record(ctx context.Context, event domain.AuditEvent) error {
+	// MongoDB keeps milliseconds, and the hash must match what is read back
+	event.Time = event.Time.UTC().Truncate(time.Millisecond)
+
+	l.appendMu.Lock()
+	defer l.appendMu.Unlock()
+
+	return appendEntry(ctx, event, l.head, l.insert)
+}
+
+// appendEntry chains event onto the entry returned by head and inserts it. When
+// another replica inserted the same sequence number first, it rereads the head
+// and chains onto that instead.
+func appendEntry(ctx context.Context, event domain.AuditEvent,
+	head func(context.Context) (*domain.AuditEntry, error),
+	insert func(context.Context, domain.AuditEntry) error,
+) error {
+	for range maxAppendAttempts {
+		prev, err := head(ctx)
+		if err != nil {
+			return fmt.Errorf("read audit chain head: %w", err)
+		}
+		err = insert(ctx, domain.NewAuditEntry(prev, event))
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		return err
+	}
+	return fmt.Errorf("append audit entry: gave up after %d conflicting attempts", maxAppendAttempts)
+}
+
+func (l *MongoAuditLog) insert(ctx context.Context, entry domain.AuditEntry) error {
+	_, err := l.collection.InsertOne(ctx, entry)
+	return err
+}
+
+// head returns the latest entry, or nil while the trail is empty.
+func (l *MongoAuditLog) head(ctx context.Context) (*domain.AuditEntry, error) {
+	var entry domain.AuditEntry
+	opts := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+	err := l.collection.FindOne(ctx, bson.M{}, opts).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (l *MongoAuditLog) Query(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	query := bson.M{}
+	if filter.Actor != "" {
+		query["actor"] = filter.Actor
+	}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	if filter.Target != "" {
+		query["target"] = filter.Target
+	}
+	if filter.Outcome != "" {
+		query["outcome"] = filter.Outcome
+	}
+	if filter.RequestID != "" {
+		query["request_id"] = filter.RequestID
+	}
+	if filter.BeforeSeq > 0 {
+		query["_id"] = bson.M{"$lt": filter.BeforeSeq}
+	}
+	timeRange := bson.M{}
+	if !filter.From.IsZero() {
+		timeRange["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		timeRange["$lt"] = filter.To
+	}
+	if len(timeRange) > 0 {
+		query["time"] = timeRange
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+	limit = min(limit, maxQueryLimit)
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := l.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := make([]domain.AuditEntry, 0)
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Verification is the result of walking the whole audit trail.
+type Verification struct {
+	Entries int64
+	// Head is the last entry, nil when the trail is empty. Recording its Seq
+	// and Hash elsewhere lets a later run detect entries removed from the end.
+	Head     *domain.AuditEntry
+	Problems []error
+}
+
+// Verify reads every entry in sequence order and reports each gap, broken link
+// and modified entry. After a problem it carries on from the entry it found, so
+// one tampered entry is reported once rather than for the rest of the trail.
+func (l *MongoAuditLog) Verify(ctx context.Context) (*Verification, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := l.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	result := &Verification{}
+	for cursor.Next(ctx) {
+		var entry domain.AuditEntry
+		if err := cursor.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("decode audit entry %v: %w", cursor.Current.Lookup("_id"), err)
+		}
+		if err := entry.Verify(result.Head); err != nil {
+			result.Problems = append(result.Problems, err)
+		}
+		result.Entries++
+		result.Head = &entry
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
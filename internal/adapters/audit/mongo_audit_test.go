@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+)
+
+// racingChain stands in for the audit_log collection. Before each insert listed
+// in raceOn, another replica appends an entry first, so the insert hits the
+// same _id.
+type racingChain struct {
+	entries []domain.AuditEntry
+	raceOn  map[int]bool
+	heads   int
+	inserts int
+}
+
+func (c *racingChain) head(ctx context.Context) (*domain.AuditEntry, error) {
+	c.heads++
+	if len(c.entries) == 0 {
+		return nil, nil
+	}
+	head := c.entries[len(c.entries)-1]
+	return &head, nil
+}
+
+func (c *racingChain) insert(ctx context.Context, entry domain.AuditEntry) error {
+	c.inserts++
+	if c.raceOn[c.inserts] {
+		c.entries = append(c.entries, domain.NewAuditEntry(c.last(), domain.AuditEvent{Actor: "other-replica"}))
+	}
+	if last := c.last(); last != nil && entry.Seq <= last.Seq {
+		return mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000, Message: "E11000 duplicate key error"}}}
+	}
+	c.entries = append(c.entries, entry)
+	return nil
+}
+
+func (c *racingChain) last() *domain.AuditEntry {
+	if len(c.entries) == 0 {
+		return nil
+	}
+	return &c.entries[len(c.entries)-1]
+}
+
+func TestAppendEntryRereadsHeadAfterDuplicateKey(t *testing.T) {
+	chain := &racingChain{raceOn: map[int]bool{1: true, 2: true}}
+	chain.entries = append(chain.entries, domain.NewAuditEntry(nil, domain.AuditEvent{Actor: "first"}))
+
+	if err := appendEntry(context.Background(), domain.AuditEvent{Actor: "admin-1"}, chain.head, chain.insert); err != nil {
+		t.Fatalf("appendEntry() error = %v", err)
+	}
+	if chain.heads != 3 || chain.inserts != 3 {
+		t.Errorf("read the head %d times for %d inserts, want 3 and 3", chain.heads, chain.inserts)
+	}
+
+	ours := chain.entries[len(chain.entries)-1]
+	if ours.Actor != "admin-1" || ours.Seq != 4 {
+		t.Fatalf("last entry = %d by %q, want 4 by admin-1", ours.Seq, ours.Actor)
+	}
+	var prev *domain.AuditEntry
+	for i := range chain.entries {
+		if err := chain.entries[i].Verify(prev); err != nil {
+			t.Errorf("chain broken after the retry: %v", err)
+		}
+		prev = &chain.entries[i]
+	}
+}
+
+func TestAppendEntryGivesUp(t *testing.T) {
+	chain := &racingChain{raceOn: map[int]bool{}}
+	for i := 1; i <= maxAppendAttempts; i++ {
+		chain.raceOn[i] = true
+	}
+
+	err := appendEntry(context.Background(), domain.AuditEvent{Actor: "admin-1"}, chain.head, chain.insert)
+	if err == nil || chain.inserts != maxAppendAttempts {
+		t.Errorf("error = %v after %d inserts, want to give up after %d", err, chain.inserts, maxAppendAttempts)
+	}
+}
+
+func TestAppendEntryReturnsOtherErrors(t *testing.T) {
+	failure := errors.New("connection reset")
+	inserts := 0
+	insert := func(context.Context, domain.AuditEntry) error {
+		inserts++
+		return failure
+	}
+	head := func(context.Context) (*domain.AuditEntry, error) { return nil, nil }
+
+	if err := appendEntry(context.Background(), domain.AuditEvent{}, head, insert); !errors.Is(err, failure) || inserts != 1 {
+		t.Errorf("error = %v after %d inserts, want %v without retrying", err, inserts, failure)
+	}
+
+	headFailure := errors.New("no primary")
+	head = func(context.Context) (*domain.AuditEntry, error) { return nil, headFailure }
+	if err := appendEntry(context.Background(), domain.AuditEvent{}, head, insert); !errors.Is(err, headFailure) {
+		t.Errorf("error = %v, want %v", err, headFailure)
+	}
+}
@@ -0,0 +1,31 @@
+package audit
+
+import (
+	"context"
+	"errors"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+)
+
+// MultiAuditLog records every event to all of its logs, so an event the audit
+// trail fails to store still reaches the log pipeline.
+type MultiAuditLog struct {
+	logs []ports.AuditLog
+}
+
+var _ ports.AuditLog = (*MultiAuditLog)(nil)
+
+func NewMultiAuditLog(logs ...ports.AuditLog) *MultiAuditLog {
+	return &MultiAuditLog{logs: logs}
+}
+
+func (m *MultiAuditLog) Record(ctx context.Context, event domain.AuditEvent) error {
+	var errs []error
+	for _, log := range m.logs {
+		if err := log.Record(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
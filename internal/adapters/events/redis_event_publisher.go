@@ -0,0 +1,63 @@
+// Package events contains EventPublisher implementations.
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+var eventsPublished = metrics.NewCounterVec(
+	"video_events_published_total",
+	"Video events appended to the event stream by type and outcome.",
+	"type", "outcome",
+)
+
+// maxStreamLength caps the stream so it does not grow without bound when no
+// consumer is reading it; older entries are trimmed first.
+const maxStreamLength = 100000
+
+// RedisEventPublisher appends video events to a Redis stream, keyed by video
+// so consumers can pick out the entries they care about.
+type RedisEventPublisher struct {
+	redisClient *redis.Client
+	stream      string
+}
+
+var _ ports.EventPublisher = (*RedisEventPublisher)(nil)
+
+func NewRedisEventPublisher(redisClient *redis.Client, stream string) *RedisEventPublisher {
+	return &RedisEventPublisher{
+		redisClient: redisClient,
+		stream:      stream,
+	}
+}
+
+func (p *RedisEventPublisher) Publish(ctx context.Context, event domain.VideoEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	err = p.redisClient.XAdd(ctx, &redis.XAddArgs{
+		Stream: p.stream,
+		MaxLen: maxStreamLength,
+		Approx: true,
+		Values: map[string]any{
+			"type":     event.Type,
+			"video_id": event.VideoID,
+			"payload":  string(payload),
+		},
+	}).Err()
+
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	eventsPublished.WithLabelValues(event.Type, outcome).Inc()
+	return err
+}
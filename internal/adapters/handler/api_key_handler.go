@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+)
+
+type APIKeyHandler struct {
+	apiKeyService ports.APIKeyService
+	audit         ports.AuditLog
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type APIKeysResponse struct {
+	APIKeys []APIKeyDTO `json:"api_keys"`
+}
+
+type APIKeyDTO struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	CreatedBy  string     `json:"created_by"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CreatedAPIKeyDTO is the only response that ever contains the raw key.
+type CreatedAPIKeyDTO struct {
+	APIKeyDTO
+	Key string `json:"key"`
+}
+
+func NewAPIKeyHandler(apiKeyService ports.APIKeyService, audit ports.AuditLog) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+		audit:         audit,
+	}
+}
+
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	scopes := make([]domain.APIKeyScope, len(req.Scopes))
+	for i, scope := range req.Scopes {
+		scopes[i] = domain.APIKeyScope(scope)
+	}
+
+	creator, _ := domain.PrincipalFromContext(r.Context())
+	rawKey, key, err := h.apiKeyService.CreateAPIKey(r.Context(), req.Name, scopes, req.ExpiresAt, creator.ID)
+	if errors.Is(err, domain.ErrInvalidAPIKeyRequest) {
+		recordAudit(h.audit, r, "auth.api_key.create", req.Name, domain.AuditFailure, map[string]string{"error": err.Error()})
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		recordAudit(h.audit, r, "auth.api_key.create", req.Name, domain.AuditFailure, map[string]string{"error": err.Error()})
+		slog.ErrorContext(r.Context(), "failed to create api key", "error", err)
+		http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, r, "auth.api_key.create", key.ID, domain.AuditSuccess, map[string]string{
+		"name":   key.Name,
+		"scopes": strings.Join(req.Scopes, ","),
+	})
+
+	writeJSON(w, r, http.StatusCreated, CreatedAPIKeyDTO{APIKeyDTO: newAPIKeyDTO(*key), Key: rawKey})
+}
+
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	keys, err := h.apiKeyService.ListAPIKeys(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to list api keys", "error", err)
+		http.Error(w, "Failed to list API keys", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, r, "auth.api_key.list", "", domain.AuditSuccess, nil)
+
+	response := APIKeysResponse{APIKeys: make([]APIKeyDTO, len(keys))}
+	for i, key := range keys {
+		response.APIKeys[i] = newAPIKeyDTO(key)
+	}
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+func (h *APIKeyHandler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Missing API key ID", http.StatusBadRequest)
+		return
+	}
+
+	err := h.apiKeyService.DeleteAPIKey(r.Context(), id)
+	if errors.Is(err, domain.ErrAPIKeyNotFound) {
+		recordAudit(h.audit, r, "auth.api_key.delete", id, domain.AuditFailure, map[string]string{"error": err.Error()})
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		recordAudit(h.audit, r, "auth.api_key.delete", id, domain.AuditFailure, map[string]string{"error": err.Error()})
+		slog.ErrorContext(r.Context(), "failed to delete api key", "api_key_id", id, "error", err)
+		http.Error(w, "Failed to delete API key", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, r, "auth.api_key.delete", id, domain.AuditSuccess, nil)
+
+	writeJSON(w, r, http.StatusOK, map[string]string{
+		"message": "API key deleted successfully",
+	})
+}
+
+func newAPIKeyDTO(key domain.APIKey) APIKeyDTO {
+	scopes := make([]string, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = string(scope)
+	}
+	return APIKeyDTO{
+		ID:         key.ID,
+		Name:       key.Name,
+		Scopes:     scopes,
+		ExpiresAt:  key.ExpiresAt,
+		CreatedAt:  key.CreatedAt,
+		CreatedBy:  key.CreatedBy,
+		LastUsedAt: key.LastUsedAt,
+	}
+}
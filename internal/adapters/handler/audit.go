@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/logging"
+)
+
+// recordAudit writes an audit event for the authenticated caller of r. A failure
+// to record is logged but does not fail the request.
+func recordAudit(audit ports.AuditLog, r *http.Request, action, target string, outcome domain.AuditOutcome, details map[string]string) {
+	actor, _ := domain.PrincipalFromContext(r.Context())
+
+	event := domain.NewAuditEvent(actor, action, target, outcome, details)
+	event.RequestID = logging.RequestID(r.Context())
+	if err := audit.Record(r.Context(), event); err != nil {
+		slog.ErrorContext(r.Context(), "failed to record audit event", "action", action, "error", err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode response", "error", err)
+	}
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+)
+
+// AuditHandler lets admins search the audit trail for compliance reviews.
+type AuditHandler struct {
+	trail ports.AuditTrail
+	audit ports.AuditLog
+}
+
+type AuditEntriesResponse struct {
+	Entries []domain.AuditEntry `json:"entries"`
+}
+
+func NewAuditHandler(trail ports.AuditTrail, audit ports.AuditLog) *AuditHandler {
+	return &AuditHandler{
+		trail: trail,
+		audit: audit,
+	}
+}
+
+// ListEntries returns matching entries newest first. Passing the seq of the
+// last entry as before returns the next page.
+func (h *AuditHandler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := domain.AuditFilter{
+		Actor:     query.Get("actor"),
+		Action:    query.Get("action"),
+		Target:    query.Get("target"),
+		Outcome:   domain.AuditOutcome(query.Get("outcome")),
+		RequestID: query.Get("request_id"),
+	}
+
+	var err error
+	if filter.From, err = parseTimeParam(query.Get("from")); err != nil {
+		http.Error(w, "Invalid from time", http.StatusBadRequest)
+		return
+	}
+	if filter.To, err = parseTimeParam(query.Get("to")); err != nil {
+		http.Error(w, "Invalid to time", http.StatusBadRequest)
+		return
+	}
+	if before := query.Get("before"); before != "" {
+		if filter.BeforeSeq, err = strconv.ParseInt(before, 10, 64); err != nil || filter.BeforeSeq < 1 {
+			http.Error(w, "Invalid before sequence number", http.StatusBadRequest)
+			return
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := h.trail.Query(r.Context(), filter)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to query audit trail", "error", err)
+		http.Error(w, "Failed to query audit trail", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, r, "audit.query", "", domain.AuditSuccess, map[string]string{
+		"filter":  r.URL.RawQuery,
+		"results": strconv.Itoa(len(entries)),
+	})
+
+	writeJSON(w, r, http.StatusOK, AuditEntriesResponse{Entries: entries})
+}
+
+// parseTimeParam accepts an RFC 3339 time; empty means unset.
+func parseTimeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/adapters/middleware"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+)
+
+// AuthAdminHandler lets admins revoke tokens and inspect the principals cached on
+// this replica during an incident. Every call is audited.
+type AuthAdminHandler struct {
+	auth        *middleware.AuthMiddleware
+	revocations *middleware.RevocationChecker
+	audit       ports.AuditLog
+}
+
+// RevokeTokenRequest names the token by jti, or hands over the token itself so
+// it is blacklisted exactly until it expires.
+type RevokeTokenRequest struct {
+	JTI   string `json:"jti"`
+	Token string `json:"token"`
+}
+
+type RevokeSubjectRequest struct {
+	Subject string `json:"subject"`
+}
+
+type RevocationDTO struct {
+	Scope         string     `json:"scope"`
+	ID            string     `json:"id"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	RevokedBefore *time.Time `json:"revoked_before,omitempty"`
+}
+
+type PrincipalsResponse struct {
+	Principals []CachedPrincipalDTO `json:"principals"`
+}
+
+type CachedPrincipalDTO struct {
+	JTI       string    `json:"jti"`
+	Subject   string    `json:"subject"`
+	Role      string    `json:"role"`
+	SessionID string    `json:"session_id,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func NewAuthAdminHandler(auth *middleware.AuthMiddleware, revocations *middleware.RevocationChecker, audit ports.AuditLog) *AuthAdminHandler {
+	return &AuthAdminHandler{
+		auth:        auth,
+		revocations: revocations,
+		audit:       audit,
+	}
+}
+
+func (h *AuthAdminHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RevokeTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.JTI == "" && req.Token == "") {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	jti, expiresAt, err := h.auth.RevocationExpiry(r.Context(), req.JTI, req.Token)
+	if err == nil {
+		err = h.revocations.RevokeToken(r.Context(), jti, expiresAt)
+	}
+	if err != nil {
+		target := jti
+		if target == "" {
+			target = req.JTI
+		}
+		h.record(r, "auth.token.revoke", target, domain.AuditFailure, map[string]string{"error": err.Error()})
+	}
+	switch {
+	case errors.Is(err, middleware.ErrTokenAlreadyExpired):
+		http.Error(w, "Token already expired", http.StatusBadRequest)
+		return
+	case errors.Is(err, middleware.ErrRevocationTokenInvalid):
+		http.Error(w, "Invalid token", http.StatusBadRequest)
+		return
+	case errors.Is(err, middleware.ErrRevocationJTIMismatch):
+		http.Error(w, "jti does not match the token", http.StatusBadRequest)
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "failed to revoke token", "jti", jti, "error", err)
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+	h.record(r, "auth.token.revoke", jti, domain.AuditSuccess, map[string]string{
+		"expires_at": expiresAt.UTC().Format(time.RFC3339),
+	})
+
+	writeJSON(w, r, http.StatusCreated, RevocationDTO{
+		Scope:     string(middleware.ScopeToken),
+		ID:        jti,
+		ExpiresAt: &expiresAt,
+	})
+}
+
+func (h *AuthAdminHandler) RevokeSubject(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RevokeSubjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Subject == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	revokedBefore := time.Now().UTC().Truncate(time.Second)
+	if err := h.revocations.RevokeSubject(r.Context(), req.Subject, revokedBefore); err != nil {
+		h.record(r, "auth.subject.revoke", req.Subject, domain.AuditFailure, map[string]string{"error": err.Error()})
+		slog.ErrorContext(r.Context(), "failed to revoke subject", "subject", req.Subject, "error", err)
+		http.Error(w, "Failed to revoke subject", http.StatusInternalServerError)
+		return
+	}
+	h.record(r, "auth.subject.revoke", req.Subject, domain.AuditSuccess, map[string]string{
+		"revoked_before": revokedBefore.Format(time.RFC3339),
+	})
+
+	writeJSON(w, r, http.StatusCreated, RevocationDTO{
+		Scope:         string(middleware.ScopeSubject),
+		ID:            req.Subject,
+		RevokedBefore: &revokedBefore,
+	})
+}
+
+func (h *AuthAdminHandler) ListPrincipals(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principals := h.auth.CachedPrincipals()
+	sort.Slice(principals, func(i, j int) bool {
+		if principals[i].Subject != principals[j].Subject {
+			return principals[i].Subject < principals[j].Subject
+		}
+		return principals[i].ExpiresAt.Before(principals[j].ExpiresAt)
+	})
+
+	response := PrincipalsResponse{Principals: make([]CachedPrincipalDTO, len(principals))}
+	for i, p := range principals {
+		response.Principals[i] = CachedPrincipalDTO{
+			JTI:       p.JTI,
+			Subject:   p.Subject,
+			Role:      p.Role,
+			SessionID: p.SessionID,
+			ExpiresAt: p.ExpiresAt,
+		}
+	}
+	h.record(r, "auth.principals.list", "", domain.AuditSuccess, nil)
+
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+func (h *AuthAdminHandler) record(r *http.Request, action, target string, outcome domain.AuditOutcome, details map[string]string) {
+	recordAudit(h.audit, r, action, target, outcome, details)
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+type DocsHandler struct {
+	spec []byte
+	page []byte
+}
+
+func NewDocsHandler(spec, page []byte) *DocsHandler {
+	return &DocsHandler{
+		spec: spec,
+		page: page,
+	}
+}
+
+// Spec serves the OpenAPI document describing this service
+func (h *DocsHandler) Spec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(h.spec); err != nil {
+		slog.ErrorContext(r.Context(), "failed to write response", "error", err)
+	}
+}
+
+// UI serves the Swagger viewer pointed at /openapi.json
+func (h *DocsHandler) UI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(h.page); err != nil {
+		slog.ErrorContext(r.Context(), "failed to write response", "error", err)
+	}
+}
@@ -1,36 +1,40 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
-	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
-	"runtime"
+	"sync/atomic"
 	"time"
 
-	"go.mongodb.org/mongo-driver/mongo"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/health"
 )
 
 type HealthHandler struct {
-	mongoClient *mongo.Client
-	startTime   time.Time
-	version     string
+	registry     *health.Registry
+	startTime    time.Time
+	version      string
+	shuttingDown atomic.Bool
 }
 
-func NewHealthHandler(mongoClient *mongo.Client) *HealthHandler {
+func NewHealthHandler(registry *health.Registry) *HealthHandler {
 	version := os.Getenv("APP_VERSION")
 	if version == "" {
 		version = "unknown"
 	}
 	return &HealthHandler{
-		mongoClient: mongoClient,
-		startTime:   time.Now(),
-		version:     version,
+		registry:  registry,
+		startTime: time.Now(),
+		version:   version,
 	}
 }
 
+// MarkShuttingDown makes readiness report DOWN so OpenShift stops routing new traffic here
+func (h *HealthHandler) MarkShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
 // HealthResponse follows Kubernetes/OpenShift health check conventions
 type HealthResponse struct {
 	Status    string           `json:"status"`
@@ -41,11 +45,15 @@ type HealthResponse struct {
 }
 
 type Check struct {
-	Status  string `json:"status"`
-	Message string `json:"message,omitempty"`
+	Status      string `json:"status"`
+	Message     string `json:"message,omitempty"`
+	Critical    bool   `json:"critical"`
+	LastChecked string `json:"last_checked,omitempty"`
+	DurationMS  int64  `json:"duration_ms"`
 }
 
-// Health is for general health status (liveness probe in OpenShift)
+// Health reports the cached result of every registered dependency check.
+// Only failing critical checks turn the response into a 503.
 func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -53,31 +61,30 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	}
 
 	checks := make(map[string]Check)
-	status := "UP"
-	httpStatus := http.StatusOK
-
-	// Database check
-	dbCheck := h.checkDatabase()
-	checks["database"] = dbCheck
-	if dbCheck.Status != "UP" {
-		status = "DOWN"
-		httpStatus = http.StatusServiceUnavailable
+	for name, result := range h.registry.Results() {
+		check := Check{
+			Status:     string(result.Status),
+			Message:    result.Message,
+			Critical:   result.Critical,
+			DurationMS: result.Duration.Milliseconds(),
+		}
+		if !result.LastChecked.IsZero() {
+			check.LastChecked = result.LastChecked.UTC().Format(time.RFC3339)
+		}
+		checks[name] = check
 	}
 
-	// Memory check
-	memCheck := h.checkMemory()
-	checks["memory"] = memCheck
-
-	// Public key check
-	keyCheck := h.checkPublicKey()
-	checks["public_key"] = keyCheck
-	if keyCheck.Status != "UP" {
-		status = "DOWN"
+	status := h.registry.Status()
+	if h.shuttingDown.Load() {
+		status = health.StatusDown
+	}
+	httpStatus := http.StatusOK
+	if status == health.StatusDown {
 		httpStatus = http.StatusServiceUnavailable
 	}
 
 	response := HealthResponse{
-		Status:    status,
+		Status:    string(status),
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		Uptime:    time.Since(h.startTime).Round(time.Second).String(),
 		Version:   h.version,
@@ -87,7 +94,7 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(httpStatus)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Failed to encode response: %v", err)
+		slog.ErrorContext(r.Context(), "failed to encode response", "error", err)
 	}
 }
 
@@ -98,26 +105,13 @@ func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check MongoDB connection with timeout
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-	defer cancel()
-
-	if err := h.mongoClient.Ping(ctx, nil); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusServiceUnavailable)
-		json.NewEncoder(w).Encode(map[string]string{
-			"status":  "DOWN",
-			"message": "Database not ready",
-		})
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(map[string]string{
-		"status": "UP",
-	}); err != nil {
-		log.Printf("Failed to encode response: %v", err)
+	switch {
+	case h.shuttingDown.Load():
+		h.writeProbe(w, r, http.StatusServiceUnavailable, "DOWN", "Shutting down")
+	case h.registry.Status() == health.StatusDown:
+		h.writeProbe(w, r, http.StatusServiceUnavailable, "DOWN", "Critical dependency unavailable")
+	default:
+		h.writeProbe(w, r, http.StatusOK, "UP", "")
 	}
 }
 
@@ -128,48 +122,32 @@ func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"status": "UP",
-	})
+	h.writeProbe(w, r, http.StatusOK, "UP", "")
 }
 
-func (h *HealthHandler) checkDatabase() Check {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := h.mongoClient.Ping(ctx, nil); err != nil {
-		return Check{
-			Status:  "DOWN",
-			Message: "Cannot connect to database",
-		}
+// Startup reports UP once every critical check has passed at least once (startup probe in OpenShift)
+func (h *HealthHandler) Startup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
-	return Check{Status: "UP"}
-}
-
-func (h *HealthHandler) checkMemory() Check {
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
 
-	allocMB := m.Alloc / 1024 / 1024
-	return Check{
-		Status:  "UP",
-		Message: fmt.Sprintf("Allocated: %d MB", allocMB),
+	if !h.registry.Started() {
+		h.writeProbe(w, r, http.StatusServiceUnavailable, "DOWN", "Waiting for critical dependencies")
+		return
 	}
+	h.writeProbe(w, r, http.StatusOK, "UP", "")
 }
 
-func (h *HealthHandler) checkPublicKey() Check {
-	keyPath := os.Getenv("PUBLIC_KEY_PATH")
-	if keyPath == "" {
-		keyPath = "/etc/certs/public.pem"
+func (h *HealthHandler) writeProbe(w http.ResponseWriter, r *http.Request, httpStatus int, status, message string) {
+	body := map[string]string{"status": status}
+	if message != "" {
+		body["message"] = message
 	}
 
-	if _, err := os.Stat(keyPath); os.IsNotExist(err) {
-		return Check{
-			Status:  "DOWN",
-			Message: "Public key file not found",
-		}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode response", "error", err)
 	}
-	return Check{Status: "UP"}
 }
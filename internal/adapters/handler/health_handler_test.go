@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/health"
+)
+
+// startedRegistry runs the checks once in the background and waits for that
+// first round to land in the cache.
+func startedRegistry(t *testing.T, critical, nonCritical health.CheckFunc) *health.Registry {
+	t.Helper()
+	r := health.NewRegistry(time.Hour)
+	r.Register("mongodb", true, time.Second, critical)
+	r.Register("memory", false, time.Second, nonCritical)
+	r.Start()
+	t.Cleanup(func() { r.Stop(context.Background()) })
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		done := true
+		for _, result := range r.Results() {
+			done = done && !result.LastChecked.IsZero()
+		}
+		if done {
+			return r
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("first health check round did not complete")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func passing(context.Context) (string, error) { return "", nil }
+
+func failing(context.Context) (string, error) { return "", errors.New("connection refused") }
+
+func probe(t *testing.T, handle http.HandlerFunc, method string) (int, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handle(rec, httptest.NewRequest(method, "/health", nil))
+
+	var body map[string]any
+	if rec.Code != http.StatusMethodNotAllowed {
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+	}
+	return rec.Code, body
+}
+
+func TestHealthProbes(t *testing.T) {
+	tests := []struct {
+		name        string
+		critical    health.CheckFunc
+		nonCritical health.CheckFunc
+		health      int
+		status      string
+		ready       int
+		startup     int
+	}{
+		{"all up", passing, passing, http.StatusOK, "UP", http.StatusOK, http.StatusOK},
+		{"non-critical down", passing, failing, http.StatusOK, "DEGRADED", http.StatusOK, http.StatusOK},
+		{"critical down", failing, passing, http.StatusServiceUnavailable, "DOWN", http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealthHandler(startedRegistry(t, tt.critical, tt.nonCritical))
+
+			code, body := probe(t, h.Health, http.MethodGet)
+			if code != tt.health || body["status"] != tt.status {
+				t.Errorf("/health = %d %v, want %d %s", code, body["status"], tt.health, tt.status)
+			}
+			checks, _ := body["checks"].(map[string]any)
+			if len(checks) != 2 {
+				t.Errorf("/health checks = %v, want both registered checks", checks)
+			}
+			if code, _ := probe(t, h.Ready, http.MethodGet); code != tt.ready {
+				t.Errorf("/health/ready = %d, want %d", code, tt.ready)
+			}
+			if code, _ := probe(t, h.Startup, http.MethodGet); code != tt.startup {
+				t.Errorf("/health/startup = %d, want %d", code, tt.startup)
+			}
+			if code, _ := probe(t, h.Live, http.MethodGet); code != http.StatusOK {
+				t.Errorf("/health/live = %d, liveness must not depend on the checks", code)
+			}
+		})
+	}
+}
+
+func TestStartupBeforeFirstRun(t *testing.T) {
+	r := health.NewRegistry(time.Hour)
+	r.Register("mongodb", true, time.Second, passing)
+	h := NewHealthHandler(r)
+
+	code, body := probe(t, h.Startup, http.MethodGet)
+	if code != http.StatusServiceUnavailable || body["message"] != "Waiting for critical dependencies" {
+		t.Errorf("/health/startup before any check = %d %v", code, body)
+	}
+}
+
+func TestShuttingDown(t *testing.T) {
+	h := NewHealthHandler(startedRegistry(t, passing, passing))
+	h.MarkShuttingDown()
+
+	if code, body := probe(t, h.Ready, http.MethodGet); code != http.StatusServiceUnavailable || body["message"] != "Shutting down" {
+		t.Errorf("/health/ready while shutting down = %d %v", code, body)
+	}
+	if code, body := probe(t, h.Health, http.MethodGet); code != http.StatusServiceUnavailable || body["status"] != "DOWN" {
+		t.Errorf("/health while shutting down = %d %v", code, body["status"])
+	}
+	if code, _ := probe(t, h.Live, http.MethodGet); code != http.StatusOK {
+		t.Errorf("/health/live while shutting down = %d, want 200 so the pod is not restarted mid-drain", code)
+	}
+}
+
+func TestHealthProbesRejectNonGET(t *testing.T) {
+	h := NewHealthHandler(health.NewRegistry(time.Hour))
+	for name, handle := range map[string]http.HandlerFunc{
+		"health": h.Health, "ready": h.Ready, "live": h.Live, "startup": h.Startup,
+	} {
+		if code, _ := probe(t, handle, http.MethodPost); code != http.StatusMethodNotAllowed {
+			t.Errorf("POST %s = %d, want 405", name, code)
+		}
+	}
+}
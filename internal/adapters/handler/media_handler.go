@@ -2,8 +2,12 @@ package handler
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
 	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
@@ -18,6 +22,38 @@ type CreateVideoRequest struct {
 	URL         string `json:"url"`
 	ContentType string `json:"content_type"`
 	Description string `json:"description"`
+	ClinicID    string `json:"clinic_id"`
+	AgeGroup    string `json:"age_group"`
+
+	Schedule *VideoScheduleRequest `json:"schedule"`
+}
+
+// UpdateVideoRequest changes only the fields that are present. A schedule
+// replaces the current one as a whole; an empty one clears it.
+type UpdateVideoRequest struct {
+	URL         *string `json:"url"`
+	ContentType *string `json:"content_type"`
+	Description *string `json:"description"`
+	ClinicID    *string `json:"clinic_id"`
+	AgeGroup    *string `json:"age_group"`
+
+	Schedule *VideoScheduleRequest `json:"schedule"`
+}
+
+// VideoScheduleRequest sets when a video is published and unpublished. Each
+// time is either RFC 3339 with an offset ("2027-07-01T08:00:00+02:00") or a
+// local time without one ("2027-07-01T08:00"), read in Timezone, an IANA name
+// such as "Europe/Amsterdam", with the daylight saving offset of that date.
+// Local times without a timezone are UTC.
+type VideoScheduleRequest struct {
+	PublishAt   string `json:"publish_at"`
+	UnpublishAt string `json:"unpublish_at"`
+	Timezone    string `json:"timezone"`
+}
+
+type TransitionVideoRequest struct {
+	Transition string `json:"transition"`
+	Comment    string `json:"comment"`
 }
 
 type VideosResponse struct {
@@ -29,6 +65,39 @@ type VideoDTO struct {
 	URL         string `json:"url"`
 	ContentType string `json:"content_type"`
 	Description string `json:"description"`
+	Status      string `json:"status"`
+	ClinicID    string `json:"clinic_id,omitempty"`
+	AgeGroup    string `json:"age_group,omitempty"`
+	CreatedBy   string `json:"created_by,omitempty"`
+
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	Revision  int       `json:"revision"`
+
+	Schedule *VideoScheduleDTO `json:"schedule,omitempty"`
+}
+
+// VideoScheduleDTO shows the times in the timezone they were entered in.
+type VideoScheduleDTO struct {
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	UnpublishAt *time.Time `json:"unpublish_at,omitempty"`
+	Timezone    string     `json:"timezone,omitempty"`
+}
+
+type VideoRevisionsResponse struct {
+	Revisions []VideoRevisionDTO `json:"revisions"`
+}
+
+type VideoRevisionDTO struct {
+	Revision     int                  `json:"revision"`
+	Operation    string               `json:"operation"`
+	Author       string               `json:"author"`
+	CreatedAt    time.Time            `json:"created_at"`
+	Changes      []domain.FieldChange `json:"changes"`
+	RestoredFrom int                  `json:"restored_from,omitempty"`
+	Comment      string               `json:"comment,omitempty"`
+	Video        VideoDTO             `json:"video"`
 }
 
 func NewMediaHandler(video ports.VideoService) *MediaHandler {
@@ -45,7 +114,9 @@ func (h *MediaHandler) GetVideos(w http.ResponseWriter, r *http.Request) {
 
 	videos, err := h.videoService.GetVideos(r.Context())
 	if err != nil {
-		http.Error(w, "Failed to get videos", http.StatusInternalServerError)
+		if !writeAccessError(w, err) {
+			http.Error(w, "Failed to get videos", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -53,12 +124,7 @@ func (h *MediaHandler) GetVideos(w http.ResponseWriter, r *http.Request) {
 		Videos: func() []VideoDTO {
 			obj := make([]VideoDTO, len(videos))
 			for i, v := range videos {
-				obj[i] = VideoDTO{
-					ID:          v.ID,
-					URL:         v.URL,
-					ContentType: string(v.ContentType),
-					Description: v.Description,
-				}
+				obj[i] = newVideoDTO(v)
 			}
 			return obj
 		}(),
@@ -66,10 +132,10 @@ func (h *MediaHandler) GetVideos(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Failed to encode response: %v", err)
+		slog.ErrorContext(r.Context(), "failed to encode response", "error", err)
 	}
 
-	log.Printf("Retrieved %d videos", len(videos))
+	slog.DebugContext(r.Context(), "retrieved videos", "count", len(videos))
 }
 func (h *MediaHandler) GetOneVideo(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -85,21 +151,18 @@ func (h *MediaHandler) GetOneVideo(w http.ResponseWriter, r *http.Request) {
 
 	video, err := h.videoService.GetVideoByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Video not found", http.StatusNotFound)
+		if !writeAccessError(w, err) {
+			http.Error(w, "Video not found", http.StatusNotFound)
+		}
 		return
 	}
 
-	response := VideoDTO{
-		ID:          video.ID,
-		URL:         video.URL,
-		ContentType: string(video.ContentType),
-		Description: video.Description,
-	}
+	response := newVideoDTO(*video)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Failed to encode response: %v", err)
+		slog.ErrorContext(r.Context(), "failed to encode response", "error", err)
 	}
 }
 func (h *MediaHandler) CreateVideo(w http.ResponseWriter, r *http.Request) {
@@ -119,27 +182,35 @@ func (h *MediaHandler) CreateVideo(w http.ResponseWriter, r *http.Request) {
 		URL:         req.URL,
 		ContentType: domain.ContentType(req.ContentType),
 		Description: req.Description,
+		ClinicID:    req.ClinicID,
+		AgeGroup:    req.AgeGroup,
+	}
+	if req.Schedule != nil {
+		schedule, err := req.Schedule.toDomain()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		newVideo.Schedule = schedule
 	}
 
 	createdVideo, err := h.videoService.CreateVideo(r.Context(), newVideo)
+	if writeVideoError(w, err) {
+		return
+	}
 	if err != nil {
-		log.Printf("Failed to add video: %v", err)
+		slog.ErrorContext(r.Context(), "failed to create video", "error", err)
 		http.Error(w, "Failed to create video", http.StatusInternalServerError)
 		return
 	}
 
-	videoDTO := VideoDTO{
-		ID:          createdVideo.ID,
-		URL:         createdVideo.URL,
-		ContentType: string(createdVideo.ContentType),
-		Description: createdVideo.Description,
-	}
+	videoDTO := newVideoDTO(*createdVideo)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 
 	if err := json.NewEncoder(w).Encode(videoDTO); err != nil {
-		log.Printf("Failed to encode response: %v", err)
+		slog.ErrorContext(r.Context(), "failed to encode response", "error", err)
 	}
 }
 func (h *MediaHandler) DeleteVideo(w http.ResponseWriter, r *http.Request) {
@@ -155,8 +226,15 @@ func (h *MediaHandler) DeleteVideo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err := h.videoService.DeleteVideo(r.Context(), id)
+	if writeAccessError(w, err) {
+		return
+	}
+	if errors.Is(err, domain.ErrVideoNotFound) {
+		http.Error(w, "Video not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		log.Printf("Failed to delete video %s: %v", id, err)
+		slog.ErrorContext(r.Context(), "failed to delete video", "video_id", id, "error", err)
 		http.Error(w, "Failed to delete video", http.StatusInternalServerError)
 		return
 	}
@@ -167,6 +245,291 @@ func (h *MediaHandler) DeleteVideo(w http.ResponseWriter, r *http.Request) {
 	if err := json.NewEncoder(w).Encode(map[string]string{
 		"message": "Video deleted successfully",
 	}); err != nil {
-		log.Printf("Failed to write response: %v", err)
+		slog.ErrorContext(r.Context(), "failed to write response", "error", err)
+	}
+}
+
+func (h *MediaHandler) UpdateVideo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Missing video ID", http.StatusBadRequest)
+		return
+	}
+
+	var req UpdateVideoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	update := domain.VideoUpdate{
+		URL:         req.URL,
+		Description: req.Description,
+		ClinicID:    req.ClinicID,
+		AgeGroup:    req.AgeGroup,
+	}
+	if req.ContentType != nil {
+		contentType := domain.ContentType(*req.ContentType)
+		update.ContentType = &contentType
+	}
+	if req.Schedule != nil {
+		schedule, err := req.Schedule.toDomain()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		update.Schedule = &schedule
+	}
+
+	video, err := h.videoService.UpdateVideo(r.Context(), id, update)
+	if writeVideoError(w, err) {
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to update video", "video_id", id, "error", err)
+		http.Error(w, "Failed to update video", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newVideoDTO(*video))
+}
+
+func (h *MediaHandler) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Missing video ID", http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := h.videoService.ListRevisions(r.Context(), id)
+	if writeVideoError(w, err) {
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to list video revisions", "video_id", id, "error", err)
+		http.Error(w, "Failed to list revisions", http.StatusInternalServerError)
+		return
+	}
+
+	response := VideoRevisionsResponse{Revisions: make([]VideoRevisionDTO, len(revisions))}
+	for i, rev := range revisions {
+		response.Revisions[i] = VideoRevisionDTO{
+			Revision:     rev.Revision,
+			Operation:    rev.Operation,
+			Author:       rev.Author,
+			CreatedAt:    rev.CreatedAt,
+			Changes:      rev.Changes,
+			RestoredFrom: rev.RestoredFrom,
+			Comment:      rev.Comment,
+			Video:        newVideoDTO(rev.Video),
+		}
+	}
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+func (h *MediaHandler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Missing video ID", http.StatusBadRequest)
+		return
+	}
+	rev, err := strconv.Atoi(r.PathValue("rev"))
+	if err != nil || rev < 1 {
+		http.Error(w, "Invalid revision", http.StatusBadRequest)
+		return
+	}
+
+	video, err := h.videoService.RestoreRevision(r.Context(), id, rev)
+	if writeVideoError(w, err) {
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to restore video revision", "video_id", id, "revision", rev, "error", err)
+		http.Error(w, "Failed to restore revision", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newVideoDTO(*video))
+}
+
+// TransitionVideo moves a video through the editorial workflow, e.g.
+// {"transition": "reject", "comment": "..."}.
+func (h *MediaHandler) TransitionVideo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "Missing video ID", http.StatusBadRequest)
+		return
+	}
+
+	var req TransitionVideoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	video, err := h.videoService.TransitionVideo(r.Context(), id, req.Transition, req.Comment)
+	if writeVideoError(w, err) {
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to transition video", "video_id", id, "transition", req.Transition, "error", err)
+		http.Error(w, "Failed to change video status", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, newVideoDTO(*video))
+}
+
+func newVideoDTO(v domain.Video) VideoDTO {
+	return VideoDTO{
+		ID:          v.ID,
+		URL:         v.URL,
+		ContentType: string(v.ContentType),
+		Description: v.Description,
+		Status:      string(v.Status),
+		ClinicID:    v.ClinicID,
+		AgeGroup:    v.AgeGroup,
+		CreatedBy:   v.CreatedBy,
+		CreatedAt:   v.CreatedAt,
+		UpdatedAt:   v.UpdatedAt,
+		UpdatedBy:   v.UpdatedBy,
+		Revision:    v.Revision,
+		Schedule:    newVideoScheduleDTO(v.Schedule),
+	}
+}
+
+func newVideoScheduleDTO(s domain.VideoSchedule) *VideoScheduleDTO {
+	if s == (domain.VideoSchedule{}) {
+		return nil
+	}
+	loc := s.Location()
+	inZone := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		local := t.In(loc)
+		return &local
+	}
+	return &VideoScheduleDTO{
+		PublishAt:   inZone(s.PublishAt),
+		UnpublishAt: inZone(s.UnpublishAt),
+		Timezone:    s.Timezone,
+	}
+}
+
+// localTimeLayouts are accepted for schedule times without an offset.
+var localTimeLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04"}
+
+func (req VideoScheduleRequest) toDomain() (domain.VideoSchedule, error) {
+	schedule := domain.VideoSchedule{Timezone: req.Timezone}
+	if err := schedule.Validate(); err != nil {
+		return domain.VideoSchedule{}, err
+	}
+	loc := schedule.Location()
+
+	var err error
+	if schedule.PublishAt, err = parseScheduleTime("publish_at", req.PublishAt, loc); err != nil {
+		return domain.VideoSchedule{}, err
+	}
+	if schedule.UnpublishAt, err = parseScheduleTime("unpublish_at", req.UnpublishAt, loc); err != nil {
+		return domain.VideoSchedule{}, err
+	}
+	return schedule, nil
+}
+
+// parseScheduleTime returns nil for an empty value. Times are truncated to what
+// MongoDB stores.
+func parseScheduleTime(field, value string, loc *time.Location) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = parseLocalTime(field, value, loc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	utc := t.UTC().Truncate(time.Millisecond)
+	return &utc, nil
+}
+
+// parseLocalTime reads a wall-clock time in loc. A time the clocks skip or pass
+// twice for daylight saving names no single instant, so it is rejected rather
+// than moved; an RFC 3339 time with an offset says which instant is meant.
+func parseLocalTime(field, value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range localTimeLayouts {
+		t, err := time.ParseInLocation(layout, value, loc)
+		if err != nil {
+			continue
+		}
+		// time.ParseInLocation moves a skipped time past the gap
+		if t.Format(layout) != value {
+			return time.Time{}, fmt.Errorf("%s %s does not exist in %s, the clocks skip it for daylight saving time", field, value, loc)
+		}
+		_, offsetBefore := t.Add(-24 * time.Hour).Zone()
+		_, offsetAfter := t.Add(24 * time.Hour).Zone()
+		if shift := time.Duration(offsetBefore-offsetAfter) * time.Second; shift > 0 &&
+			(t.Add(shift).Format(layout) == value || t.Add(-shift).Format(layout) == value) {
+			return time.Time{}, fmt.Errorf("%s %s occurs twice in %s as the clocks go back, give it with an offset", field, value, loc)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%s must be an RFC 3339 time or a local time like 2027-07-01T08:00", field)
+}
+
+// writeVideoError answers the access, not found, conflict, workflow and
+// schedule errors of the VideoService and reports whether err was one of them.
+func writeVideoError(w http.ResponseWriter, err error) bool {
+	switch {
+	case writeAccessError(w, err):
+	case errors.Is(err, domain.ErrVideoNotFound):
+		http.Error(w, "Video not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrRevisionNotFound):
+		http.Error(w, "Revision not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrVideoConflict):
+		http.Error(w, "Video was modified concurrently, retry", http.StatusConflict)
+	case errors.Is(err, domain.ErrUnknownTransition), errors.Is(err, domain.ErrCommentRequired), errors.Is(err, domain.ErrInvalidSchedule):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, domain.ErrInvalidTransition), errors.Is(err, domain.ErrVideoNotEditable):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		return false
+	}
+	return true
+}
+
+// writeAccessError answers the authentication and authorization errors of the
+// core services and reports whether err was one of them.
+func writeAccessError(w http.ResponseWriter, err error) bool {
+	var denied *domain.AccessDeniedError
+	switch {
+	case errors.As(err, &denied):
+		http.Error(w, denied.Error(), http.StatusForbidden)
+	case errors.Is(err, domain.ErrUnauthenticated):
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+	default:
+		return false
 	}
+	return true
 }
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+)
+
+func TestVideoScheduleRequestToDomain(t *testing.T) {
+	utc := func(s string) *time.Time {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			panic(err)
+		}
+		return &t
+	}
+
+	tests := []struct {
+		name    string
+		req     VideoScheduleRequest
+		want    domain.VideoSchedule
+		wantErr string
+	}{
+		{
+			name: "RFC 3339 keeps its offset whatever the timezone",
+			req:  VideoScheduleRequest{PublishAt: "2027-07-01T08:00:00+02:00", UnpublishAt: "2027-09-01T00:00:00Z", Timezone: "America/New_York"},
+			want: domain.VideoSchedule{PublishAt: utc("2027-07-01T06:00:00Z"), UnpublishAt: utc("2027-09-01T00:00:00Z"), Timezone: "America/New_York"},
+		},
+		{
+			name: "local summer time",
+			req:  VideoScheduleRequest{PublishAt: "2027-07-01T08:00", Timezone: "Europe/Amsterdam"},
+			want: domain.VideoSchedule{PublishAt: utc("2027-07-01T06:00:00Z"), Timezone: "Europe/Amsterdam"},
+		},
+		{
+			name: "local winter time with seconds",
+			req:  VideoScheduleRequest{UnpublishAt: "2027-12-01T08:00:30", Timezone: "Europe/Amsterdam"},
+			want: domain.VideoSchedule{UnpublishAt: utc("2027-12-01T07:00:30Z"), Timezone: "Europe/Amsterdam"},
+		},
+		{
+			name: "local time without timezone is UTC",
+			req:  VideoScheduleRequest{PublishAt: "2027-07-01T08:00"},
+			want: domain.VideoSchedule{PublishAt: utc("2027-07-01T08:00:00Z")},
+		},
+		{
+			name: "truncated to milliseconds",
+			req:  VideoScheduleRequest{PublishAt: "2027-07-01T08:00:00.123456789Z"},
+			want: domain.VideoSchedule{PublishAt: utc("2027-07-01T08:00:00.123Z")},
+		},
+		{
+			name: "just before the spring gap",
+			req:  VideoScheduleRequest{PublishAt: "2027-03-28T01:59", Timezone: "Europe/Amsterdam"},
+			want: domain.VideoSchedule{PublishAt: utc("2027-03-28T00:59:00Z"), Timezone: "Europe/Amsterdam"},
+		},
+		{
+			name: "just after the spring gap",
+			req:  VideoScheduleRequest{PublishAt: "2027-03-28T03:00", Timezone: "Europe/Amsterdam"},
+			want: domain.VideoSchedule{PublishAt: utc("2027-03-28T01:00:00Z"), Timezone: "Europe/Amsterdam"},
+		},
+		{
+			name: "offset settles the autumn overlap",
+			req:  VideoScheduleRequest{PublishAt: "2027-10-31T02:30:00+01:00", Timezone: "Europe/Amsterdam"},
+			want: domain.VideoSchedule{PublishAt: utc("2027-10-31T01:30:00Z"), Timezone: "Europe/Amsterdam"},
+		},
+		{
+			name:    "skipped by the spring gap",
+			req:     VideoScheduleRequest{PublishAt: "2027-03-28T02:30", Timezone: "Europe/Amsterdam"},
+			wantErr: "publish_at 2027-03-28T02:30 does not exist in Europe/Amsterdam",
+		},
+		{
+			name:    "skipped by the spring gap in New York",
+			req:     VideoScheduleRequest{UnpublishAt: "2027-03-14T02:15:00", Timezone: "America/New_York"},
+			wantErr: "unpublish_at 2027-03-14T02:15:00 does not exist in America/New_York",
+		},
+		{
+			name:    "repeated in the autumn overlap",
+			req:     VideoScheduleRequest{PublishAt: "2027-10-31T02:30", Timezone: "Europe/Amsterdam"},
+			wantErr: "publish_at 2027-10-31T02:30 occurs twice in Europe/Amsterdam",
+		},
+		{
+			name:    "unparseable",
+			req:     VideoScheduleRequest{PublishAt: "next tuesday"},
+			wantErr: "publish_at must be an RFC 3339 time or a local time",
+		},
+		{
+			name:    "unknown timezone",
+			req:     VideoScheduleRequest{PublishAt: "2027-07-01T08:00", Timezone: "Europe/Atlantis"},
+			wantErr: "unknown timezone",
+		},
+		{
+			name:    "server local timezone",
+			req:     VideoScheduleRequest{PublishAt: "2027-07-01T08:00", Timezone: "Local"},
+			wantErr: "unknown timezone",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.req.toDomain()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if !equalTimes(got.PublishAt, tt.want.PublishAt) || !equalTimes(got.UnpublishAt, tt.want.UnpublishAt) || got.Timezone != tt.want.Timezone {
+				t.Errorf("schedule = %s, want %s", formatSchedule(got), formatSchedule(tt.want))
+			}
+		})
+	}
+}
+
+func TestVideoScheduleRequestOrder(t *testing.T) {
+	// Both are 02:00 UTC, given in different zones
+	req := VideoScheduleRequest{PublishAt: "2027-07-01T04:00", UnpublishAt: "2027-07-01T02:00:00Z", Timezone: "Europe/Amsterdam"}
+	schedule, err := req.toDomain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := schedule.Validate(); !errors.Is(err, domain.ErrInvalidSchedule) {
+		t.Errorf("Validate() error = %v, want ErrInvalidSchedule", err)
+	}
+}
+
+func TestNewVideoScheduleDTOUsesScheduleZone(t *testing.T) {
+	publishAt := time.Date(2027, 7, 1, 6, 0, 0, 0, time.UTC)
+	dto := newVideoScheduleDTO(domain.VideoSchedule{PublishAt: &publishAt, Timezone: "Europe/Amsterdam"})
+	if got := dto.PublishAt.Format(time.RFC3339); got != "2027-07-01T08:00:00+02:00" {
+		t.Errorf("publish_at = %s, want it in Europe/Amsterdam", got)
+	}
+	if dto := newVideoScheduleDTO(domain.VideoSchedule{}); dto != nil {
+		t.Errorf("empty schedule rendered as %+v, want it left out", dto)
+	}
+}
+
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func formatSchedule(s domain.VideoSchedule) string {
+	format := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	return format(s.PublishAt) + " to " + format(s.UnpublishAt) + " in " + s.Timezone
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+)
+
+// PolicyHandler lets admins dry-run the access policy against a hypothetical
+// subject, to explain or try out a decision without making the request.
+type PolicyHandler struct {
+	policy ports.PolicyEngine
+	videos ports.VideoService
+	audit  ports.AuditLog
+}
+
+// EvaluatePolicyRequest describes the resource either by VideoID, whose stored
+// attributes are used, or by explicit Resource attributes.
+type EvaluatePolicyRequest struct {
+	Subject  domain.Attributes `json:"subject"`
+	Action   domain.Action     `json:"action"`
+	VideoID  string            `json:"video_id"`
+	Resource domain.Attributes `json:"resource"`
+}
+
+func NewPolicyHandler(policy ports.PolicyEngine, videos ports.VideoService, audit ports.AuditLog) *PolicyHandler {
+	return &PolicyHandler{
+		policy: policy,
+		videos: videos,
+		audit:  audit,
+	}
+}
+
+func (h *PolicyHandler) Evaluate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req EvaluatePolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(domain.Actions, req.Action) {
+		http.Error(w, "Unknown action", http.StatusBadRequest)
+		return
+	}
+	if req.VideoID != "" && req.Resource != nil {
+		http.Error(w, "Set either video_id or resource, not both", http.StatusBadRequest)
+		return
+	}
+
+	resource := req.Resource
+	if req.VideoID != "" {
+		video, err := h.videos.GetVideoByID(r.Context(), req.VideoID)
+		if err != nil {
+			if !writeAccessError(w, err) {
+				http.Error(w, "Video not found", http.StatusNotFound)
+			}
+			return
+		}
+		resource = video.Attributes()
+	}
+
+	decision := h.policy.Evaluate(r.Context(), domain.AccessRequest{
+		Subject:  req.Subject,
+		Action:   req.Action,
+		Resource: resource,
+	})
+
+	role, _ := req.Subject["role"].(string)
+	recordAudit(h.audit, r, "auth.policy.evaluate", string(req.Action), domain.AuditSuccess, map[string]string{
+		"role":     role,
+		"video_id": req.VideoID,
+		"allowed":  strconv.FormatBool(decision.Allowed),
+		"rule":     decision.RuleID,
+	})
+
+	writeJSON(w, r, http.StatusOK, decision)
+}
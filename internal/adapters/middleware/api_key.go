@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/metrics"
+)
+
+var apiKeyAuthentications = metrics.NewCounterVec(
+	"auth_api_key_authentications_total",
+	"API key authentication attempts by outcome.",
+	"outcome",
+)
+
+// APIKeyHeader carries the raw API key of a machine client.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyRole is the role of every API key principal. The core services further
+// limit a key to the actions named by its scopes.
+const APIKeyRole = "API_KEY"
+
+// AcceptAPIKeys lets requests without a bearer token authenticate with an
+// X-API-Key header. It must be called before serving.
+func (m *AuthMiddleware) AcceptAPIKeys(apiKeys ports.APIKeyService) {
+	m.apiKeys = apiKeys
+}
+
+// apiKeyOutcome labels an Authenticate error for metrics and logs.
+func apiKeyOutcome(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, domain.ErrAPIKeyExpired):
+		return "expired"
+	case errors.Is(err, domain.ErrInvalidAPIKey):
+		return "invalid"
+	default:
+		return "error"
+	}
+}
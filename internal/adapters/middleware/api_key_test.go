@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/services"
+)
+
+// fakeAPIKeys answers Authenticate from a fixed table of raw keys.
+type fakeAPIKeys struct {
+	keys map[string]*domain.APIKey
+	err  error
+}
+
+func (f *fakeAPIKeys) CreateAPIKey(context.Context, string, []domain.APIKeyScope, *time.Time, string) (string, *domain.APIKey, error) {
+	return "", nil, errors.New("not implemented")
+}
+
+func (f *fakeAPIKeys) Authenticate(ctx context.Context, rawKey string) (*domain.APIKey, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	key, ok := f.keys[rawKey]
+	if !ok {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	if key.Expired(time.Now()) {
+		return nil, domain.ErrAPIKeyExpired
+	}
+	return key, nil
+}
+
+func (f *fakeAPIKeys) ListAPIKeys(context.Context) ([]domain.APIKey, error) { return nil, nil }
+
+func (f *fakeAPIKeys) DeleteAPIKey(context.Context, string) error { return nil }
+
+func withAPIKey(method, rawKey string) *http.Request {
+	r := httptest.NewRequest(method, "/api/media/videos", nil)
+	r.Header.Set(APIKeyHeader, rawKey)
+	return r
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	apiKeys := &fakeAPIKeys{keys: map[string]*domain.APIKey{
+		"bkm_1_reader": {ID: "1", Name: "analytics", Scopes: []domain.APIKeyScope{domain.ScopeVideosRead}},
+		"bkm_2_old":    {ID: "2", Name: "retired", Scopes: []domain.APIKeyScope{domain.ScopeVideosRead}, ExpiresAt: &expired},
+	}}
+	m, _ := newTestAuth(t)
+	m.AcceptAPIKeys(apiKeys)
+
+	rec, principal := serve(m, withAPIKey(http.MethodGet, "bkm_1_reader"))
+	if rec.Code != http.StatusNoContent || principal == nil {
+		t.Fatalf("valid key = %d", rec.Code)
+	}
+	want := domain.Principal{ID: "apikey:1", Role: APIKeyRole, AuthMethod: domain.AuthMethodAPIKey}
+	if principal.ID != want.ID || principal.Role != want.Role || principal.AuthMethod != want.AuthMethod || principal.Attributes["api_key_name"] != "analytics" {
+		t.Errorf("principal = %+v, want %+v", principal, want)
+	}
+
+	for _, tt := range []struct {
+		name string
+		raw  string
+		err  error
+		want int
+	}{
+		{"unknown key", "bkm_9_nope", nil, http.StatusUnauthorized},
+		{"expired key", "bkm_2_old", nil, http.StatusUnauthorized},
+		{"store unavailable", "bkm_1_reader", errors.New("connection reset"), http.StatusServiceUnavailable},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			apiKeys.err = tt.err
+			defer func() { apiKeys.err = nil }()
+			if rec, seen := serve(m, withAPIKey(http.MethodGet, tt.raw)); rec.Code != tt.want || seen != nil {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	// A bearer token takes precedence: the API key is not consulted
+	apiKeys.err = errors.New("must not be called")
+	r := withAPIKey(http.MethodGet, "bkm_1_reader")
+	r.Header.Set("Authorization", "Bearer not-a-jwt")
+	if rec, _ := serve(m, r); rec.Code != http.StatusUnauthorized {
+		t.Errorf("API key with an invalid bearer token = %d, want 401 from the token check", rec.Code)
+	}
+}
+
+// TestAPIKeyPrincipalAccess checks the principal built from an X-API-Key
+// against the default policy: it may read published videos and nothing else,
+// whatever scopes the key claims.
+func TestAPIKeyPrincipalAccess(t *testing.T) {
+	engine, err := services.NewPolicyEngine(services.DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	everyScope := make([]domain.APIKeyScope, 0, len(domain.Actions))
+	for _, action := range domain.Actions {
+		everyScope = append(everyScope, domain.APIKeyScope(action))
+	}
+	apiKeys := &fakeAPIKeys{keys: map[string]*domain.APIKey{
+		"bkm_1_reader":     {ID: "1", Scopes: []domain.APIKeyScope{domain.ScopeVideosRead}},
+		"bkm_2_everything": {ID: "2", Scopes: everyScope},
+		"bkm_3_unscoped":   {ID: "3"},
+	}}
+	m, _ := newTestAuth(t)
+	m.AcceptAPIKeys(apiKeys)
+
+	tests := []struct {
+		raw     string
+		allowed []domain.Action
+	}{
+		{"bkm_1_reader", []domain.Action{domain.ActionVideoRead}},
+		// Scopes only narrow what the policy grants the API_KEY role
+		{"bkm_2_everything", []domain.Action{domain.ActionVideoRead}},
+		{"bkm_3_unscoped", nil},
+	}
+	for _, tt := range tests {
+		_, principal := serve(m, withAPIKey(http.MethodGet, tt.raw))
+		if principal == nil {
+			t.Fatalf("%s not authenticated", tt.raw)
+		}
+		for _, action := range domain.Actions {
+			want := false
+			for _, a := range tt.allowed {
+				want = want || a == action
+			}
+			// The checks VideoService.authorize makes for a published video
+			got := principal.HasScope(action) && engine.Evaluate(context.Background(), domain.AccessRequest{
+				Subject:  principal.AccessAttributes(),
+				Action:   action,
+				Resource: domain.Video{ID: "v1", Status: domain.StatusPublished}.Attributes(),
+			}).Allowed
+			if got != want {
+				t.Errorf("%s %s allowed = %v, want %v", tt.raw, action, got, want)
+			}
+		}
+	}
+}
@@ -2,36 +2,98 @@ package middleware
 
 import (
 	"context"
-	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/cache"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/keys"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/metrics"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/tracing"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/redis/go-redis/v9"
 )
 
+var (
+	jwtCacheHits = metrics.NewCounter(
+		"auth_jwt_cache_hits_total",
+		"Token validations served from the L1 claims cache.",
+	)
+	jwtCacheMisses = metrics.NewCounter(
+		"auth_jwt_cache_misses_total",
+		"Token validations that required full signature verification.",
+	)
+	jwtCacheEvictions = metrics.NewCounterVec(
+		"auth_jwt_cache_evictions_total",
+		"Entries removed from the L1 claims cache by reason.",
+		"reason",
+	)
+	jwtCacheEntries = metrics.NewGauge(
+		"auth_jwt_cache_entries",
+		"Entries currently held in the L1 claims cache.",
+	)
+	jwtCacheCapacity = metrics.NewGauge(
+		"auth_jwt_cache_capacity",
+		"Maximum number of entries the L1 claims cache can hold.",
+	)
+)
+
+// cacheEntry is keyed by jti; the token hash guards against a forged token
+// that reuses the jti of a cached one.
 type cacheEntry struct {
-	claims jwt.MapClaims
-	exp    int64
+	claims    jwt.MapClaims
+	tokenHash [sha256.Size]byte
+}
+
+// CacheConfig sizes the L1 claims cache.
+type CacheConfig struct {
+	Capacity int
+	Shards   int
 }
 
 type AuthMiddleware struct {
-	publicKey   *rsa.PublicKey
-	cache       sync.Map
-	redisClient *redis.Client
+	keys        keys.Provider
+	policy      TokenPolicy
+	cache       *cache.LRU[cacheEntry]
+	revocations *RevocationChecker
+	services    *ServiceIdentities
+	apiKeys     ports.APIKeyService
+	// impersonation is nil unless X-Act-As is allowed
+	impersonation *Impersonation
+	stop          chan struct{}
+	stopOnce      sync.Once
 }
 
 const CacheCleanupInterval = 10 * time.Minute
 
-func NewAuthMiddleware(publicKey *rsa.PublicKey, redisClient *redis.Client) *AuthMiddleware {
+func NewAuthMiddleware(keyProvider keys.Provider, revocations *RevocationChecker, policy TokenPolicy, cacheConfig CacheConfig) *AuthMiddleware {
 	m := &AuthMiddleware{
-		publicKey:   publicKey,
-		redisClient: redisClient,
+		keys:        keyProvider,
+		policy:      policy,
+		revocations: revocations,
+		stop:        make(chan struct{}),
 	}
+	// Explicit removals are labelled by the caller (revoked, key_rotation)
+	m.cache = cache.New[cacheEntry](cacheConfig.Capacity, cacheConfig.Shards, func(_ string, reason cache.EvictReason) {
+		if reason != cache.EvictRemoved {
+			jwtCacheEvictions.WithLabelValues(string(reason)).Inc()
+		}
+	})
+	jwtCacheCapacity.Set(float64(m.cache.Stats().Capacity))
+
+	// Revocations announced by any replica evict the token here immediately
+	revocations.OnRevoke(m.evictRevoked)
+	revocations.OnResync(func() {
+		m.flush("resync")
+	})
 
 	// Start Background Janitor to sweep L1 cache every 10 minutes
 	go m.startJanitor(CacheCleanupInterval)
@@ -41,117 +103,332 @@ func NewAuthMiddleware(publicKey *rsa.PublicKey, redisClient *redis.Client) *Aut
 
 type contextKey string
 
-const (
-	UserIDKey contextKey = "userID"
-	RoleKey   contextKey = "role"
-	TokenKey  contextKey = "token"
-)
+// TokenKey holds the caller's raw bearer token; the caller itself is stored as a
+// domain.Principal.
+const TokenKey contextKey = "token"
 
-func (m *AuthMiddleware) RequireRole(roles []string, next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now() // start time for processing time measurement
+// Token is the raw bearer token stored under TokenKey. It redacts itself when
+// printed or logged; use Value to forward it to another service.
+type Token string
 
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			log.Printf("Missing Authorization header")
-			http.Error(w, "missing authorization header", http.StatusUnauthorized)
-			return
-		}
+func (t Token) Value() string {
+	return string(t)
+}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+func (t Token) String() string {
+	return "[REDACTED]"
+}
 
-		// Peek and L1 cache check
-		claims, jti, err := m.getClaimsFromCacheOrParse(tokenString)
-		if err != nil {
-			log.Printf("Token parse error: %v", err)
-			http.Error(w, "invalid token", http.StatusUnauthorized)
+func (t Token) GoString() string {
+	return t.String()
+}
+
+func (t Token) LogValue() slog.Value {
+	return slog.StringValue(t.String())
+}
+
+// TrustServices lets requests without a bearer token authenticate with a verified
+// client certificate mapped to one of ids. It must be called before serving.
+func (m *AuthMiddleware) TrustServices(ids *ServiceIdentities) {
+	m.services = ids
+}
+
+// Authenticate identifies the caller by client certificate, API key or bearer
+// token and stores it as a domain.Principal in the request context. Whether the
+// principal may perform the operation is left to the core services.
+func (m *AuthMiddleware) Authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := m.authenticate(w, r)
+		if !ok {
 			return
 		}
+		next(w, r.WithContext(ctx))
+	}
+}
 
-		// L2 Redis blacklist check (Kill-Switch)
-		isRevoked, err := m.redisClient.Exists(r.Context(), "blacklist:"+jti).Result()
-		if err == nil && isRevoked > 0 {
-			m.cache.Delete(jti)
-			log.Printf("Rejected: JTI %s is blacklisted", jti)
-			http.Error(w, "token revoked", http.StatusUnauthorized)
+// RequireRole authenticates like Authenticate and also rejects principals whose
+// role is not in roles. It guards endpoints, such as the admin API, that are not
+// backed by a core service enforcing the access policy.
+func (m *AuthMiddleware) RequireRole(roles []string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := m.authenticate(w, r)
+		if !ok {
 			return
 		}
 
-		userRole, _ := claims["role"].(string)
-		if !m.isAuthorized(userRole, roles) {
+		principal, _ := domain.PrincipalFromContext(ctx)
+		if !m.isAuthorized(principal.Role, roles) {
+			slog.WarnContext(r.Context(), "role mismatch", "required", roles, "role", principal.Role, "principal", principal.ID)
 			http.Error(w, "forbidden", http.StatusForbidden)
 			return
 		}
-		userID, _ := claims["sub"].(string)
 
-		log.Printf("Token validated - UserID: %s, Role: %s", userID, userRole)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// authenticate returns the request context carrying the caller's principal, or
+// the impersonated one when X-Act-As is set. When the caller cannot be
+// authenticated it writes the error response and returns false.
+func (m *AuthMiddleware) authenticate(w http.ResponseWriter, r *http.Request) (context.Context, bool) {
+	ctx, ok := m.identify(w, r)
+	if !ok {
+		return nil, false
+	}
+	if subject := r.Header.Get(ActAsHeader); subject != "" {
+		return m.actAs(w, r, ctx, subject)
+	}
+	return ctx, true
+}
+
+// identify authenticates the caller by client certificate, API key or bearer token.
+func (m *AuthMiddleware) identify(w http.ResponseWriter, r *http.Request) (context.Context, bool) {
+	start := time.Now() // start time for processing time measurement
+
+	authCtx, span := tracing.Start(r.Context(), "auth.Authenticate", tracing.KindInternal)
+	defer span.End()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" && m.services != nil {
+		// A user token always wins, so a service forwarding one acts as that user
+		service, err := m.services.Identify(r)
+		if err == nil {
+			serviceAuthentications.WithLabelValues(service.Name).Inc()
+			return domain.ContextWithPrincipal(r.Context(), domain.Principal{
+				ID:         service.Principal(),
+				Role:       service.Role,
+				AuthMethod: domain.AuthMethodCertificate,
+				Attributes: domain.Attributes{"service": service.Name},
+			}), true
+		}
+		if !errors.Is(err, errNoClientCertificate) {
+			serviceAuthentications.WithLabelValues("unmapped").Inc()
+			slog.WarnContext(r.Context(), "client certificate rejected", "error", err)
+		}
+	}
+	if rawKey := r.Header.Get(APIKeyHeader); authHeader == "" && rawKey != "" && m.apiKeys != nil {
+		key, err := m.apiKeys.Authenticate(authCtx, rawKey)
+		outcome := apiKeyOutcome(err)
+		apiKeyAuthentications.WithLabelValues(outcome).Inc()
+		if outcome == "error" {
+			slog.ErrorContext(r.Context(), "api key lookup failed", "error", err)
+			http.Error(w, "api key check unavailable", http.StatusServiceUnavailable)
+			return nil, false
+		}
+		if err != nil {
+			slog.WarnContext(r.Context(), "api key rejected", "reason", outcome)
+			http.Error(w, "invalid api key", http.StatusUnauthorized)
+			return nil, false
+		}
+
+		return domain.ContextWithPrincipal(r.Context(), domain.Principal{
+			ID:         "apikey:" + key.ID,
+			Role:       APIKeyRole,
+			AuthMethod: domain.AuthMethodAPIKey,
+			Scopes:     key.Scopes,
+			Attributes: domain.Attributes{"api_key_name": key.Name},
+		}), true
+	}
+	if authHeader == "" {
+		slog.WarnContext(r.Context(), "missing authorization header")
+		http.Error(w, "missing authorization header", http.StatusUnauthorized)
+		return nil, false
+	}
 
-		allowedRoles := false
-		for _, r := range roles {
-			if userRole == r {
-				allowedRoles = true
-				break
-			}
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+
+	// Peek and L1 cache check
+	claims, jti, cached, err := m.getClaimsFromCacheOrParse(authCtx, tokenString)
+	if err != nil {
+		reason := rejectionReason(err)
+		tokenRejections.WithLabelValues(reason).Inc()
+		slog.WarnContext(r.Context(), "token rejected", "reason", reason, "error", err)
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	// L2 Redis blacklist check (Kill-Switch). Cached tokens may skip it while
+	// the revocation channel evicts them as soon as they are revoked.
+	var revokedBy RevocationScope
+	if cached && m.revocations.SkipsCachedLookups() {
+		revocationLookupsSkipped.Inc()
+	} else {
+		revokedBy, err = m.revocations.IsRevoked(authCtx, newRevocationTarget(jti, claims))
+	}
+	if err != nil {
+		if !m.revocations.AllowsUnchecked(r.Method) {
+			revocationUnavailable.WithLabelValues("rejected").Inc()
+			slog.ErrorContext(r.Context(), "revocation check unavailable, rejecting request", "error", err)
+			http.Error(w, "token revocation check unavailable", http.StatusServiceUnavailable)
+			return nil, false
 		}
-		if !allowedRoles {
-			log.Printf("Role mismatch: required one of %v, got %s", roles, userRole)
-			http.Error(w, "forbidden", http.StatusForbidden)
-			return
+		revocationUnavailable.WithLabelValues("allowed").Inc()
+		slog.WarnContext(r.Context(), "revocation check unavailable, allowing request", "error", err)
+	}
+	if revokedBy != "" {
+		if m.cache.Delete(jti) {
+			jwtCacheEvictions.WithLabelValues("revoked").Inc()
 		}
+		slog.WarnContext(r.Context(), "token revoked", "jti", jti, "scope", revokedBy)
+		http.Error(w, "token revoked", http.StatusUnauthorized)
+		return nil, false
+	}
 
-		ctx := context.WithValue(r.Context(), UserIDKey, userID)
-		ctx = context.WithValue(ctx, RoleKey, userRole)
-		ctx = context.WithValue(ctx, TokenKey, tokenString)
+	userRole, _ := claims["role"].(string)
+	userID, _ := claims["sub"].(string)
 
-		log.Printf("AuthMiddleware processing time: %v", time.Since(start))
+	slog.DebugContext(r.Context(), "token validated", "user_id", userID, "role", userRole)
 
-		next(w, r.WithContext(ctx))
+	principal := domain.Principal{
+		ID:         userID,
+		Role:       userRole,
+		AuthMethod: domain.AuthMethodToken,
+		Attributes: claimAttributes(claims),
+	}
+	if !cached && m.impersonation != nil {
+		if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+			m.impersonation.remember(authCtx, principal, newRevocationTarget(jti, claims), exp.Time)
+		}
 	}
+
+	ctx := domain.ContextWithPrincipal(r.Context(), principal)
+	ctx = context.WithValue(ctx, TokenKey, Token(tokenString))
+
+	slog.DebugContext(r.Context(), "auth middleware completed", "duration", time.Since(start))
+	return ctx, true
 }
 
-func (m *AuthMiddleware) getClaimsFromCacheOrParse(tokenString string) (jwt.MapClaims, string, error) {
-	// Peek at the JTI without verifying the signature yet
-	parser := new(jwt.Parser)
+// getClaimsFromCacheOrParse returns the verified claims, the jti and whether they
+// were served from the L1 cache.
+func (m *AuthMiddleware) getClaimsFromCacheOrParse(ctx context.Context, tokenString string) (jwt.MapClaims, string, bool, error) {
+	// Peek at the header and claims without verifying the signature yet
+	parser := jwt.NewParser()
 	unverifiedToken, _, err := parser.ParseUnverified(tokenString, jwt.MapClaims{})
 	if err != nil {
-		return nil, "", err
+		return nil, "", false, errors.Join(errMalformedToken, err)
+	}
+
+	// Reject disallowed algorithms before any key lookup or crypto
+	if !m.policy.algorithmAllowed(unverifiedToken.Method.Alg()) {
+		return nil, "", false, errAlgorithmNotAllowed
 	}
 
 	claims, _ := unverifiedToken.Claims.(jwt.MapClaims)
 	jti, _ := claims["jti"].(string)
-	expFloat, _ := claims["exp"].(float64)
-	exp := int64(expFloat)
-
 	if jti == "" {
-		return nil, "", errors.New("missing jti")
+		return nil, "", false, errMissingJTI
 	}
 
-	// Immediate expiry check (Fastest fail)
-	if time.Now().Unix() > exp {
-		return nil, "", errors.New("token expired")
+	// Time-based checks (Fastest fail, also applied to cached tokens)
+	if err := m.policy.validateTimes(claims, time.Now()); err != nil {
+		return nil, "", false, err
 	}
 
-	// L1 Cache Lookup (Keyed by JTI)
-	if entry, ok := m.cache.Load(jti); ok {
-		return entry.(cacheEntry).claims, jti, nil
+	// L1 Cache Lookup (Keyed by JTI, only trusted for the exact same token)
+	tokenHash := sha256.Sum256([]byte(tokenString))
+	if entry, ok := m.cache.Get(jti); ok && subtle.ConstantTimeCompare(entry.tokenHash[:], tokenHash[:]) == 1 {
+		jwtCacheHits.Inc()
+		return entry.claims, jti, true, nil
+	}
+	jwtCacheMisses.Inc()
+
+	// Full signature validation (Cold path)
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods(m.policy.Algorithms),
+		jwt.WithLeeway(m.policy.Leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
 	}
-
-	// Full RSA Validation (Cold path)
 	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (any, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, jwt.ErrSignatureInvalid
+		// Select the key by kid so the identity service can rotate keys without a redeploy
+		kid, _ := t.Header["kid"].(string)
+		candidates, err := m.keys.Lookup(ctx, kid)
+		if err != nil {
+			return nil, err
 		}
-		return m.publicKey, nil
-	})
 
-	if err != nil || !token.Valid {
-		return nil, "", err
+		// Only keys bound to the token's algorithm may verify it (no algorithm confusion)
+		alg := t.Method.Alg()
+		set := jwt.VerificationKeySet{}
+		for _, c := range candidates {
+			if c.Permits(alg) {
+				set.Keys = append(set.Keys, c.Public)
+			}
+		}
+		switch len(set.Keys) {
+		case 0:
+			return nil, errKeyAlgorithmMismatch
+		case 1:
+			return set.Keys[0], nil
+		default:
+			// During a key rotation overlap a signature from any candidate is accepted
+			return set, nil
+		}
+	}, parserOptions...)
+
+	if err != nil {
+		return nil, "", false, err
+	}
+	if !token.Valid {
+		return nil, "", false, jwt.ErrTokenSignatureInvalid
+	}
+
+	if err := m.policy.validateIssuerAndAudience(claims); err != nil {
+		return nil, "", false, err
 	}
 
-	// Store in Cache
-	m.cache.Store(jti, cacheEntry{claims: claims, exp: exp})
+	// Store in Cache until the token expires
+	exp, _ := claims.GetExpirationTime()
+	m.cache.Set(jti, cacheEntry{claims: claims, tokenHash: tokenHash}, exp.Time)
+	jwtCacheEntries.Set(float64(m.cache.Len()))
 
-	return claims, jti, nil
+	return claims, jti, false, nil
+}
+
+func newRevocationTarget(jti string, claims jwt.MapClaims) revocationTarget {
+	t := revocationTarget{JTI: jti}
+	t.Subject, _ = claims["sub"].(string)
+	t.SessionID, _ = claims["sid"].(string)
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		t.IssuedAt = iat.Time
+	}
+	return t
+}
+
+// evictRevoked drops the cached claims covered by a revocation announced on the channel.
+func (m *AuthMiddleware) evictRevoked(revocation Revocation) {
+	if revocation.Scope == ScopeToken {
+		if m.cache.Delete(revocation.ID) {
+			jwtCacheEvictions.WithLabelValues("revoked").Inc()
+		}
+		return
+	}
+
+	claim := string(revocation.Scope)
+	evicted := 0
+	m.cache.Range(func(jti string, entry cacheEntry, _ time.Time) bool {
+		if id, _ := entry.claims[claim].(string); id == revocation.ID && m.cache.Delete(jti) {
+			evicted++
+		}
+		return true
+	})
+	jwtCacheEvictions.WithLabelValues("revoked").Add(float64(evicted))
+}
+
+// registeredClaims are checked by token validation and are not useful to the
+// access policy.
+var registeredClaims = []string{"iss", "aud", "exp", "nbf", "iat", "jti"}
+
+// claimAttributes keeps the remaining token claims as principal attributes, which
+// the access policy sees as "subject.<claim>", e.g. clinic_id or baby_age_group.
+func claimAttributes(claims jwt.MapClaims) domain.Attributes {
+	attrs := make(domain.Attributes, len(claims))
+	for name, value := range claims {
+		if !slices.Contains(registeredClaims, name) {
+			attrs[name] = value
+		}
+	}
+	return attrs
 }
 
 func (m *AuthMiddleware) isAuthorized(userRole string, allowedRoles []string) bool {
@@ -167,18 +444,112 @@ func (m *AuthMiddleware) startJanitor(interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		now := time.Now().Unix()
-		deleted := 0
-		m.cache.Range(func(key, value any) bool {
-			if entry, ok := value.(cacheEntry); ok && now > entry.exp {
-				m.cache.Delete(key)
-				deleted++
-			}
-			return true
-		})
-		if deleted > 0 {
-			log.Printf("L1 Janitor: Purged %d expired entries", deleted)
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
 		}
+
+		// Expired entries are also dropped on access; this reclaims tokens never seen again
+		if deleted := m.cache.PurgeExpired(); deleted > 0 {
+			slog.Info("L1 janitor purged expired entries", "count", deleted)
+		}
+		jwtCacheEntries.Set(float64(m.cache.Len()))
+	}
+}
+
+// FlushCache drops every cached claim set so the next request for each token is
+// verified again against the current keys (called on key rotation).
+func (m *AuthMiddleware) FlushCache() {
+	m.flush("key_rotation")
+}
+
+func (m *AuthMiddleware) flush(reason string) {
+	flushed := m.cache.Clear()
+	jwtCacheEvictions.WithLabelValues(reason).Add(float64(flushed))
+	jwtCacheEntries.Set(0)
+	slog.Info("L1 claims cache flushed", "reason", reason, "count", flushed)
+}
+
+// CachedPrincipal describes a verified token held in the L1 claims cache.
+type CachedPrincipal struct {
+	JTI       string
+	Subject   string
+	Role      string
+	SessionID string
+	ExpiresAt time.Time
+}
+
+// CachedPrincipals lists the tokens currently cached on this replica.
+func (m *AuthMiddleware) CachedPrincipals() []CachedPrincipal {
+	var principals []CachedPrincipal
+	m.cache.Range(func(jti string, entry cacheEntry, expiresAt time.Time) bool {
+		principals = append(principals, newCachedPrincipal(jti, entry, expiresAt))
+		return true
+	})
+	return principals
+}
+
+// CachedPrincipal returns the cached token with the given jti, if any.
+func (m *AuthMiddleware) CachedPrincipal(jti string) (CachedPrincipal, bool) {
+	entry, expiresAt, ok := m.cache.Peek(jti)
+	if !ok {
+		return CachedPrincipal{}, false
 	}
+	return newCachedPrincipal(jti, entry, expiresAt), true
+}
+
+var (
+	ErrRevocationTokenInvalid = errors.New("token cannot be verified")
+	ErrRevocationJTIMismatch  = errors.New("jti does not match the token")
+)
+
+// RevocationExpiry returns the jti to revoke and how long it must stay
+// blacklisted. The expiry comes from the verified token when it is given or
+// cached on this replica, plus the leeway it is still accepted for. A bare jti
+// is blacklisted for the longest token lifetime; an expiry supplied by the
+// caller is never trusted, so a revocation cannot be cut short.
+func (m *AuthMiddleware) RevocationExpiry(ctx context.Context, jti, tokenString string) (string, time.Time, error) {
+	if tokenString != "" {
+		claims, tokenJTI, _, err := m.getClaimsFromCacheOrParse(ctx, tokenString)
+		if errors.Is(err, errTokenExpired) || errors.Is(err, jwt.ErrTokenExpired) {
+			return "", time.Time{}, ErrTokenAlreadyExpired
+		}
+		if err != nil {
+			return "", time.Time{}, errors.Join(ErrRevocationTokenInvalid, err)
+		}
+		if jti != "" && jti != tokenJTI {
+			return "", time.Time{}, ErrRevocationJTIMismatch
+		}
+		exp, _ := claims.GetExpirationTime()
+		return tokenJTI, exp.Add(m.policy.Leeway), nil
+	}
+	if _, expiresAt, ok := m.cache.Peek(jti); ok {
+		return jti, expiresAt.Add(m.policy.Leeway), nil
+	}
+	return jti, time.Now().Add(m.revocations.MaxTokenLifetime()), nil
+}
+
+func newCachedPrincipal(jti string, entry cacheEntry, expiresAt time.Time) CachedPrincipal {
+	p := CachedPrincipal{JTI: jti, ExpiresAt: expiresAt}
+	p.Subject, _ = entry.claims["sub"].(string)
+	p.Role, _ = entry.claims["role"].(string)
+	p.SessionID, _ = entry.claims["sid"].(string)
+	return p
+}
+
+// CacheCheck reports the L1 claims cache occupancy and hit ratio. It never fails;
+// a cold or thrashing cache only costs latency.
+func (m *AuthMiddleware) CacheCheck(ctx context.Context) (string, error) {
+	stats := m.cache.Stats()
+	return fmt.Sprintf("%d/%d entries, hit ratio %.2f, %d expired, %d evicted for capacity",
+		stats.Entries, stats.Capacity, stats.HitRatio(), stats.Expired, stats.Evicted), nil
+}
+
+// Close stops the background janitor. It is safe to call more than once.
+func (m *AuthMiddleware) Close() {
+	m.stopOnce.Do(func() {
+		close(m.stop)
+	})
 }
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/keys"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	testLeeway      = 30 * time.Second
+	testMaxLifetime = 24 * time.Hour
+)
+
+func newTestAuth(t *testing.T) (*AuthMiddleware, *ecdsa.PrivateKey) {
+	t.Helper()
+	// Redis is never reached: RevocationExpiry only reads the configuration
+	return newTestAuthWith(t, redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"}), RevocationConfig{
+		BreakerThreshold: 1,
+		WatermarkTTL:     testMaxLifetime,
+	})
+}
+
+// newTestAuthWith builds a middleware that checks revocations against client.
+func newTestAuthWith(t *testing.T, client *redis.Client, config RevocationConfig) (*AuthMiddleware, *ecdsa.PrivateKey) {
+	t.Helper()
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revocations := NewRevocationChecker(client, config)
+	t.Cleanup(revocations.Close)
+	m := NewAuthMiddleware(keys.NewStaticProvider(&signer.PublicKey), revocations, TokenPolicy{
+		Algorithms: []string{"ES256"},
+		Leeway:     testLeeway,
+	}, CacheConfig{Capacity: 16, Shards: 1})
+	t.Cleanup(m.Close)
+	return m, signer
+}
+
+// newMiniredis starts an in-memory Redis and a client connected to it.
+func newMiniredis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	// No retries, so tests that stop the server fail fast
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1, DialerRetries: 1})
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+func signToken(t *testing.T, key *ecdsa.PrivateKey, jti string, exp time.Time) string {
+	t.Helper()
+	return signClaims(t, key, jwt.MapClaims{
+		"sub": "user-1",
+		"jti": jti,
+		"iat": exp.Add(-time.Hour).Unix(),
+		"exp": exp.Unix(),
+	})
+}
+
+func signClaims(t *testing.T, key *ecdsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// serve runs the request through Authenticate and returns the response
+// together with the principal the next handler saw, if it was reached.
+func serve(m *AuthMiddleware, r *http.Request) (*httptest.ResponseRecorder, *domain.Principal) {
+	var seen *domain.Principal
+	rec := httptest.NewRecorder()
+	m.Authenticate(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := domain.PrincipalFromContext(r.Context())
+		seen = &principal
+		w.WriteHeader(http.StatusNoContent)
+	})(rec, r)
+	return rec, seen
+}
+
+func bearer(method, token string) *http.Request {
+	r := httptest.NewRequest(method, "/api/media/videos", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestRevocationExpiry(t *testing.T) {
+	m, signer := newTestAuth(t)
+	forger, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	exp := time.Now().Add(10 * time.Minute).Truncate(time.Second)
+
+	cached := signToken(t, signer, "cached", exp)
+	if _, _, err := m.RevocationExpiry(context.Background(), "", cached); err != nil {
+		t.Fatalf("verifying the cached token: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		jti     string
+		token   string
+		wantJTI string
+		// wantExp is zero when the expiry is the maximum token lifetime from now
+		wantExp time.Time
+		wantErr error
+	}{
+		{"token sets jti and expiry", "", signToken(t, signer, "a", exp), "a", exp.Add(testLeeway), nil},
+		{"token with matching jti", "a", signToken(t, signer, "a", exp), "a", exp.Add(testLeeway), nil},
+		{"token with other jti", "b", signToken(t, signer, "a", exp), "", time.Time{}, ErrRevocationJTIMismatch},
+		{"expired token", "", signToken(t, signer, "old", time.Now().Add(-time.Hour)), "", time.Time{}, ErrTokenAlreadyExpired},
+		{"forged token", "", signToken(t, forger, "forged", exp), "", time.Time{}, ErrRevocationTokenInvalid},
+		{"cached jti", "cached", "", "cached", exp.Add(testLeeway), nil},
+		{"unknown jti", "unknown", "", "unknown", time.Time{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now()
+			jti, expiresAt, err := m.RevocationExpiry(context.Background(), tt.jti, tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if jti != tt.wantJTI {
+				t.Errorf("jti = %q, want %q", jti, tt.wantJTI)
+			}
+			if tt.wantExp.IsZero() {
+				if expiresAt.Before(before.Add(testMaxLifetime)) || expiresAt.After(time.Now().Add(testMaxLifetime)) {
+					t.Errorf("expires at %v, want the maximum token lifetime from now", expiresAt)
+				}
+			} else if !expiresAt.Equal(tt.wantExp) {
+				t.Errorf("expires at %v, want %v", expiresAt, tt.wantExp)
+			}
+		})
+	}
+}
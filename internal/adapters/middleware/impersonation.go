@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/logging"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+var impersonations = metrics.NewCounterVec(
+	"auth_impersonations_total",
+	"Requests carrying X-Act-As by outcome.",
+	"outcome",
+)
+
+// ActAsHeader names the subject an admin wants to act as.
+const ActAsHeader = "X-Act-As"
+
+// Only token-authenticated admins whose permissions claim lists
+// ImpersonatePermission may impersonate.
+const (
+	PermissionsClaim      = "permissions"
+	ImpersonatePermission = "impersonate"
+	impersonatorRole      = "ADMIN"
+)
+
+// principalSnapshotPrefix keys the role and claims of each subject's most recent token.
+const principalSnapshotPrefix = "principal:"
+
+// snapshotWriteTimeout bounds the background write of a principal snapshot.
+const snapshotWriteTimeout = 2 * time.Second
+
+var errUnknownSubject = errors.New("no recent token for subject")
+
+// principalSnapshot also identifies the token it was taken from, so that
+// revoking the token, its session or its subject also ends impersonation.
+type principalSnapshot struct {
+	Role       string            `json:"role"`
+	Attributes domain.Attributes `json:"attributes"`
+	JTI        string            `json:"jti"`
+	SessionID  string            `json:"sid,omitempty"`
+	IssuedAt   int64             `json:"iat,omitempty"`
+}
+
+// Impersonation lets support staff see exactly what a subject sees. The subject's
+// role and claims come from a snapshot of their most recent token, which every
+// replica writes to Redis the first time it verifies that token, so a subject can
+// be impersonated while they hold an unexpired token.
+type Impersonation struct {
+	redisClient *redis.Client
+	audit       ports.AuditLog
+}
+
+func NewImpersonation(redisClient *redis.Client, audit ports.AuditLog) *Impersonation {
+	return &Impersonation{
+		redisClient: redisClient,
+		audit:       audit,
+	}
+}
+
+// AllowImpersonation enables the X-Act-As header. It must be called before
+// serving; without it the header is rejected.
+func (m *AuthMiddleware) AllowImpersonation(impersonation *Impersonation) {
+	m.impersonation = impersonation
+}
+
+// remember snapshots principal until its token expires. It runs in the
+// background and a failed write only costs the ability to impersonate.
+func (i *Impersonation) remember(ctx context.Context, principal domain.Principal, token revocationTarget, expiresAt time.Time) {
+	ttl := time.Until(expiresAt)
+	if principal.ID == "" || ttl <= 0 {
+		return
+	}
+	snapshot := principalSnapshot{
+		Role:       principal.Role,
+		Attributes: principal.Attributes,
+		JTI:        token.JTI,
+		SessionID:  token.SessionID,
+	}
+	if !token.IssuedAt.IsZero() {
+		snapshot.IssuedAt = token.IssuedAt.Unix()
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to encode principal snapshot", "error", err)
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), snapshotWriteTimeout)
+		defer cancel()
+		if err := i.redisClient.Set(ctx, principalSnapshotPrefix+principal.ID, data, ttl).Err(); err != nil {
+			slog.WarnContext(ctx, "failed to store principal snapshot", "error", err)
+		}
+	}()
+}
+
+// lookup returns the subject's snapshot and the token it was taken from.
+func (i *Impersonation) lookup(ctx context.Context, subject string) (domain.Principal, revocationTarget, error) {
+	data, err := i.redisClient.Get(ctx, principalSnapshotPrefix+subject).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return domain.Principal{}, revocationTarget{}, errUnknownSubject
+	}
+	if err != nil {
+		return domain.Principal{}, revocationTarget{}, err
+	}
+
+	var snapshot principalSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return domain.Principal{}, revocationTarget{}, err
+	}
+	token := revocationTarget{JTI: snapshot.JTI, Subject: subject, SessionID: snapshot.SessionID}
+	if snapshot.IssuedAt != 0 {
+		token.IssuedAt = time.Unix(snapshot.IssuedAt, 0)
+	}
+	return domain.Principal{
+		ID:         subject,
+		Role:       snapshot.Role,
+		AuthMethod: domain.AuthMethodToken,
+		Attributes: snapshot.Attributes,
+	}, token, nil
+}
+
+// actAs replaces the authenticated principal in ctx with subject, keeping the
+// real caller as its Impersonator. Only reads are allowed, and every attempt is
+// audited with the real caller as actor.
+func (m *AuthMiddleware) actAs(w http.ResponseWriter, r *http.Request, ctx context.Context, subject string) (context.Context, bool) {
+	actor, _ := domain.PrincipalFromContext(ctx)
+
+	reject := func(outcome string, status int, msg string) (context.Context, bool) {
+		impersonations.WithLabelValues(outcome).Inc()
+		slog.WarnContext(r.Context(), "impersonation rejected", "actor", actor.ID, "subject", subject, "reason", outcome)
+		if m.impersonation != nil {
+			m.impersonation.record(r, actor, subject, domain.AuditFailure, outcome)
+		}
+		http.Error(w, msg, status)
+		return nil, false
+	}
+
+	if m.impersonation == nil {
+		return reject("disabled", http.StatusForbidden, "impersonation is disabled")
+	}
+	if actor.AuthMethod != domain.AuthMethodToken || actor.Role != impersonatorRole || !hasPermission(actor, ImpersonatePermission) {
+		return reject("not_permitted", http.StatusForbidden, "impersonation not permitted")
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions {
+		return reject("write_blocked", http.StatusForbidden, "write operations are not allowed while impersonating")
+	}
+
+	principal, token, err := m.impersonation.lookup(ctx, subject)
+	if errors.Is(err, errUnknownSubject) {
+		return reject("unknown_subject", http.StatusNotFound, "subject has no active session to impersonate")
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "principal snapshot lookup failed", "error", err)
+		return reject("error", http.StatusServiceUnavailable, "impersonation unavailable")
+	}
+	// The snapshot outlives revocation of its token, so check it like the token.
+	// Unlike the subject's own reads, this never falls back to the failure policy.
+	revokedBy, err := m.revocations.IsRevoked(ctx, token)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "revocation check for impersonated subject failed", "error", err)
+		return reject("error", http.StatusServiceUnavailable, "impersonation unavailable")
+	}
+	if revokedBy != "" {
+		return reject("revoked", http.StatusNotFound, "subject has no active session to impersonate")
+	}
+
+	principal.Impersonator = &actor
+	impersonations.WithLabelValues("allowed").Inc()
+	m.impersonation.record(r, actor, subject, domain.AuditSuccess, "")
+	slog.InfoContext(r.Context(), "impersonating subject", "actor", actor.ID, "subject", subject, "role", principal.Role)
+
+	ctx = domain.ContextWithPrincipal(ctx, principal)
+	// The admin's own token must not be forwarded on the subject's behalf
+	ctx = context.WithValue(ctx, TokenKey, Token(""))
+	return ctx, true
+}
+
+func (i *Impersonation) record(r *http.Request, actor domain.Principal, subject string, outcome domain.AuditOutcome, reason string) {
+	details := map[string]string{
+		"method": r.Method,
+		"path":   r.URL.Path,
+	}
+	if reason != "" {
+		details["reason"] = reason
+	}
+
+	event := domain.NewAuditEvent(actor, "auth.impersonate", subject, outcome, details)
+	event.RequestID = logging.RequestID(r.Context())
+	if err := i.audit.Record(r.Context(), event); err != nil {
+		slog.ErrorContext(r.Context(), "failed to record audit event", "action", event.Action, "error", err)
+	}
+}
+
+// hasPermission reports whether the principal's permissions claim, a string or
+// a list of strings, includes permission.
+func hasPermission(principal domain.Principal, permission string) bool {
+	switch granted := principal.Attributes[PermissionsClaim].(type) {
+	case string:
+		return granted == permission
+	case []any:
+		return slices.Contains(granted, any(permission))
+	case []string:
+		return slices.Contains(granted, permission)
+	}
+	return false
+}
@@ -0,0 +1,355 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// recordedAudit keeps every audit event in memory.
+type recordedAudit struct {
+	mu     sync.Mutex
+	events []domain.AuditEvent
+}
+
+func (a *recordedAudit) Record(ctx context.Context, event domain.AuditEvent) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, event)
+	return nil
+}
+
+func (a *recordedAudit) take() []domain.AuditEvent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	events := a.events
+	a.events = nil
+	return events
+}
+
+type impersonationFixture struct {
+	m      *AuthMiddleware
+	signer *ecdsa.PrivateKey
+	server *miniredis.Miniredis
+	audit  *recordedAudit
+	admin  string
+}
+
+func newImpersonationFixture(t *testing.T) *impersonationFixture {
+	t.Helper()
+	return newImpersonationFixtureWith(t, RevocationConfig{BreakerThreshold: 3, WatermarkTTL: time.Hour})
+}
+
+func newImpersonationFixtureWith(t *testing.T, config RevocationConfig) *impersonationFixture {
+	t.Helper()
+	server, client := newMiniredis(t)
+	m, signer := newTestAuthWith(t, client, config)
+	audit := &recordedAudit{}
+	m.AllowImpersonation(NewImpersonation(client, audit))
+
+	f := &impersonationFixture{m: m, signer: signer, server: server, audit: audit}
+	f.admin = f.token(t, "admin-1", "ADMIN", []string{"audit", ImpersonatePermission})
+	return f
+}
+
+// token signs a token issued a minute ago; permissions is omitted when nil.
+func (f *impersonationFixture) token(t *testing.T, sub, role string, permissions any, extra ...any) string {
+	t.Helper()
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub":  sub,
+		"role": role,
+		"jti":  sub + "-" + strconv.FormatInt(now.UnixNano(), 10),
+		"iat":  now.Add(-time.Minute).Unix(),
+		"exp":  now.Add(time.Hour).Unix(),
+	}
+	if permissions != nil {
+		claims[PermissionsClaim] = permissions
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		claims[extra[i].(string)] = extra[i+1]
+	}
+	return signClaims(t, f.signer, claims)
+}
+
+// signIn authenticates token so its snapshot is written, and waits for it.
+func (f *impersonationFixture) signIn(t *testing.T, token string) {
+	t.Helper()
+	before, _ := f.server.Get(principalSnapshotPrefix + "parent-1")
+	if rec, _ := serve(f.m, bearer(http.MethodGet, token)); rec.Code != http.StatusNoContent {
+		t.Fatalf("sign in = %d", rec.Code)
+	}
+	waitUntil(t, "principal snapshot", func() bool {
+		after, _ := f.server.Get(principalSnapshotPrefix + "parent-1")
+		return after != "" && after != before
+	})
+}
+
+func (f *impersonationFixture) actAs(method, token, subject string) (*httptest.ResponseRecorder, *domain.Principal) {
+	r := bearer(method, token)
+	r.Header.Set(ActAsHeader, subject)
+	return serve(f.m, r)
+}
+
+func (f *impersonationFixture) parentToken(t *testing.T, extra ...any) string {
+	t.Helper()
+	return f.token(t, "parent-1", "PARENT", nil, append([]any{"clinic_id", "clinic-7", "sid", "s1"}, extra...)...)
+}
+
+// assertAudited checks exactly one auth.impersonate event was recorded for
+// the attempt, with the real caller as actor.
+func assertAudited(t *testing.T, audit *recordedAudit, actor, subject string, outcome domain.AuditOutcome, reason string) {
+	t.Helper()
+	events := audit.take()
+	if len(events) != 1 {
+		t.Fatalf("%d audit events, want 1: %+v", len(events), events)
+	}
+	e := events[0]
+	if e.Action != "auth.impersonate" || e.Actor != actor || e.Target != subject || e.Outcome != outcome || e.Details["reason"] != reason {
+		t.Errorf("audit event = %+v, want auth.impersonate by %s of %s, %s %q", e, actor, subject, outcome, reason)
+	}
+	if e.ActingAs != "" {
+		t.Errorf("impersonation audit event has acting_as %q, want it recorded as the admin", e.ActingAs)
+	}
+}
+
+func TestHasPermission(t *testing.T) {
+	tests := []struct {
+		name    string
+		granted any
+		want    bool
+	}{
+		{"string", "impersonate", true},
+		{"other string", "audit", false},
+		{"space separated string", "audit impersonate", false},
+		{"list from JSON", []any{"audit", "impersonate"}, true},
+		{"list without it", []any{"audit"}, false},
+		{"list with a non-string", []any{42, "impersonate"}, true},
+		{"string list", []string{"impersonate"}, true},
+		{"empty list", []any{}, false},
+		{"other type", true, false},
+		{"missing", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal := domain.Principal{Attributes: domain.Attributes{}}
+			if tt.granted != nil {
+				principal.Attributes[PermissionsClaim] = tt.granted
+			}
+			if got := hasPermission(principal, ImpersonatePermission); got != tt.want {
+				t.Errorf("hasPermission() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActAsAllowed(t *testing.T) {
+	f := newImpersonationFixture(t)
+	f.signIn(t, f.parentToken(t))
+
+	for name, admin := range map[string]string{
+		"permission list":   f.admin,
+		"permission string": f.token(t, "admin-2", "ADMIN", ImpersonatePermission),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var forwarded Token = "unset"
+			r := bearer(http.MethodGet, admin)
+			r.Header.Set(ActAsHeader, "parent-1")
+			rec := httptest.NewRecorder()
+			var principal domain.Principal
+			f.m.Authenticate(func(w http.ResponseWriter, r *http.Request) {
+				principal, _ = domain.PrincipalFromContext(r.Context())
+				forwarded, _ = r.Context().Value(TokenKey).(Token)
+			})(rec, r)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+			}
+			if principal.ID != "parent-1" || principal.Role != "PARENT" || principal.AuthMethod != domain.AuthMethodToken {
+				t.Errorf("effective principal = %+v, want the subject", principal)
+			}
+			if principal.Attributes["clinic_id"] != "clinic-7" {
+				t.Errorf("subject claims = %v, want them from the snapshot", principal.Attributes)
+			}
+			if principal.Impersonator == nil || principal.Impersonator.Role != "ADMIN" {
+				t.Fatalf("Impersonator = %+v, want the admin", principal.Impersonator)
+			}
+			if got := principal.AccessAttributes()["impersonated_by"]; got != principal.Impersonator.ID {
+				t.Errorf("impersonated_by = %v", got)
+			}
+			if forwarded.Value() != "" {
+				t.Error("admin token forwarded on the subject's behalf")
+			}
+			assertAudited(t, f.audit, principal.Impersonator.ID, "parent-1", domain.AuditSuccess, "")
+		})
+	}
+
+	// HEAD and OPTIONS are reads too
+	for _, method := range []string{http.MethodHead, http.MethodOptions} {
+		if rec, _ := f.actAs(method, f.admin, "parent-1"); rec.Code != http.StatusNoContent {
+			t.Errorf("%s while impersonating = %d", method, rec.Code)
+		}
+		f.audit.take()
+	}
+}
+
+func TestActAsNotPermitted(t *testing.T) {
+	f := newImpersonationFixture(t)
+	f.signIn(t, f.parentToken(t))
+
+	tests := []struct {
+		name   string
+		caller string
+		actor  string
+	}{
+		{"admin without permissions claim", f.token(t, "admin-2", "ADMIN", nil), "admin-2"},
+		{"admin with other permission string", f.token(t, "admin-3", "ADMIN", "audit"), "admin-3"},
+		{"admin with other permission list", f.token(t, "admin-4", "ADMIN", []string{"audit"}), "admin-4"},
+		{"reviewer with permission", f.token(t, "reviewer-1", "REVIEWER", []string{ImpersonatePermission}), "reviewer-1"},
+		{"parent with permission string", f.token(t, "parent-2", "PARENT", ImpersonatePermission), "parent-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, seen := f.actAs(http.MethodGet, tt.caller, "parent-1")
+			if rec.Code != http.StatusForbidden || seen != nil {
+				t.Errorf("status = %d, want 403", rec.Code)
+			}
+			assertAudited(t, f.audit, tt.actor, "parent-1", domain.AuditFailure, "not_permitted")
+		})
+	}
+
+	t.Run("api key", func(t *testing.T) {
+		f.m.AcceptAPIKeys(&fakeAPIKeys{keys: map[string]*domain.APIKey{
+			"bkm_1_admin": {ID: "1", Scopes: []domain.APIKeyScope{domain.ScopeVideosRead}},
+		}})
+		r := withAPIKey(http.MethodGet, "bkm_1_admin")
+		r.Header.Set(ActAsHeader, "parent-1")
+		if rec, seen := serve(f.m, r); rec.Code != http.StatusForbidden || seen != nil {
+			t.Errorf("status = %d, want 403", rec.Code)
+		}
+		assertAudited(t, f.audit, "apikey:1", "parent-1", domain.AuditFailure, "not_permitted")
+	})
+}
+
+func TestActAsWriteBlocked(t *testing.T) {
+	f := newImpersonationFixture(t)
+	f.signIn(t, f.parentToken(t))
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		t.Run(method, func(t *testing.T) {
+			rec, seen := f.actAs(method, f.admin, "parent-1")
+			if rec.Code != http.StatusForbidden || seen != nil {
+				t.Fatalf("status = %d, want 403", rec.Code)
+			}
+			if body := rec.Body.String(); body != "write operations are not allowed while impersonating\n" {
+				t.Errorf("body = %q", body)
+			}
+			assertAudited(t, f.audit, "admin-1", "parent-1", domain.AuditFailure, "write_blocked")
+		})
+	}
+}
+
+func TestActAsUnknownSubject(t *testing.T) {
+	f := newImpersonationFixture(t)
+
+	rec, seen := f.actAs(http.MethodGet, f.admin, "parent-9")
+	if rec.Code != http.StatusNotFound || seen != nil {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+	assertAudited(t, f.audit, "admin-1", "parent-9", domain.AuditFailure, "unknown_subject")
+
+	// Snapshots expire with the token they were taken from
+	f.signIn(t, f.parentToken(t))
+	f.server.FastForward(2 * time.Hour)
+	if rec, _ := f.actAs(http.MethodGet, f.admin, "parent-1"); rec.Code != http.StatusNotFound {
+		t.Errorf("status after the subject's token expired = %d, want 404", rec.Code)
+	}
+	f.audit.take()
+}
+
+func TestActAsRevokedSubject(t *testing.T) {
+	tests := []struct {
+		name   string
+		revoke func(f *impersonationFixture, jti string)
+	}{
+		{"subject", func(f *impersonationFixture, _ string) {
+			f.m.revocations.RevokeSubject(context.Background(), "parent-1", time.Now())
+		}},
+		{"session", func(f *impersonationFixture, _ string) {
+			f.server.Set(sessionWatermarkKeyPrefix+"s1", strconv.FormatInt(time.Now().Unix(), 10))
+		}},
+		{"token", func(f *impersonationFixture, jti string) {
+			f.m.revocations.RevokeToken(context.Background(), jti, time.Now().Add(time.Hour))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newImpersonationFixture(t)
+			token := f.parentToken(t)
+			f.signIn(t, token)
+			if rec, _ := f.actAs(http.MethodGet, f.admin, "parent-1"); rec.Code != http.StatusNoContent {
+				t.Fatalf("status before revocation = %d", rec.Code)
+			}
+			f.audit.take()
+
+			claims := jwt.MapClaims{}
+			jwt.NewParser().ParseUnverified(token, claims)
+			tt.revoke(f, claims["jti"].(string))
+
+			if rec, seen := f.actAs(http.MethodGet, f.admin, "parent-1"); rec.Code != http.StatusNotFound || seen != nil {
+				t.Fatalf("status after revocation = %d, want 404", rec.Code)
+			}
+			assertAudited(t, f.audit, "admin-1", "parent-1", domain.AuditFailure, "revoked")
+		})
+	}
+
+	t.Run("new token after revocation", func(t *testing.T) {
+		f := newImpersonationFixture(t)
+		f.signIn(t, f.parentToken(t))
+		f.m.revocations.RevokeSubject(context.Background(), "parent-1", time.Now().Add(-30*time.Second))
+
+		// Issued after the watermark, so the subject can be impersonated again
+		f.signIn(t, f.parentToken(t, "iat", time.Now().Unix(), "sid", "s2"))
+		if rec, _ := f.actAs(http.MethodGet, f.admin, "parent-1"); rec.Code != http.StatusNoContent {
+			t.Errorf("status = %d, want the new token's snapshot to be usable", rec.Code)
+		}
+	})
+
+	// The admin's own read passes under fail-open-reads, but the subject's
+	// revocation status must be known
+	t.Run("revocation status unavailable", func(t *testing.T) {
+		f := newImpersonationFixtureWith(t, RevocationConfig{
+			FailurePolicy:    FailOpenReads,
+			BreakerThreshold: 1,
+			BreakerCooldown:  time.Hour,
+			WatermarkTTL:     time.Hour,
+		})
+		f.signIn(t, f.parentToken(t))
+		f.m.revocations.breaker.Failure()
+
+		if rec, seen := f.actAs(http.MethodGet, f.admin, "parent-1"); rec.Code != http.StatusServiceUnavailable || seen != nil {
+			t.Errorf("status = %d, want 503", rec.Code)
+		}
+		assertAudited(t, f.audit, "admin-1", "parent-1", domain.AuditFailure, "error")
+	})
+}
+
+func TestActAsDisabled(t *testing.T) {
+	_, client := newMiniredis(t)
+	m, signer := newTestAuthWith(t, client, RevocationConfig{BreakerThreshold: 3, WatermarkTTL: time.Hour})
+	f := &impersonationFixture{m: m, signer: signer}
+	admin := f.token(t, "admin-1", "ADMIN", []string{ImpersonatePermission})
+
+	if rec, seen := f.actAs(http.MethodGet, admin, "parent-1"); rec.Code != http.StatusForbidden || seen != nil {
+		t.Errorf("status = %d, want 403", rec.Code)
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/metrics"
+)
+
+var (
+	httpRequestsTotal = metrics.NewCounterVec(
+		"http_requests_total",
+		"Total HTTP requests by method, route pattern and status code.",
+		"method", "route", "status",
+	)
+	httpRequestDuration = metrics.NewHistogramVec(
+		"http_request_duration_seconds",
+		"HTTP request latency by method and route pattern.",
+		metrics.DefBuckets,
+		"method", "route",
+	)
+)
+
+// Metrics records request counts and latencies per route. The route label is the
+// mux pattern rather than the raw path so that IDs do not explode label cardinality.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequestsTotal.WithLabelValues(r.Method, route, strconv.Itoa(rec.status)).Inc()
+		httpRequestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/adapters/openapi"
+)
+
+// OpenAPIValidator rejects requests that do not match the OpenAPI document and,
+// when response validation is enabled (test mode), fails responses that drift from it.
+// It runs before authentication, so request bodies are capped at maxBodyBytes.
+type OpenAPIValidator struct {
+	spec              *openapi.Spec
+	validateResponses bool
+	maxBodyBytes      int64
+}
+
+func NewOpenAPIValidator(spec *openapi.Spec, validateResponses bool, maxBodyBytes int64) *OpenAPIValidator {
+	return &OpenAPIValidator{
+		spec:              spec,
+		validateResponses: validateResponses,
+		maxBodyBytes:      maxBodyBytes,
+	}
+}
+
+func (v *OpenAPIValidator) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []byte
+		if r.Body != nil {
+			var err error
+			body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, v.maxBodyBytes))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		if err := v.spec.ValidateRequest(r, body); err != nil {
+			// Undocumented operations are left to the mux (404/405)
+			if !errors.Is(err, openapi.ErrNoOperation) {
+				slog.WarnContext(r.Context(), "openapi request validation failed", "method", r.Method, "path", r.URL.Path, "error", err)
+				http.Error(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		if !v.validateResponses {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &bufferedResponseWriter{header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		err := v.spec.ValidateResponse(r.Method, r.URL.Path, rec.status, rec.header.Get("Content-Type"), rec.body.Bytes())
+		if err != nil && !errors.Is(err, openapi.ErrNoOperation) {
+			slog.ErrorContext(r.Context(), "openapi response validation failed", "method", r.Method, "path", r.URL.Path, "status", rec.status, "error", err)
+			http.Error(w, "Response does not match API specification: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for key, values := range rec.header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(rec.status)
+		if _, err := w.Write(rec.body.Bytes()); err != nil {
+			slog.ErrorContext(r.Context(), "failed to write response", "error", err)
+		}
+	})
+}
+
+// bufferedResponseWriter holds the full response so it can be validated before it is sent.
+type bufferedResponseWriter struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (b *bufferedResponseWriter) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponseWriter) WriteHeader(status int) {
+	if b.wroteHeader {
+		return
+	}
+	b.status = status
+	b.wroteHeader = true
+}
+
+func (b *bufferedResponseWriter) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
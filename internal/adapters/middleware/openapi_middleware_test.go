@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/adapters/openapi"
+)
+
+func TestOpenAPIValidatorBody(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	validator := NewOpenAPIValidator(spec, false, 64)
+
+	var received string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusCreated)
+	})
+	handler := validator.Handler(next)
+
+	tests := []struct {
+		name       string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{"valid body reaches handler", "/media/videos", `{"url": "u", "content_type": "SLEEPING"}`, http.StatusCreated},
+		{"invalid body", "/media/videos", `{"content_type": "SLEEPING"}`, http.StatusBadRequest},
+		{"oversized body", "/media/videos", `{"url": "` + strings.Repeat("a", 100) + `", "content_type": "SLEEPING"}`, http.StatusRequestEntityTooLarge},
+		{"oversized body on unknown route", "/media/unknown", strings.Repeat("a", 100), http.StatusRequestEntityTooLarge},
+		{"unknown route is left to the mux", "/media/unknown", `{}`, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = ""
+			r := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusCreated && received != tt.body {
+				t.Errorf("handler read %q, want the original body", received)
+			}
+		})
+	}
+}
@@ -0,0 +1,448 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/breaker"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/cache"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/metrics"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/tracing"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	blacklistLookupDuration = metrics.NewHistogram(
+		"auth_blacklist_lookup_duration_seconds",
+		"Latency of the pipelined Redis blacklist and watermark lookup.",
+		metrics.DefBuckets,
+	)
+	blacklistLookupErrors = metrics.NewCounter(
+		"auth_blacklist_lookup_errors_total",
+		"Redis blacklist lookups that failed.",
+	)
+	revocationNegativeCacheHits = metrics.NewCounter(
+		"auth_revocation_negative_cache_hits_total",
+		"Revocation lookups answered by the local not-revoked cache.",
+	)
+	revocationUnavailable = metrics.NewCounterVec(
+		"auth_revocation_unavailable_total",
+		"Requests whose revocation status could not be checked, by outcome.",
+		"outcome",
+	)
+	revocationBreakerState = metrics.NewGauge(
+		"auth_revocation_breaker_state",
+		"Redis blacklist circuit breaker state (0 closed, 1 half-open, 2 open).",
+	)
+	revocationSubscriptionUp = metrics.NewGauge(
+		"auth_revocation_subscription_up",
+		"Whether the revocation channel subscription is established (1) or not (0).",
+	)
+	revocationMessages = metrics.NewCounter(
+		"auth_revocation_messages_total",
+		"Revocation announcements received on the pub/sub channel.",
+	)
+	revocationLookupsSkipped = metrics.NewCounter(
+		"auth_revocation_lookups_skipped_total",
+		"Blacklist lookups skipped for cached tokens while the subscription was healthy.",
+	)
+)
+
+// subscriptionPingInterval bounds how long a silently dead subscription goes unnoticed.
+const subscriptionPingInterval = 30 * time.Second
+
+// FailurePolicy decides what happens to a request when the blacklist cannot be read.
+type FailurePolicy string
+
+const (
+	FailClosed    FailurePolicy = "fail-closed"
+	FailOpen      FailurePolicy = "fail-open"
+	FailOpenReads FailurePolicy = "fail-open-reads"
+)
+
+func ParseFailurePolicy(s string) (FailurePolicy, error) {
+	switch p := FailurePolicy(s); p {
+	case FailClosed, FailOpen, FailOpenReads:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown revocation failure policy %q (want %s, %s or %s)", s, FailClosed, FailOpen, FailOpenReads)
+}
+
+// allows reports whether a request with the given method may proceed unchecked.
+func (p FailurePolicy) allows(method string) bool {
+	switch p {
+	case FailOpen:
+		return true
+	case FailOpenReads:
+		return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+	default:
+		return false
+	}
+}
+
+// errRevocationUnavailable is returned while Redis is failing or the breaker is open.
+var errRevocationUnavailable = errors.New("revocation status unavailable")
+
+// RevocationConfig tunes how the blacklist lookup behaves when Redis misbehaves.
+type RevocationConfig struct {
+	FailurePolicy    FailurePolicy
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// NegativeCacheTTL is how long a "not revoked" answer is reused locally; zero disables it.
+	NegativeCacheTTL      time.Duration
+	NegativeCacheCapacity int
+	// Channel carries revoked JTIs, subjects and sessions. Publishers must set
+	// the blacklist or watermark key before publishing.
+	Channel string
+	// WatermarkTTL is how long a subject or session watermark is kept; it must
+	// cover the longest token lifetime. Tokens revoked by bare jti are
+	// blacklisted for as long.
+	WatermarkTTL time.Duration
+	// SkipCachedLookups trusts the channel for tokens already in the claims cache
+	// and skips their EXISTS round-trip while the subscription is healthy.
+	SkipCachedLookups bool
+}
+
+// RevocationChecker looks tokens up in the Redis blacklist behind a circuit
+// breaker and a short-lived cache of tokens recently confirmed not revoked.
+type RevocationChecker struct {
+	redisClient *redis.Client
+	config      RevocationConfig
+	breaker     *breaker.Breaker
+	notRevoked  *cache.LRU[struct{}]
+
+	subscribed atomic.Bool
+	onRevoke   func(Revocation)
+	onResync   func()
+	stop       chan struct{}
+	stopOnce   sync.Once
+}
+
+func NewRevocationChecker(redisClient *redis.Client, config RevocationConfig) *RevocationChecker {
+	c := &RevocationChecker{
+		redisClient: redisClient,
+		config:      config,
+		breaker:     breaker.New(config.BreakerThreshold, config.BreakerCooldown),
+		stop:        make(chan struct{}),
+	}
+	if config.NegativeCacheTTL > 0 {
+		c.notRevoked = cache.New[struct{}](config.NegativeCacheCapacity, 16, nil)
+	}
+
+	c.breaker.OnStateChange(func(from, to breaker.State) {
+		revocationBreakerState.Set(float64(to))
+		if to == breaker.Open {
+			slog.Error("revocation circuit breaker opened", "from", from.String(), "cooldown", config.BreakerCooldown)
+		} else {
+			slog.Info("revocation circuit breaker state changed", "from", from.String(), "to", to.String())
+		}
+	})
+	return c
+}
+
+// Redis key prefixes. Watermarks hold a Unix timestamp: every token of the subject
+// or session issued at or before it is revoked.
+const (
+	blacklistKeyPrefix        = "blacklist:"
+	subjectWatermarkKeyPrefix = "revoked_before:sub:"
+	sessionWatermarkKeyPrefix = "revoked_before:sid:"
+)
+
+// RevocationScope says which tokens a revocation applies to.
+type RevocationScope string
+
+const (
+	ScopeToken   RevocationScope = "jti"
+	ScopeSubject RevocationScope = "sub"
+	ScopeSession RevocationScope = "sid"
+)
+
+// Revocation is a single revoked token, subject or session.
+type Revocation struct {
+	Scope RevocationScope
+	ID    string
+}
+
+// parseRevocation reads a channel payload: "sub:<subject>", "sid:<session>",
+// "jti:<jti>" or a bare JTI.
+func parseRevocation(payload string) (Revocation, bool) {
+	payload = strings.TrimSpace(payload)
+	for _, scope := range []RevocationScope{ScopeToken, ScopeSubject, ScopeSession} {
+		if id, ok := strings.CutPrefix(payload, string(scope)+":"); ok {
+			return Revocation{Scope: scope, ID: id}, id != ""
+		}
+	}
+	return Revocation{Scope: ScopeToken, ID: payload}, payload != ""
+}
+
+// revocationTarget identifies a token for the revocation lookup.
+type revocationTarget struct {
+	JTI       string
+	Subject   string
+	SessionID string
+	IssuedAt  time.Time // zero when the token has no iat
+}
+
+// IsRevoked checks the token's blacklist key and its subject and session
+// watermarks in a single pipelined round-trip. It returns the scope that revoked
+// the token, or "" if it is not revoked, and errRevocationUnavailable when the
+// answer is unknown; the caller applies the failure policy.
+func (c *RevocationChecker) IsRevoked(ctx context.Context, t revocationTarget) (RevocationScope, error) {
+	if c.notRevoked != nil {
+		if _, ok := c.notRevoked.Get(t.JTI); ok {
+			revocationNegativeCacheHits.Inc()
+			return "", nil
+		}
+	}
+
+	if !c.breaker.Allow() {
+		return "", errRevocationUnavailable
+	}
+
+	ctx, span := tracing.Start(ctx, "redis.pipeline", tracing.KindClient,
+		slog.String("db.system", "redis"),
+		slog.String("db.operation", "EXISTS GET"),
+	)
+	defer span.End()
+
+	start := time.Now()
+	pipe := c.redisClient.Pipeline()
+	blacklisted := pipe.Exists(ctx, blacklistKeyPrefix+t.JTI)
+	var subjectWatermark, sessionWatermark *redis.StringCmd
+	if t.Subject != "" {
+		subjectWatermark = pipe.Get(ctx, subjectWatermarkKeyPrefix+t.Subject)
+	}
+	if t.SessionID != "" {
+		sessionWatermark = pipe.Get(ctx, sessionWatermarkKeyPrefix+t.SessionID)
+	}
+	_, err := pipe.Exec(ctx)
+	blacklistLookupDuration.Observe(time.Since(start).Seconds())
+	// redis.Nil only means a watermark is not set
+	if err != nil && !errors.Is(err, redis.Nil) {
+		blacklistLookupErrors.Inc()
+		span.RecordError(err)
+		// A client hanging up says nothing about Redis health
+		if !errors.Is(err, context.Canceled) {
+			c.breaker.Failure()
+		}
+		return "", errors.Join(errRevocationUnavailable, err)
+	}
+	c.breaker.Success()
+
+	if blacklisted.Val() > 0 {
+		return ScopeToken, nil
+	}
+	if issuedBeforeWatermark(subjectWatermark, t.IssuedAt) {
+		return ScopeSubject, nil
+	}
+	if issuedBeforeWatermark(sessionWatermark, t.IssuedAt) {
+		return ScopeSession, nil
+	}
+
+	if c.notRevoked != nil {
+		c.notRevoked.Set(t.JTI, struct{}{}, time.Now().Add(c.config.NegativeCacheTTL))
+	}
+	return "", nil
+}
+
+// ErrTokenAlreadyExpired is returned when revoking a token that can no longer be used anyway.
+var ErrTokenAlreadyExpired = errors.New("token already expired")
+
+// RevokeToken blacklists jti until the token expires and announces it to every replica.
+func (c *RevocationChecker) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return ErrTokenAlreadyExpired
+	}
+	return c.publish(ctx, Revocation{Scope: ScopeToken, ID: jti}, blacklistKeyPrefix+jti, 1, ttl)
+}
+
+// RevokeSubject revokes every token of subject issued at or before at and
+// announces it to every replica.
+func (c *RevocationChecker) RevokeSubject(ctx context.Context, subject string, at time.Time) error {
+	return c.publish(ctx, Revocation{Scope: ScopeSubject, ID: subject}, subjectWatermarkKeyPrefix+subject, at.Unix(), c.config.WatermarkTTL)
+}
+
+// publish writes the revocation key and announces it in one transaction, so the
+// key is always visible to a replica that reacts to the announcement.
+func (c *RevocationChecker) publish(ctx context.Context, revocation Revocation, key string, value any, ttl time.Duration) error {
+	ctx, span := tracing.Start(ctx, "redis.MULTI", tracing.KindClient,
+		slog.String("db.system", "redis"),
+		slog.String("db.operation", "SET PUBLISH"),
+	)
+	defer span.End()
+
+	pipe := c.redisClient.TxPipeline()
+	pipe.Set(ctx, key, value, ttl)
+	pipe.Publish(ctx, c.config.Channel, string(revocation.Scope)+":"+revocation.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	// Apply locally straight away in case this replica's subscription is down
+	if c.notRevoked != nil {
+		c.notRevoked.Clear()
+	}
+	if c.onRevoke != nil {
+		c.onRevoke(revocation)
+	}
+	return nil
+}
+
+// issuedBeforeWatermark reports whether a token issued at iat is covered by the
+// watermark. A token without iat cannot prove it is newer, so any watermark covers it.
+func issuedBeforeWatermark(cmd *redis.StringCmd, iat time.Time) bool {
+	if cmd == nil || cmd.Err() != nil {
+		return false
+	}
+	watermark, err := cmd.Int64()
+	if err != nil {
+		slog.Warn("ignoring malformed revocation watermark", "key", cmd.Args()[1], "value", cmd.Val())
+		return false
+	}
+	return iat.IsZero() || iat.Unix() <= watermark
+}
+
+// SkipsCachedLookups reports whether a token served from the claims cache can skip
+// the blacklist lookup right now.
+func (c *RevocationChecker) SkipsCachedLookups() bool {
+	return c.config.SkipCachedLookups && c.subscribed.Load()
+}
+
+// MaxTokenLifetime is the longest a token can live, which the watermark TTL covers.
+func (c *RevocationChecker) MaxTokenLifetime() time.Duration {
+	return c.config.WatermarkTTL
+}
+
+// AllowsUnchecked reports whether the failure policy lets a request with the given
+// method through when its revocation status is unknown.
+func (c *RevocationChecker) AllowsUnchecked(method string) bool {
+	return c.config.FailurePolicy.allows(method)
+}
+
+// OnRevoke registers fn to be called with every revocation announced on the
+// channel. It must be set before Subscribe.
+func (c *RevocationChecker) OnRevoke(fn func(Revocation)) {
+	c.onRevoke = fn
+}
+
+// OnResync registers fn to be called whenever the subscription is (re)established.
+// Announcements sent while it was down are lost, so anything trusted because of
+// the subscription must be dropped. It must be set before Subscribe.
+func (c *RevocationChecker) OnResync(fn func()) {
+	c.onResync = fn
+}
+
+// Subscribe listens on the revocation channel in the background until Close,
+// reconnecting with backoff when the connection drops.
+func (c *RevocationChecker) Subscribe() {
+	pubsub := c.redisClient.Subscribe(context.Background(), c.config.Channel)
+	go func() {
+		<-c.stop
+		pubsub.Close()
+	}()
+	go c.listen(pubsub)
+}
+
+func (c *RevocationChecker) listen(pubsub *redis.PubSub) {
+	ctx := context.Background()
+	backoff := time.Second
+	for {
+		msg, err := pubsub.ReceiveTimeout(ctx, subscriptionPingInterval)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				// Quiet channel: make sure the connection is still alive
+				err = pubsub.Ping(ctx)
+			}
+		}
+		if err != nil {
+			select {
+			case <-c.stop:
+				return
+			default:
+			}
+			if c.subscribed.Swap(false) {
+				revocationSubscriptionUp.Set(0)
+				slog.Error("revocation subscription lost, checking every token against Redis", "channel", c.config.Channel, "error", err)
+			}
+			select {
+			case <-c.stop:
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, 30*time.Second)
+			continue
+		}
+		backoff = time.Second
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			if m.Kind != "subscribe" {
+				continue
+			}
+			// Drop what was trusted before announcements could be received
+			if c.notRevoked != nil {
+				c.notRevoked.Clear()
+			}
+			if c.onResync != nil {
+				c.onResync()
+			}
+			c.subscribed.Store(true)
+			revocationSubscriptionUp.Set(1)
+			slog.Info("subscribed to revocation channel", "channel", c.config.Channel)
+		case *redis.Message:
+			if revocation, ok := parseRevocation(m.Payload); ok {
+				c.revoke(revocation)
+			}
+		}
+	}
+}
+
+func (c *RevocationChecker) revoke(revocation Revocation) {
+	revocationMessages.Inc()
+	if c.notRevoked != nil {
+		if revocation.Scope == ScopeToken {
+			c.notRevoked.Delete(revocation.ID)
+		} else {
+			// The negative cache is keyed by jti, so it cannot be filtered by subject or session
+			c.notRevoked.Clear()
+		}
+	}
+	if c.onRevoke != nil {
+		c.onRevoke(revocation)
+	}
+	slog.Debug("revocation announced", "scope", revocation.Scope, "id", revocation.ID)
+}
+
+// Close stops the subscription. It is safe to call more than once.
+func (c *RevocationChecker) Close() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+	c.subscribed.Store(false)
+	revocationSubscriptionUp.Set(0)
+}
+
+// Check fails while the breaker is not closed so the outage shows up as DEGRADED.
+func (c *RevocationChecker) Check(ctx context.Context) (string, error) {
+	subscription := "down"
+	if c.subscribed.Load() {
+		subscription = "up"
+	}
+	state := c.breaker.State()
+	if state != breaker.Closed {
+		return "", fmt.Errorf("circuit breaker %s since %s, policy %s, subscription %s",
+			state, c.breaker.OpenedAt().UTC().Format(time.RFC3339), c.config.FailurePolicy, subscription)
+	}
+	return fmt.Sprintf("circuit breaker closed, policy %s, subscription %s", c.config.FailurePolicy, subscription), nil
+}
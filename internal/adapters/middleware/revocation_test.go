@@ -0,0 +1,447 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+const testChannel = "revocations"
+
+// roundTrips counts the blacklist and watermark reads a client sends to Redis,
+// ignoring the connection handshake.
+type roundTrips struct {
+	commands  atomic.Int32
+	pipelines atomic.Int32
+	// pipelined is the size of the last pipeline
+	pipelined atomic.Int32
+}
+
+func isLookup(cmd redis.Cmder) bool {
+	return cmd.Name() == "exists" || cmd.Name() == "get"
+}
+
+func (h *roundTrips) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *roundTrips) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if isLookup(cmd) {
+			h.commands.Add(1)
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *roundTrips) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if len(cmds) > 0 && isLookup(cmds[0]) {
+			h.pipelines.Add(1)
+			h.pipelined.Store(int32(len(cmds)))
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func TestParseRevocation(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    Revocation
+		ok      bool
+	}{
+		{"jti:abc", Revocation{ScopeToken, "abc"}, true},
+		{"sub:user-1", Revocation{ScopeSubject, "user-1"}, true},
+		{"sid:s-9", Revocation{ScopeSession, "s-9"}, true},
+		{"abc", Revocation{ScopeToken, "abc"}, true},
+		{"  sub:user-1\n", Revocation{ScopeSubject, "user-1"}, true},
+		// only the first prefix is a scope
+		{"sub:sid:x", Revocation{ScopeSubject, "sid:x"}, true},
+		{"sub:", Revocation{ScopeSubject, ""}, false},
+		{"jti:", Revocation{ScopeToken, ""}, false},
+		{"", Revocation{ScopeToken, ""}, false},
+		{"   ", Revocation{ScopeToken, ""}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRevocation(tt.payload)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRevocation(%q) = %+v, %v, want %+v, %v", tt.payload, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestIssuedBeforeWatermark(t *testing.T) {
+	watermark := time.Unix(1_700_000_000, 0)
+	set := redis.NewStringResult(strconv.FormatInt(watermark.Unix(), 10), nil)
+
+	tests := []struct {
+		name string
+		cmd  *redis.StringCmd
+		iat  time.Time
+		want bool
+	}{
+		{"not looked up", nil, watermark, false},
+		{"no watermark", redis.NewStringResult("", redis.Nil), watermark, false},
+		{"lookup failed", redis.NewStringResult("", errors.New("timeout")), watermark, false},
+		{"issued before", set, watermark.Add(-time.Minute), true},
+		{"issued at the watermark", set, watermark, true},
+		// iat has second precision, so the same second is still covered
+		{"issued within the watermark second", set, watermark.Add(999 * time.Millisecond), true},
+		{"issued after", set, watermark.Add(time.Second), false},
+		{"no iat", set, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := issuedBeforeWatermark(tt.cmd, tt.iat); got != tt.want {
+				t.Errorf("issuedBeforeWatermark() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRevoked(t *testing.T) {
+	server, _ := newMiniredis(t)
+	iat := time.Now().Truncate(time.Second)
+	server.Set(blacklistKeyPrefix+"revoked-jti", "1")
+	server.Set(subjectWatermarkKeyPrefix+"revoked-user", strconv.FormatInt(iat.Unix(), 10))
+	server.Set(sessionWatermarkKeyPrefix+"revoked-session", strconv.FormatInt(iat.Unix(), 10))
+	server.Set(subjectWatermarkKeyPrefix+"garbled-user", "yesterday")
+
+	tests := []struct {
+		name   string
+		target revocationTarget
+		want   RevocationScope
+		// size of the pipelined lookup
+		commands int32
+	}{
+		{"clean token", revocationTarget{JTI: "t1", Subject: "user-1", SessionID: "s1", IssuedAt: iat}, "", 3},
+		{"blacklisted jti", revocationTarget{JTI: "revoked-jti", Subject: "user-1", SessionID: "s1", IssuedAt: iat}, ScopeToken, 3},
+		{"subject watermark at iat", revocationTarget{JTI: "t2", Subject: "revoked-user", IssuedAt: iat}, ScopeSubject, 2},
+		{"subject watermark before iat", revocationTarget{JTI: "t3", Subject: "revoked-user", IssuedAt: iat.Add(time.Second)}, "", 2},
+		{"subject watermark without iat", revocationTarget{JTI: "t4", Subject: "revoked-user"}, ScopeSubject, 2},
+		{"session watermark", revocationTarget{JTI: "t5", Subject: "user-1", SessionID: "revoked-session", IssuedAt: iat.Add(-time.Hour)}, ScopeSession, 3},
+		{"session watermark before iat", revocationTarget{JTI: "t6", SessionID: "revoked-session", IssuedAt: iat.Add(time.Second)}, "", 2},
+		{"malformed watermark ignored", revocationTarget{JTI: "t7", Subject: "garbled-user", IssuedAt: iat}, "", 2},
+		{"jti only", revocationTarget{JTI: "t8"}, "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := &roundTrips{}
+			client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+			client.AddHook(hook)
+			defer client.Close()
+			c := NewRevocationChecker(client, RevocationConfig{BreakerThreshold: 3, BreakerCooldown: time.Minute})
+
+			got, err := c.IsRevoked(context.Background(), tt.target)
+			if err != nil {
+				t.Fatalf("IsRevoked() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsRevoked() = %q, want %q", got, tt.want)
+			}
+			if hook.pipelines.Load() != 1 || hook.commands.Load() != 0 || hook.pipelined.Load() != tt.commands {
+				t.Errorf("sent %d pipelines of %d commands and %d single commands, want one pipeline of %d",
+					hook.pipelines.Load(), hook.pipelined.Load(), hook.commands.Load(), tt.commands)
+			}
+		})
+	}
+}
+
+func TestIsRevokedNegativeCache(t *testing.T) {
+	server, client := newMiniredis(t)
+	hook := &roundTrips{}
+	client.AddHook(hook)
+	c := NewRevocationChecker(client, RevocationConfig{
+		BreakerThreshold:      3,
+		NegativeCacheTTL:      time.Minute,
+		NegativeCacheCapacity: 16,
+	})
+	target := revocationTarget{JTI: "t1", Subject: "user-1", IssuedAt: time.Now()}
+
+	for range 3 {
+		if got, err := c.IsRevoked(context.Background(), target); got != "" || err != nil {
+			t.Fatalf("IsRevoked() = %q, %v", got, err)
+		}
+	}
+	if n := hook.pipelines.Load(); n != 1 {
+		t.Fatalf("%d lookups reached Redis, want the repeats answered locally", n)
+	}
+
+	// A revocation announced on the channel drops the cached answer
+	server.Set(blacklistKeyPrefix+"t1", "1")
+	c.revoke(Revocation{Scope: ScopeToken, ID: "t1"})
+	if got, _ := c.IsRevoked(context.Background(), target); got != ScopeToken {
+		t.Errorf("IsRevoked() after the announcement = %q, want %q", got, ScopeToken)
+	}
+
+	// Revoked tokens are never cached as not revoked
+	if got, _ := c.IsRevoked(context.Background(), target); got != ScopeToken {
+		t.Errorf("IsRevoked() repeated = %q, want %q", got, ScopeToken)
+	}
+	if n := hook.pipelines.Load(); n != 3 {
+		t.Errorf("%d lookups reached Redis, want 3", n)
+	}
+}
+
+func TestIsRevokedUnavailable(t *testing.T) {
+	server, client := newMiniredis(t)
+	hook := &roundTrips{}
+	client.AddHook(hook)
+	c := NewRevocationChecker(client, RevocationConfig{BreakerThreshold: 2, BreakerCooldown: time.Hour})
+	target := revocationTarget{JTI: "t1", Subject: "user-1"}
+
+	// A caller hanging up does not count against Redis
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for range 3 {
+		if _, err := c.IsRevoked(ctx, target); !errors.Is(err, errRevocationUnavailable) {
+			t.Fatalf("IsRevoked() with a cancelled context = %v, want errRevocationUnavailable", err)
+		}
+	}
+	if _, err := c.Check(context.Background()); err != nil {
+		t.Fatalf("cancelled lookups opened the breaker: %v", err)
+	}
+
+	server.Close()
+	for range 2 {
+		if _, err := c.IsRevoked(context.Background(), target); !errors.Is(err, errRevocationUnavailable) {
+			t.Fatalf("IsRevoked() with Redis down = %v, want errRevocationUnavailable", err)
+		}
+	}
+	if _, err := c.Check(context.Background()); err == nil {
+		t.Fatal("Check() passes with the breaker open")
+	}
+
+	sent := hook.pipelines.Load()
+	if _, err := c.IsRevoked(context.Background(), target); !errors.Is(err, errRevocationUnavailable) {
+		t.Fatalf("IsRevoked() with the breaker open = %v", err)
+	}
+	if hook.pipelines.Load() != sent {
+		t.Error("lookup reached Redis while the breaker was open")
+	}
+}
+
+func TestFailurePolicy(t *testing.T) {
+	methods := []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPost, http.MethodPatch, http.MethodDelete}
+	tests := []struct {
+		policy  FailurePolicy
+		allowed []string
+	}{
+		{FailClosed, nil},
+		{FailOpen, methods},
+		{FailOpenReads, []string{http.MethodGet, http.MethodHead, http.MethodOptions}},
+	}
+	for _, tt := range tests {
+		for _, method := range methods {
+			want := false
+			for _, m := range tt.allowed {
+				want = want || m == method
+			}
+			if got := tt.policy.allows(method); got != want {
+				t.Errorf("%s.allows(%s) = %v, want %v", tt.policy, method, got, want)
+			}
+		}
+	}
+
+	if _, err := ParseFailurePolicy("fail-sometimes"); err == nil {
+		t.Error("ParseFailurePolicy accepted an unknown policy")
+	}
+	for _, p := range []FailurePolicy{FailClosed, FailOpen, FailOpenReads} {
+		if got, err := ParseFailurePolicy(string(p)); got != p || err != nil {
+			t.Errorf("ParseFailurePolicy(%q) = %q, %v", p, got, err)
+		}
+	}
+}
+
+// TestAuthenticateRevocationUnavailable runs requests through the middleware
+// while Redis is down to check the failure policy is applied per method.
+func TestAuthenticateRevocationUnavailable(t *testing.T) {
+	tests := []struct {
+		policy FailurePolicy
+		method string
+		want   int
+	}{
+		{FailClosed, http.MethodGet, http.StatusServiceUnavailable},
+		{FailClosed, http.MethodPost, http.StatusServiceUnavailable},
+		{FailOpenReads, http.MethodGet, http.StatusNoContent},
+		{FailOpenReads, http.MethodPost, http.StatusServiceUnavailable},
+		{FailOpenReads, http.MethodDelete, http.StatusServiceUnavailable},
+		{FailOpen, http.MethodGet, http.StatusNoContent},
+		{FailOpen, http.MethodPatch, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy)+" "+tt.method, func(t *testing.T) {
+			server, client := newMiniredis(t)
+			server.Close()
+			m, signer := newTestAuthWith(t, client, RevocationConfig{
+				FailurePolicy:    tt.policy,
+				BreakerThreshold: 1,
+				BreakerCooldown:  time.Hour,
+			})
+			token := signToken(t, signer, "t1", time.Now().Add(time.Hour))
+
+			rec, _ := serve(m, bearer(tt.method, token))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticateRevokedToken(t *testing.T) {
+	server, client := newMiniredis(t)
+	m, signer := newTestAuthWith(t, client, RevocationConfig{BreakerThreshold: 3, WatermarkTTL: time.Hour})
+	iat := time.Now().Add(-time.Minute).Truncate(time.Second)
+	token := signClaims(t, signer, jwt.MapClaims{
+		"sub": "user-1", "sid": "s1", "jti": "t1",
+		"iat": iat.Unix(), "exp": iat.Add(time.Hour).Unix(),
+	})
+
+	if rec, _ := serve(m, bearer(http.MethodGet, token)); rec.Code != http.StatusNoContent {
+		t.Fatalf("status before revocation = %d", rec.Code)
+	}
+	if len(m.CachedPrincipals()) != 1 {
+		t.Fatal("token not cached")
+	}
+
+	server.Set(sessionWatermarkKeyPrefix+"s1", strconv.FormatInt(iat.Unix(), 10))
+	if rec, seen := serve(m, bearer(http.MethodGet, token)); rec.Code != http.StatusUnauthorized || seen != nil {
+		t.Errorf("status after revoking the session = %d, want 401", rec.Code)
+	}
+	if len(m.CachedPrincipals()) != 0 {
+		t.Error("revoked token still cached")
+	}
+}
+
+func TestSubscribeEvictsAndResyncs(t *testing.T) {
+	server, client := newMiniredis(t)
+	m, signer := newTestAuthWith(t, client, RevocationConfig{
+		BreakerThreshold:      3,
+		NegativeCacheTTL:      time.Minute,
+		NegativeCacheCapacity: 16,
+		Channel:               testChannel,
+		WatermarkTTL:          time.Hour,
+		SkipCachedLookups:     true,
+	})
+	c := m.revocations
+	c.Subscribe()
+	waitUntil(t, "subscription", c.SkipsCachedLookups)
+
+	exp := time.Now().Add(time.Hour)
+	cacheToken := func(jti, sub string) {
+		t.Helper()
+		token := signClaims(t, signer, jwt.MapClaims{"sub": sub, "jti": jti, "iat": time.Now().Unix(), "exp": exp.Unix()})
+		if rec, _ := serve(m, bearer(http.MethodGet, token)); rec.Code != http.StatusNoContent {
+			t.Fatalf("status = %d", rec.Code)
+		}
+	}
+	cached := func(jti string) bool {
+		for _, p := range m.CachedPrincipals() {
+			if p.JTI == jti {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Announcements evict matching tokens only
+	cacheToken("a", "user-1")
+	cacheToken("b", "user-2")
+	client.Publish(context.Background(), testChannel, "sub:user-1")
+	waitUntil(t, "subject eviction", func() bool { return !cached("a") })
+	if !cached("b") {
+		t.Error("token of another subject evicted")
+	}
+	client.Publish(context.Background(), testChannel, "b")
+	waitUntil(t, "jti eviction", func() bool { return !cached("b") })
+
+	// Announcements sent while the subscription is down are lost, so
+	// reconnecting must drop everything trusted before
+	cacheToken("c", "user-3")
+	if _, ok := c.notRevoked.Get("c"); !ok {
+		t.Fatal("negative cache not filled")
+	}
+	server.Close()
+	waitUntil(t, "subscription loss", func() bool { return !c.SkipsCachedLookups() })
+	if !cached("c") {
+		t.Fatal("cache flushed before the subscription was re-established")
+	}
+	if err := server.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	waitUntil(t, "resubscription", c.SkipsCachedLookups)
+	if cached("c") {
+		t.Error("claims cache kept across a resync")
+	}
+	if _, ok := c.notRevoked.Get("c"); ok {
+		t.Error("negative cache kept across a resync")
+	}
+
+	c.Close()
+	if c.SkipsCachedLookups() {
+		t.Error("cached lookups still skipped after Close")
+	}
+}
+
+func TestRevokePublishes(t *testing.T) {
+	server, client := newMiniredis(t)
+	c := NewRevocationChecker(client, RevocationConfig{BreakerThreshold: 3, Channel: testChannel, WatermarkTTL: time.Hour})
+	var announced []Revocation
+	c.OnRevoke(func(r Revocation) { announced = append(announced, r) })
+
+	sub := client.Subscribe(context.Background(), testChannel)
+	defer sub.Close()
+	if _, err := sub.Receive(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.RevokeToken(context.Background(), "t1", time.Now().Add(-time.Second)); !errors.Is(err, ErrTokenAlreadyExpired) {
+		t.Fatalf("RevokeToken(expired) = %v", err)
+	}
+	if err := c.RevokeToken(context.Background(), "t1", time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Now().Truncate(time.Second)
+	if err := c.RevokeSubject(context.Background(), "user-1", at); err != nil {
+		t.Fatal(err)
+	}
+
+	if !server.Exists(blacklistKeyPrefix+"t1") || server.TTL(blacklistKeyPrefix+"t1") > time.Minute {
+		t.Errorf("blacklist key missing or outliving the token: ttl %v", server.TTL(blacklistKeyPrefix+"t1"))
+	}
+	if got, _ := server.Get(subjectWatermarkKeyPrefix + "user-1"); got != strconv.FormatInt(at.Unix(), 10) {
+		t.Errorf("subject watermark = %q", got)
+	}
+	if ttl := server.TTL(subjectWatermarkKeyPrefix + "user-1"); ttl != time.Hour {
+		t.Errorf("subject watermark ttl = %v, want the watermark TTL", ttl)
+	}
+
+	for _, want := range []string{"jti:t1", "sub:user-1"} {
+		msg, err := sub.ReceiveMessage(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Payload != want {
+			t.Errorf("announced %q, want %q", msg.Payload, want)
+		}
+	}
+	if len(announced) != 2 {
+		t.Errorf("applied locally %d times, want 2", len(announced))
+	}
+}
+
+func waitUntil(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
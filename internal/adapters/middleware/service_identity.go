@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/metrics"
+)
+
+var serviceAuthentications = metrics.NewCounterVec(
+	"auth_service_authentications_total",
+	"Requests authenticated by a client certificate, by service (\"unmapped\" for verified certificates matching no service).",
+	"service",
+)
+
+// DefaultServiceRole is given to services whose mapping does not name a role.
+const DefaultServiceRole = "SERVICE"
+
+// ServiceIdentity maps a client certificate to a service principal. A
+// certificate matches when any of its URI SANs, DNS SANs or its subject common
+// name is listed.
+type ServiceIdentity struct {
+	Name        string   `json:"name"`
+	Role        string   `json:"role"`
+	URISANs     []string `json:"uri_sans"`
+	DNSSANs     []string `json:"dns_sans"`
+	CommonNames []string `json:"common_names"`
+}
+
+// Principal is the user ID under which the service appears in the request context.
+func (s ServiceIdentity) Principal() string {
+	return "service:" + s.Name
+}
+
+func (s ServiceIdentity) matches(cert *x509.Certificate) bool {
+	for _, uri := range cert.URIs {
+		if slices.Contains(s.URISANs, uri.String()) {
+			return true
+		}
+	}
+	for _, dns := range cert.DNSNames {
+		if slices.Contains(s.DNSSANs, dns) {
+			return true
+		}
+	}
+	return cert.Subject.CommonName != "" && slices.Contains(s.CommonNames, cert.Subject.CommonName)
+}
+
+// ServiceIdentities is the set of trusted services loaded from the identities file.
+type ServiceIdentities struct {
+	Services []ServiceIdentity `json:"services"`
+}
+
+func LoadServiceIdentities(path string) (*ServiceIdentities, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids ServiceIdentities
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, fmt.Errorf("parse service identities: %w", err)
+	}
+	for i := range ids.Services {
+		s := &ids.Services[i]
+		if s.Name == "" {
+			return nil, fmt.Errorf("service identity %d has no name", i)
+		}
+		if len(s.URISANs)+len(s.DNSSANs)+len(s.CommonNames) == 0 {
+			return nil, fmt.Errorf("service identity %q matches no certificate", s.Name)
+		}
+		if s.Role == "" {
+			s.Role = DefaultServiceRole
+		}
+	}
+	return &ids, nil
+}
+
+var errNoClientCertificate = errors.New("no verified client certificate")
+
+// Identify returns the service behind the request's verified client certificate.
+func (ids *ServiceIdentities) Identify(r *http.Request) (ServiceIdentity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ServiceIdentity{}, errNoClientCertificate
+	}
+	leaf := r.TLS.VerifiedChains[0][0]
+	for _, s := range ids.Services {
+		if s.matches(leaf) {
+			return s, nil
+		}
+	}
+	return ServiceIdentity{}, fmt.Errorf("client certificate %q is not mapped to a service", leaf.Subject.String())
+}
@@ -0,0 +1,293 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+)
+
+// testCA issues client certificates for the service identity tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue signs a client certificate with the given common name, DNS SANs and
+// URI SANs ("" for no common name).
+func (ca *testCA) issue(t *testing.T, cn string, dns []string, uris ...string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     dns,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, raw := range uris {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template.URIs = append(template.URIs, u)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+const spiffeID = "spiffe://kliniek.local/ns/media/sa/thumbnailer"
+
+var testIdentities = &ServiceIdentities{Services: []ServiceIdentity{
+	{Name: "thumbnailer", Role: "SERVICE", URISANs: []string{spiffeID}},
+	{Name: "scheduler", Role: "ADMIN", DNSSANs: []string{"scheduler.media.svc"}},
+	{Name: "legacy-importer", Role: "SERVICE", CommonNames: []string{"legacy-importer"}},
+}}
+
+func TestServiceIdentityMatches(t *testing.T) {
+	ca := newTestCA(t)
+	tests := []struct {
+		name string
+		cert tls.Certificate
+		want string
+	}{
+		{"URI SAN", ca.issue(t, "", nil, spiffeID), "thumbnailer"},
+		{"DNS SAN", ca.issue(t, "", []string{"other.svc", "scheduler.media.svc"}), "scheduler"},
+		{"common name", ca.issue(t, "legacy-importer", nil), "legacy-importer"},
+		{"SANs are checked before the common name", ca.issue(t, "legacy-importer", nil, spiffeID), "thumbnailer"},
+		{"URI listed only as DNS", ca.issue(t, "", []string{"kliniek.local"}), ""},
+		{"URI prefix", ca.issue(t, "", nil, spiffeID+"-v2"), ""},
+		{"DNS name as common name", ca.issue(t, "scheduler.media.svc", nil), ""},
+		{"nothing listed", ca.issue(t, "someone", []string{"someone.svc"}), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			for _, s := range testIdentities.Services {
+				if s.matches(tt.cert.Leaf) {
+					got = s.Name
+					break
+				}
+			}
+			if got != tt.want {
+				t.Errorf("matched %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// An empty entry must not match certificates without a common name
+	empty := ServiceIdentity{Name: "empty", CommonNames: []string{""}}
+	if empty.matches(ca.issue(t, "", nil).Leaf) {
+		t.Error("empty common name matched")
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	ca := newTestCA(t)
+	mapped := ca.issue(t, "", nil, spiffeID)
+	unmapped := ca.issue(t, "stranger", nil)
+
+	tests := []struct {
+		name    string
+		state   *tls.ConnectionState
+		want    string
+		wantErr error
+	}{
+		{"plain HTTP", nil, "", errNoClientCertificate},
+		{"TLS without a client certificate", &tls.ConnectionState{}, "", errNoClientCertificate},
+		// Presented but not verified, e.g. RequestClientCert: must not be trusted
+		{"unverified certificate", &tls.ConnectionState{PeerCertificates: []*x509.Certificate{mapped.Leaf}}, "", errNoClientCertificate},
+		{"empty verified chain", &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{}}}, "", errNoClientCertificate},
+		{"verified and mapped", &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{mapped.Leaf, ca.cert}}}, "thumbnailer", nil},
+		{"verified but unmapped", &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{unmapped.Leaf, ca.cert}}}, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.TLS = tt.state
+
+			got, err := testIdentities.Identify(r)
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Identify() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.want == "" && err == nil {
+				t.Fatalf("Identify() = %q, want an error", got.Name)
+			}
+			if tt.want == "" && tt.wantErr == nil && errors.Is(err, errNoClientCertificate) {
+				t.Errorf("unmapped certificate reported as missing: %v", err)
+			}
+			if got.Name != tt.want {
+				t.Errorf("Identify() = %q, want %q", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+// TestAuthenticateClientCertificate runs real TLS handshakes against a server
+// configured like the API, so VerifiedChains is set by crypto/tls.
+func TestAuthenticateClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	_, client := newMiniredis(t)
+	m, signer := newTestAuthWith(t, client, RevocationConfig{BreakerThreshold: 3, WatermarkTTL: time.Hour})
+	m.TrustServices(testIdentities)
+
+	server := httptest.NewUnstartedServer(nil)
+	server.Config.Handler = m.Authenticate(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := domain.PrincipalFromContext(r.Context())
+		w.Write([]byte(principal.ID + " " + principal.Role + " " + string(principal.AuthMethod)))
+	})
+	server.TLS = &tls.Config{ClientCAs: ca.pool, ClientAuth: tls.VerifyClientCertIfGiven}
+	server.StartTLS()
+	defer server.Close()
+
+	get := func(cert *tls.Certificate, header string) (int, string, error) {
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		if cert != nil {
+			transport.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+		}
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		if header != "" {
+			req.Header.Set("Authorization", "Bearer "+header)
+		}
+		resp, err := (&http.Client{Transport: transport}).Do(req)
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return resp.StatusCode, strings.TrimSpace(string(body)), err
+	}
+
+	mapped := ca.issue(t, "", []string{"scheduler.media.svc"})
+	code, body, err := get(&mapped, "")
+	if err != nil || code != http.StatusOK || body != "service:scheduler ADMIN certificate" {
+		t.Errorf("mapped certificate = %d %q %v", code, body, err)
+	}
+
+	unmapped := ca.issue(t, "stranger", nil)
+	if code, _, _ := get(&unmapped, ""); code != http.StatusUnauthorized {
+		t.Errorf("unmapped certificate = %d, want 401", code)
+	}
+
+	if code, _, _ := get(nil, ""); code != http.StatusUnauthorized {
+		t.Errorf("no certificate = %d, want 401", code)
+	}
+
+	foreign := newTestCA(t).issue(t, "", []string{"scheduler.media.svc"})
+	if _, _, err := get(&foreign, ""); err == nil {
+		t.Error("certificate from an untrusted CA completed the handshake")
+	}
+
+	// A forwarded user token wins over the service certificate
+	token := signToken(t, signer, "t1", time.Now().Add(time.Hour))
+	code, body, err = get(&mapped, token)
+	if err != nil || code != http.StatusOK || body != "user-1  token" {
+		t.Errorf("certificate with a bearer token = %d %q %v, want the token's principal", code, body, err)
+	}
+}
+
+func TestLoadServiceIdentities(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"valid", `{"services":[{"name":"thumbnailer","uri_sans":["` + spiffeID + `"]},{"name":"scheduler","role":"ADMIN","dns_sans":["scheduler.media.svc"]}]}`, ""},
+		{"no services", `{"services":[]}`, ""},
+		{"malformed JSON", `{"services":[`, "parse service identities"},
+		{"missing name", `{"services":[{"common_names":["x"]}]}`, "service identity 0 has no name"},
+		{"no matchers", `{"services":[{"name":"thumbnailer","role":"SERVICE"}]}`, `"thumbnailer" matches no certificate`},
+		{"empty matcher lists", `{"services":[{"name":"thumbnailer","uri_sans":[],"dns_sans":[],"common_names":[]}]}`, "matches no certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "identities.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := LoadServiceIdentities(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadServiceIdentities() error = %v", err)
+			}
+		})
+	}
+
+	t.Run("default role", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "identities.json")
+		os.WriteFile(path, []byte(`{"services":[{"name":"thumbnailer","uri_sans":["`+spiffeID+`"]},{"name":"scheduler","role":"ADMIN","dns_sans":["s"]}]}`), 0o600)
+		ids, err := LoadServiceIdentities(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids.Services[0].Role != DefaultServiceRole || ids.Services[1].Role != "ADMIN" {
+			t.Errorf("roles = %q, %q", ids.Services[0].Role, ids.Services[1].Role)
+		}
+	})
+
+	if _, err := LoadServiceIdentities(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file error = %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/keys"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/metrics"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenPolicy lists the registered-claims checks applied to every token on top of
+// the signature. Empty Issuers/Audience and a zero MaxAge disable that check.
+type TokenPolicy struct {
+	Issuers    []string
+	Audience   string
+	Leeway     time.Duration
+	MaxAge     time.Duration
+	Algorithms []string
+}
+
+var (
+	errMalformedToken       = errors.New("malformed token")
+	errMissingJTI           = errors.New("missing jti")
+	errMissingExp           = errors.New("missing exp")
+	errMissingIat           = errors.New("missing iat")
+	errTokenExpired         = errors.New("token expired")
+	errTokenNotYetValid     = errors.New("token not valid yet")
+	errTokenIssuedInFuture  = errors.New("token issued in the future")
+	errTokenTooOld          = errors.New("token exceeds maximum age")
+	errInvalidIssuer        = errors.New("unexpected issuer")
+	errInvalidAudience      = errors.New("unexpected audience")
+	errAlgorithmNotAllowed  = errors.New("signing algorithm not allowed")
+	errKeyAlgorithmMismatch = errors.New("no key bound to the token's algorithm")
+)
+
+var tokenRejections = metrics.NewCounterVec(
+	"auth_token_rejections_total",
+	"Tokens rejected by the auth middleware by reason.",
+	"reason",
+)
+
+// rejectionReason maps a validation error onto a bounded metric/log label.
+func rejectionReason(err error) string {
+	switch {
+	case errors.Is(err, errMalformedToken), errors.Is(err, jwt.ErrTokenMalformed):
+		return "malformed"
+	case errors.Is(err, errMissingJTI):
+		return "missing_jti"
+	case errors.Is(err, errMissingExp), errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return "missing_exp"
+	case errors.Is(err, errMissingIat):
+		return "missing_iat"
+	case errors.Is(err, errTokenExpired), errors.Is(err, jwt.ErrTokenExpired):
+		return "expired"
+	case errors.Is(err, errTokenNotYetValid), errors.Is(err, jwt.ErrTokenNotValidYet):
+		return "not_yet_valid"
+	case errors.Is(err, errTokenIssuedInFuture), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return "issued_in_future"
+	case errors.Is(err, errTokenTooOld):
+		return "too_old"
+	case errors.Is(err, errInvalidIssuer), errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return "invalid_issuer"
+	case errors.Is(err, errInvalidAudience), errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return "invalid_audience"
+	case errors.Is(err, errAlgorithmNotAllowed):
+		return "algorithm_not_allowed"
+	case errors.Is(err, errKeyAlgorithmMismatch):
+		return "key_algorithm_mismatch"
+	case errors.Is(err, keys.ErrUnknownKey), errors.Is(err, keys.ErrNoKeys):
+		return "unknown_key"
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return "invalid_signature"
+	default:
+		return "invalid"
+	}
+}
+
+func (p TokenPolicy) algorithmAllowed(alg string) bool {
+	return slices.Contains(p.Algorithms, alg)
+}
+
+// validateTimes checks exp, nbf, iat and the maximum token age. It runs on every
+// request, including cache hits, because the outcome changes as time passes.
+func (p TokenPolicy) validateTimes(claims jwt.MapClaims, now time.Time) error {
+	exp, err := claims.GetExpirationTime()
+	if err != nil {
+		return errMalformedToken
+	}
+	if exp == nil {
+		return errMissingExp
+	}
+	if now.After(exp.Add(p.Leeway)) {
+		return errTokenExpired
+	}
+
+	nbf, err := claims.GetNotBefore()
+	if err != nil {
+		return errMalformedToken
+	}
+	if nbf != nil && now.Add(p.Leeway).Before(nbf.Time) {
+		return errTokenNotYetValid
+	}
+
+	iat, err := claims.GetIssuedAt()
+	if err != nil {
+		return errMalformedToken
+	}
+	if iat == nil {
+		if p.MaxAge > 0 {
+			return errMissingIat
+		}
+		return nil
+	}
+	if now.Add(p.Leeway).Before(iat.Time) {
+		return errTokenIssuedInFuture
+	}
+	if p.MaxAge > 0 && now.Sub(iat.Time) > p.MaxAge+p.Leeway {
+		return errTokenTooOld
+	}
+	return nil
+}
+
+// validateIssuerAndAudience checks the claims that do not depend on time. It only
+// needs to run once per token, before the claims are cached.
+func (p TokenPolicy) validateIssuerAndAudience(claims jwt.MapClaims) error {
+	if len(p.Issuers) > 0 {
+		iss, _ := claims.GetIssuer()
+		if !slices.Contains(p.Issuers, iss) {
+			return errInvalidIssuer
+		}
+	}
+
+	if p.Audience != "" {
+		aud, err := claims.GetAudience()
+		if err != nil || !slices.Contains([]string(aud), p.Audience) {
+			return errInvalidAudience
+		}
+	}
+	return nil
+}
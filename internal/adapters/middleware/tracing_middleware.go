@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/tracing"
+)
+
+// Tracing continues the caller's W3C trace (traceparent/tracestate) or starts a new
+// one, and wraps the request in a server span named after the matched route.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if parent, ok := tracing.Extract(r.Header); ok {
+			ctx = tracing.ContextWithRemoteParent(ctx, parent)
+		}
+
+		ctx, span := tracing.Start(ctx, "HTTP "+r.Method, tracing.KindServer,
+			slog.String("http.method", r.Method),
+			slog.String("http.target", r.URL.Path),
+		)
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		r = r.WithContext(ctx)
+		next.ServeHTTP(rec, r)
+
+		if r.Pattern != "" {
+			span.SetName(r.Pattern)
+			span.SetAttributes(slog.String("http.route", r.Pattern))
+		}
+		span.SetAttributes(slog.Int("http.status_code", rec.status))
+		if rec.status >= 500 {
+			span.RecordError(fmt.Errorf("HTTP %d", rec.status))
+		}
+	})
+}
@@ -0,0 +1,64 @@
+// Package notification contains Notifier implementations.
+package notification
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+var notificationsSent = metrics.NewCounterVec(
+	"notifications_sent_total",
+	"Notifications handed to the notification stream by type and outcome.",
+	"type", "outcome",
+)
+
+// maxStreamLength caps the stream so it does not grow without bound when no
+// consumer is reading it; older entries are trimmed first.
+const maxStreamLength = 100000
+
+// RedisNotifier appends notifications to a Redis stream. The notification
+// service reads the stream with a consumer group and delivers each entry to
+// its recipient, so nothing is lost while it is down.
+type RedisNotifier struct {
+	redisClient *redis.Client
+	stream      string
+}
+
+var _ ports.Notifier = (*RedisNotifier)(nil)
+
+func NewRedisNotifier(redisClient *redis.Client, stream string) *RedisNotifier {
+	return &RedisNotifier{
+		redisClient: redisClient,
+		stream:      stream,
+	}
+}
+
+func (n *RedisNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	err = n.redisClient.XAdd(ctx, &redis.XAddArgs{
+		Stream: n.stream,
+		MaxLen: maxStreamLength,
+		Approx: true,
+		Values: map[string]any{
+			"type":      notification.Type,
+			"recipient": notification.Recipient,
+			"payload":   string(payload),
+		},
+	}).Err()
+
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	notificationsSent.WithLabelValues(notification.Type, outcome).Inc()
+	return err
+}
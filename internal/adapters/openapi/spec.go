@@ -0,0 +1,212 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+//go:embed openapi.json
+var specJSON []byte
+
+//go:embed docs.html
+var docsHTML []byte
+
+// Spec is the subset of an OpenAPI 3 document needed to route and validate requests.
+type Spec struct {
+	OpenAPI    string              `json:"openapi"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+
+	routes []route
+}
+
+type Components struct {
+	Schemas    map[string]*Schema   `json:"schemas"`
+	Parameters map[string]Parameter `json:"parameters"`
+	Responses  map[string]Response  `json:"responses"`
+}
+
+type PathItem struct {
+	Parameters []Parameter `json:"parameters"`
+	Get        *Operation  `json:"get"`
+	Post       *Operation  `json:"post"`
+	Put        *Operation  `json:"put"`
+	Patch      *Operation  `json:"patch"`
+	Delete     *Operation  `json:"delete"`
+}
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Parameters  []Parameter         `json:"parameters"`
+	RequestBody *RequestBody        `json:"requestBody"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// route is a resolved operation bound to its path template.
+type route struct {
+	method     string
+	template   string
+	segments   []string
+	operation  *Operation
+	parameters []Parameter
+}
+
+// Document returns the raw embedded OpenAPI document.
+func Document() []byte {
+	return specJSON
+}
+
+// DocsPage returns the embedded HTML viewer for the document.
+func DocsPage() []byte {
+	return docsHTML
+}
+
+// Load parses the embedded document and resolves its local references.
+func Load() (*Spec, error) {
+	return Parse(specJSON)
+}
+
+func Parse(data []byte) (*Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("parse openapi document: %w", err)
+	}
+
+	for template, item := range spec.Paths {
+		ops := map[string]*Operation{
+			http.MethodGet:    item.Get,
+			http.MethodPost:   item.Post,
+			http.MethodPut:    item.Put,
+			http.MethodPatch:  item.Patch,
+			http.MethodDelete: item.Delete,
+		}
+		for method, op := range ops {
+			if op == nil {
+				continue
+			}
+			params, err := spec.resolveParameters(append(append([]Parameter{}, item.Parameters...), op.Parameters...))
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, template, err)
+			}
+			for code, resp := range op.Responses {
+				resolved, err := spec.resolveResponse(resp)
+				if err != nil {
+					return nil, fmt.Errorf("%s %s response %s: %w", method, template, code, err)
+				}
+				op.Responses[code] = resolved
+			}
+			spec.routes = append(spec.routes, route{
+				method:     method,
+				template:   template,
+				segments:   splitPath(template),
+				operation:  op,
+				parameters: params,
+			})
+		}
+	}
+
+	return &spec, nil
+}
+
+// findRoute returns the operation matching the request method and path
+// together with the extracted path parameters.
+func (s *Spec) findRoute(method, path string) (*route, map[string]string, bool) {
+	segments := splitPath(path)
+	for i := range s.routes {
+		rt := &s.routes[i]
+		if rt.method != method || len(rt.segments) != len(segments) {
+			continue
+		}
+		params, ok := matchSegments(rt.segments, segments)
+		if ok {
+			return rt, params, true
+		}
+	}
+	return nil, nil, false
+}
+
+func (s *Spec) resolveParameters(params []Parameter) ([]Parameter, error) {
+	resolved := make([]Parameter, 0, len(params))
+	for _, p := range params {
+		if p.Ref != "" {
+			name := strings.TrimPrefix(p.Ref, "#/components/parameters/")
+			ref, ok := s.Components.Parameters[name]
+			if !ok {
+				return nil, fmt.Errorf("unresolved parameter reference %q", p.Ref)
+			}
+			p = ref
+		}
+		resolved = append(resolved, p)
+	}
+	return resolved, nil
+}
+
+func (s *Spec) resolveResponse(resp Response) (Response, error) {
+	if resp.Ref == "" {
+		return resp, nil
+	}
+	name := strings.TrimPrefix(resp.Ref, "#/components/responses/")
+	ref, ok := s.Components.Responses[name]
+	if !ok {
+		return Response{}, fmt.Errorf("unresolved response reference %q", resp.Ref)
+	}
+	return ref, nil
+}
+
+func (s *Spec) resolveSchema(schema *Schema) (*Schema, error) {
+	for schema != nil && schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		ref, ok := s.Components.Schemas[name]
+		if !ok {
+			return nil, fmt.Errorf("unresolved schema reference %q", schema.Ref)
+		}
+		schema = ref
+	}
+	return schema, nil
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func matchSegments(template, actual []string) (map[string]string, bool) {
+	params := make(map[string]string)
+	for i, seg := range template {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			if actual[i] == "" {
+				return nil, false
+			}
+			params[seg[1:len(seg)-1]] = actual[i]
+			continue
+		}
+		if seg != actual[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
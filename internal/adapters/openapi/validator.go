@@ -0,0 +1,310 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrNoOperation is returned when the request does not map to any documented operation.
+var ErrNoOperation = errors.New("no matching operation")
+
+// Schema is the subset of JSON Schema supported by the validator.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Enum                 []any              `json:"enum"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties *Schema            `json:"-"`
+	NoAdditional         bool               `json:"-"`
+	Items                *Schema            `json:"items"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	Nullable             bool               `json:"nullable"`
+}
+
+// UnmarshalJSON accepts both the boolean and schema forms of additionalProperties.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	type plain Schema
+	var aux struct {
+		plain
+		AdditionalProperties json.RawMessage `json:"additionalProperties"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*s = Schema(aux.plain)
+
+	raw := bytes.TrimSpace(aux.AdditionalProperties)
+	switch {
+	case len(raw) == 0 || bytes.Equal(raw, []byte("true")):
+	case bytes.Equal(raw, []byte("false")):
+		s.NoAdditional = true
+	default:
+		s.AdditionalProperties = new(Schema)
+		if err := json.Unmarshal(raw, s.AdditionalProperties); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateRequest checks path, query and header parameters and the JSON body of r
+// against the matching operation. body is the already-read request body.
+func (s *Spec) ValidateRequest(r *http.Request, body []byte) error {
+	rt, pathParams, ok := s.findRoute(r.Method, r.URL.Path)
+	if !ok {
+		return ErrNoOperation
+	}
+
+	for _, p := range rt.parameters {
+		var value string
+		var present bool
+		switch p.In {
+		case "path":
+			value, present = pathParams[p.Name]
+		case "query":
+			present = r.URL.Query().Has(p.Name)
+			value = r.URL.Query().Get(p.Name)
+		case "header":
+			value = r.Header.Get(p.Name)
+			present = value != ""
+		default:
+			continue
+		}
+		if !present {
+			if p.Required {
+				return fmt.Errorf("missing required %s parameter %q", p.In, p.Name)
+			}
+			continue
+		}
+		if err := s.validateParameter(p, value); err != nil {
+			return err
+		}
+	}
+
+	rb := rt.operation.RequestBody
+	if rb == nil {
+		return nil
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		if rb.Required {
+			return errors.New("request body is required")
+		}
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "" {
+		mediaType = "application/json"
+	}
+	content, ok := rb.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("unsupported content type %q", mediaType)
+	}
+	return s.validateJSON(content.Schema, body, "body")
+}
+
+// ValidateResponse checks that status is documented for the operation and that
+// a JSON body matches the documented schema.
+func (s *Spec) ValidateResponse(method, path string, status int, contentType string, body []byte) error {
+	rt, _, ok := s.findRoute(method, path)
+	if !ok {
+		return ErrNoOperation
+	}
+
+	resp, ok := rt.operation.Responses[strconv.Itoa(status)]
+	if !ok {
+		resp, ok = rt.operation.Responses["default"]
+	}
+	if !ok {
+		return fmt.Errorf("undocumented status %d", status)
+	}
+	if len(resp.Content) == 0 {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	content, ok := resp.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("undocumented content type %q for status %d", mediaType, status)
+	}
+	if mediaType != "application/json" {
+		return nil
+	}
+	return s.validateJSON(content.Schema, body, "response")
+}
+
+func (s *Spec) validateParameter(p Parameter, raw string) error {
+	schema, err := s.resolveSchema(p.Schema)
+	if err != nil || schema == nil {
+		return err
+	}
+
+	var value any = raw
+	switch schema.Type {
+	case "integer":
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s parameter %q must be an integer", p.In, p.Name)
+		}
+		value = float64(n)
+	case "number":
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%s parameter %q must be a number", p.In, p.Name)
+		}
+		value = n
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s parameter %q must be a boolean", p.In, p.Name)
+		}
+		value = b
+	}
+	return s.validateValue(schema, value, p.Name)
+}
+
+func (s *Spec) validateJSON(schema *Schema, body []byte, location string) error {
+	if schema == nil {
+		return nil
+	}
+	var value any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return fmt.Errorf("%s is not valid JSON: %w", location, err)
+	}
+	return s.validateValue(schema, normalize(value), location)
+}
+
+func (s *Spec) validateValue(schema *Schema, value any, path string) error {
+	schema, err := s.resolveSchema(schema)
+	if err != nil || schema == nil {
+		return err
+	}
+
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return fmt.Errorf("%s must not be null", path)
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		return fmt.Errorf("%s must be one of %v", path, schema.Enum)
+	}
+
+	switch schema.Type {
+	case "":
+		return nil
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", path)
+		}
+		if schema.MinLength != nil && len(str) < *schema.MinLength {
+			return fmt.Errorf("%s must be at least %d characters", path, *schema.MinLength)
+		}
+		if schema.MaxLength != nil && len(str) > *schema.MaxLength {
+			return fmt.Errorf("%s must be at most %d characters", path, *schema.MaxLength)
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				return fmt.Errorf("%s must be an RFC 3339 date-time", path)
+			}
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%s must be a %s", path, schema.Type)
+		}
+		if schema.Type == "integer" && n != float64(int64(n)) {
+			return fmt.Errorf("%s must be an integer", path)
+		}
+		if schema.Minimum != nil && n < *schema.Minimum {
+			return fmt.Errorf("%s must be >= %v", path, *schema.Minimum)
+		}
+		if schema.Maximum != nil && n > *schema.Maximum {
+			return fmt.Errorf("%s must be <= %v", path, *schema.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean", path)
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s must be an array", path)
+		}
+		for i, item := range items {
+			if err := s.validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s must be an object", path)
+		}
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s.%s is required", path, name)
+			}
+		}
+		for name, v := range obj {
+			prop, known := schema.Properties[name]
+			switch {
+			case known:
+			case schema.AdditionalProperties != nil:
+				prop = schema.AdditionalProperties
+			case schema.NoAdditional:
+				return fmt.Errorf("%s.%s is not allowed", path, name)
+			default:
+				continue
+			}
+			if err := s.validateValue(prop, v, path+"."+name); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%s has unsupported schema type %q", path, schema.Type)
+	}
+	return nil
+}
+
+// normalize converts json.Number values into float64 so numeric checks are uniform.
+func normalize(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i := range v {
+			v[i] = normalize(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = normalize(v[k])
+		}
+	}
+	return value
+}
+
+func inEnum(enum []any, value any) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
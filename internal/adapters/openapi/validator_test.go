@@ -0,0 +1,109 @@
+package openapi
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateRequest(t *testing.T) {
+	spec, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		wantErr string
+		noOp    bool
+	}{
+		{
+			name:   "valid create",
+			method: "POST", target: "/media/videos",
+			body: `{"url": "https://cdn.example/v.mp4", "content_type": "SLEEPING"}`,
+		},
+		{
+			name:   "unknown route",
+			method: "GET", target: "/media/unknown",
+			noOp: true,
+		},
+		{
+			name:   "bad parameter type",
+			method: "POST", target: "/media/videos/abc/revisions/first/restore",
+			wantErr: `path parameter "rev" must be an integer`,
+		},
+		{
+			name:   "parameter below minimum",
+			method: "POST", target: "/media/videos/abc/revisions/0/restore",
+			wantErr: "rev must be >= 1",
+		},
+		{
+			name:   "missing required field",
+			method: "POST", target: "/media/videos",
+			body:    `{"content_type": "SLEEPING"}`,
+			wantErr: "body.url is required",
+		},
+		{
+			name:   "wrong field type",
+			method: "POST", target: "/media/videos",
+			body:    `{"url": 5, "content_type": "SLEEPING"}`,
+			wantErr: "body.url must be a string",
+		},
+		{
+			name:   "value outside enum",
+			method: "POST", target: "/media/videos",
+			body:    `{"url": "u", "content_type": "DANCING"}`,
+			wantErr: "body.content_type must be one of",
+		},
+		{
+			name:   "invalid JSON",
+			method: "POST", target: "/media/videos",
+			body:    `{"url": `,
+			wantErr: "body is not valid JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+
+			err := spec.ValidateRequest(r, []byte(tt.body))
+			switch {
+			case tt.noOp:
+				if !errors.Is(err, ErrNoOperation) {
+					t.Fatalf("error = %v, want ErrNoOperation", err)
+				}
+			case tt.wantErr == "":
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			case err == nil || !strings.Contains(err.Error(), tt.wantErr):
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	spec, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	ok := `{"id": "1", "url": "u", "content_type": "SLEEPING", "description": "", "status": "DRAFT", "revision": 1}`
+	if err := spec.ValidateResponse("GET", "/media/videos/1", 200, "application/json", []byte(ok)); err != nil {
+		t.Errorf("valid response: %v", err)
+	}
+	missing := `{"id": "1", "url": "u", "content_type": "SLEEPING", "description": ""}`
+	if err := spec.ValidateResponse("GET", "/media/videos/1", 200, "application/json", []byte(missing)); err == nil {
+		t.Error("response without status was accepted")
+	}
+	if err := spec.ValidateResponse("GET", "/media/videos/1", 418, "text/plain", nil); err == nil {
+		t.Error("undocumented status was accepted")
+	}
+}
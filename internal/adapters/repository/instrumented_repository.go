@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/metrics"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/tracing"
+)
+
+var (
+	mongoOperationDuration = metrics.NewHistogramVec(
+		"mongo_operation_duration_seconds",
+		"Latency of repository operations against MongoDB.",
+		metrics.DefBuckets,
+		"operation",
+	)
+	mongoOperationErrors = metrics.NewCounterVec(
+		"mongo_operation_errors_total",
+		"Repository operations against MongoDB that returned an error.",
+		"operation",
+	)
+)
+
+// InstrumentedRepository records latency, errors and a client span for every call
+// to the wrapped repository.
+type InstrumentedRepository struct {
+	next ports.VideoRepository
+}
+
+var _ ports.VideoRepository = (*InstrumentedRepository)(nil)
+
+func NewInstrumentedRepository(next ports.VideoRepository) *InstrumentedRepository {
+	return &InstrumentedRepository{
+		next: next,
+	}
+}
+
+func (r *InstrumentedRepository) GetVideos(ctx context.Context, query domain.VideoQuery) ([]domain.Video, error) {
+	ctx, done := observe(ctx, "GetVideos")
+	videos, err := r.next.GetVideos(ctx, query)
+	done(err)
+	return videos, err
+}
+
+func (r *InstrumentedRepository) GetVideoByID(ctx context.Context, id string) (*domain.Video, error) {
+	ctx, done := observe(ctx, "GetVideoByID")
+	video, err := r.next.GetVideoByID(ctx, id)
+	done(expectedAsSuccess(err, domain.ErrVideoNotFound))
+	return video, err
+}
+
+func (r *InstrumentedRepository) GetVisibleVideoByID(ctx context.Context, id string) (*domain.Video, error) {
+	ctx, done := observe(ctx, "GetVisibleVideoByID")
+	video, err := r.next.GetVisibleVideoByID(ctx, id)
+	done(expectedAsSuccess(err, domain.ErrVideoNotFound))
+	return video, err
+}
+
+func (r *InstrumentedRepository) CreateVideo(ctx context.Context, video domain.Video) (*domain.Video, error) {
+	ctx, done := observe(ctx, "CreateVideo")
+	created, err := r.next.CreateVideo(ctx, video)
+	done(err)
+	return created, err
+}
+
+func (r *InstrumentedRepository) UpdateVideo(ctx context.Context, video domain.Video, expectedRevision int) error {
+	ctx, done := observe(ctx, "UpdateVideo")
+	err := r.next.UpdateVideo(ctx, video, expectedRevision)
+	done(expectedAsSuccess(err, domain.ErrVideoNotFound, domain.ErrVideoConflict))
+	return err
+}
+
+func (r *InstrumentedRepository) DeleteVideo(ctx context.Context, id string) error {
+	ctx, done := observe(ctx, "DeleteVideo")
+	err := r.next.DeleteVideo(ctx, id)
+	done(expectedAsSuccess(err, domain.ErrVideoNotFound))
+	return err
+}
+
+func (r *InstrumentedRepository) GetDueVideos(ctx context.Context, now time.Time) ([]domain.Video, error) {
+	ctx, done := observe(ctx, "GetDueVideos")
+	videos, err := r.next.GetDueVideos(ctx, now)
+	done(err)
+	return videos, err
+}
+
+func (r *InstrumentedRepository) NextScheduledTime(ctx context.Context, now time.Time) (*time.Time, error) {
+	ctx, done := observe(ctx, "NextScheduledTime")
+	next, err := r.next.NextScheduledTime(ctx, now)
+	done(err)
+	return next, err
+}
+
+// InstrumentedRevisionRepository is the VideoRevisionRepository counterpart of InstrumentedRepository.
+type InstrumentedRevisionRepository struct {
+	next ports.VideoRevisionRepository
+}
+
+var _ ports.VideoRevisionRepository = (*InstrumentedRevisionRepository)(nil)
+
+func NewInstrumentedRevisionRepository(next ports.VideoRevisionRepository) *InstrumentedRevisionRepository {
+	return &InstrumentedRevisionRepository{
+		next: next,
+	}
+}
+
+func (r *InstrumentedRevisionRepository) AddRevision(ctx context.Context, revision domain.VideoRevision) error {
+	ctx, done := observe(ctx, "AddRevision")
+	err := r.next.AddRevision(ctx, revision)
+	done(expectedAsSuccess(err, domain.ErrVideoConflict))
+	return err
+}
+
+func (r *InstrumentedRevisionRepository) ListRevisions(ctx context.Context, videoID string) ([]domain.VideoRevision, error) {
+	ctx, done := observe(ctx, "ListRevisions")
+	revisions, err := r.next.ListRevisions(ctx, videoID)
+	done(err)
+	return revisions, err
+}
+
+func (r *InstrumentedRevisionRepository) GetRevision(ctx context.Context, videoID string, revision int) (*domain.VideoRevision, error) {
+	ctx, done := observe(ctx, "GetRevision")
+	rev, err := r.next.GetRevision(ctx, videoID, revision)
+	done(expectedAsSuccess(err, domain.ErrRevisionNotFound))
+	return rev, err
+}
+
+// InstrumentedAPIKeyRepository is the APIKeyRepository counterpart of InstrumentedRepository.
+type InstrumentedAPIKeyRepository struct {
+	next ports.APIKeyRepository
+}
+
+var _ ports.APIKeyRepository = (*InstrumentedAPIKeyRepository)(nil)
+
+func NewInstrumentedAPIKeyRepository(next ports.APIKeyRepository) *InstrumentedAPIKeyRepository {
+	return &InstrumentedAPIKeyRepository{
+		next: next,
+	}
+}
+
+func (r *InstrumentedAPIKeyRepository) CreateAPIKey(ctx context.Context, key domain.APIKey) error {
+	ctx, done := observe(ctx, "CreateAPIKey")
+	err := r.next.CreateAPIKey(ctx, key)
+	done(err)
+	return err
+}
+
+func (r *InstrumentedAPIKeyRepository) GetAPIKey(ctx context.Context, id string) (*domain.APIKey, error) {
+	ctx, done := observe(ctx, "GetAPIKey")
+	key, err := r.next.GetAPIKey(ctx, id)
+	done(expectedAsSuccess(err, domain.ErrAPIKeyNotFound))
+	return key, err
+}
+
+func (r *InstrumentedAPIKeyRepository) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	ctx, done := observe(ctx, "ListAPIKeys")
+	keys, err := r.next.ListAPIKeys(ctx)
+	done(err)
+	return keys, err
+}
+
+func (r *InstrumentedAPIKeyRepository) DeleteAPIKey(ctx context.Context, id string) error {
+	ctx, done := observe(ctx, "DeleteAPIKey")
+	err := r.next.DeleteAPIKey(ctx, id)
+	done(err)
+	return err
+}
+
+func (r *InstrumentedAPIKeyRepository) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	ctx, done := observe(ctx, "TouchAPIKey")
+	err := r.next.TouchAPIKey(ctx, id, usedAt)
+	done(err)
+	return err
+}
+
+// expectedAsSuccess hides the expected errors from the error metrics: an unknown
+// ID, like a lost optimistic concurrency check, is a normal outcome, not a
+// database error.
+func expectedAsSuccess(err error, expected ...error) error {
+	for _, e := range expected {
+		if errors.Is(err, e) {
+			return nil
+		}
+	}
+	return err
+}
+
+// observe starts a span for operation and returns a function that records its outcome.
+func observe(ctx context.Context, operation string) (context.Context, func(error)) {
+	ctx, span := tracing.Start(ctx, "mongo."+operation, tracing.KindClient,
+		slog.String("db.system", "mongodb"),
+		slog.String("db.operation", operation),
+	)
+	start := time.Now()
+
+	return ctx, func(err error) {
+		mongoOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+		if err != nil {
+			mongoOperationErrors.WithLabelValues(operation).Inc()
+			span.RecordError(err)
+		}
+		span.End()
+	}
+}
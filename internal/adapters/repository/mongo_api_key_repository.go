@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoAPIKeyRepository struct {
+	mongoAPIKeyCollection *mongo.Collection
+}
+
+var _ ports.APIKeyRepository = (*MongoAPIKeyRepository)(nil)
+
+func NewMongoAPIKeyRepository(mongodb *mongo.Client) *MongoAPIKeyRepository {
+	return &MongoAPIKeyRepository{
+		mongoAPIKeyCollection: mongodb.Database("media").Collection("api_keys"),
+	}
+}
+
+func (r *MongoAPIKeyRepository) CreateAPIKey(ctx context.Context, key domain.APIKey) error {
+	_, err := r.mongoAPIKeyCollection.InsertOne(ctx, key)
+	return err
+}
+
+func (r *MongoAPIKeyRepository) GetAPIKey(ctx context.Context, id string) (*domain.APIKey, error) {
+	var key domain.APIKey
+
+	err := r.mongoAPIKeyCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&key)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r *MongoAPIKeyRepository) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.mongoAPIKeyCollection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	keys := make([]domain.APIKey, 0)
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (r *MongoAPIKeyRepository) DeleteAPIKey(ctx context.Context, id string) error {
+	result, err := r.mongoAPIKeyCollection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return domain.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (r *MongoAPIKeyRepository) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	_, err := r.mongoAPIKeyCollection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"last_used_at": usedAt}},
+	)
+	return err
+}
@@ -3,11 +3,13 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
 	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MongoRepository struct {
@@ -23,37 +25,40 @@ func NewMongoRepository(mongodb *mongo.Client) *MongoRepository {
 	}
 }
 
-func (r *MongoRepository) GetVideos(ctx context.Context) ([]domain.Video, error) {
-	filter := bson.M{}
+// publishedStatus matches published videos, including those stored before
+// videos had a status.
+var publishedStatus = bson.M{"$in": bson.A{domain.StatusPublished, nil}}
 
-	// Cursor is a MongoDB stream that we can iterate over
-	cursor, err := r.mongoVideoCollection.Find(ctx, filter)
-	if err != nil {
-		return nil, err
+func (r *MongoRepository) GetVideos(ctx context.Context, query domain.VideoQuery) ([]domain.Video, error) {
+	filter := bson.M{}
+	if query.PublishedOnly {
+		filter = scheduleFilter(time.Now())
+		filter["status"] = publishedStatus
 	}
-	defer cursor.Close(ctx)
-
-	videos := make([]domain.Video, 0)
+	return r.findVideos(ctx, filter)
+}
 
-	if err := cursor.All(ctx, &videos); err != nil {
-		return nil, err
-	}
+func (r *MongoRepository) GetVideoByID(ctx context.Context, id string) (*domain.Video, error) {
+	return r.findVideo(ctx, bson.M{"_id": id})
+}
 
-	return videos, nil
+func (r *MongoRepository) GetVisibleVideoByID(ctx context.Context, id string) (*domain.Video, error) {
+	filter := scheduleFilter(time.Now())
+	filter["_id"] = id
+	return r.findVideo(ctx, filter)
 }
 
-func (r *MongoRepository) GetVideoByID(ctx context.Context, id string) (*domain.Video, error) {
+func (r *MongoRepository) findVideo(ctx context.Context, filter bson.M) (*domain.Video, error) {
 	var video domain.Video
 
-	filter := bson.M{"_id": id}
-
 	err := r.mongoVideoCollection.FindOne(ctx, filter).Decode(&video)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("video not found")
+			return nil, domain.ErrVideoNotFound
 		}
 		return nil, err
 	}
+	applyVideoDefaults(&video)
 
 	return &video, nil
 }
@@ -67,6 +72,32 @@ func (r *MongoRepository) CreateVideo(ctx context.Context, video domain.Video) (
 	return &video, nil
 }
 
+func (r *MongoRepository) UpdateVideo(ctx context.Context, video domain.Video, expectedRevision int) error {
+	filter := bson.M{"_id": video.ID, "revision": expectedRevision}
+	if expectedRevision == 0 {
+		// Videos stored before revisions were kept have no revision field
+		filter["revision"] = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	result, err := r.mongoVideoCollection.ReplaceOne(ctx, filter, video)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		count, err := r.mongoVideoCollection.CountDocuments(ctx, bson.M{"_id": video.ID})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return domain.ErrVideoNotFound
+		}
+		return domain.ErrVideoConflict
+	}
+
+	return nil
+}
+
 func (r *MongoRepository) DeleteVideo(ctx context.Context, id string) error {
 	filter := bson.M{"_id": id}
 
@@ -76,8 +107,97 @@ func (r *MongoRepository) DeleteVideo(ctx context.Context, id string) error {
 	}
 
 	if result.DeletedCount == 0 {
-		return errors.New("video not found")
+		return domain.ErrVideoNotFound
 	}
 
 	return nil
 }
+
+func (r *MongoRepository) GetDueVideos(ctx context.Context, now time.Time) ([]domain.Video, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{
+			"status":     domain.StatusApproved,
+			"publish_at": bson.M{"$lte": now},
+			"$or": bson.A{
+				bson.M{"unpublish_at": nil},
+				bson.M{"unpublish_at": bson.M{"$gt": now}},
+			},
+		},
+		bson.M{"status": publishedStatus, "unpublish_at": bson.M{"$lte": now}},
+	}}
+	return r.findVideos(ctx, filter)
+}
+
+func (r *MongoRepository) NextScheduledTime(ctx context.Context, now time.Time) (*time.Time, error) {
+	candidates := []struct {
+		field  string
+		filter bson.M
+		at     func(domain.Video) *time.Time
+	}{
+		{
+			field:  "publish_at",
+			filter: bson.M{"status": domain.StatusApproved, "publish_at": bson.M{"$gt": now}},
+			at:     func(v domain.Video) *time.Time { return v.Schedule.PublishAt },
+		},
+		{
+			field:  "unpublish_at",
+			filter: bson.M{"status": publishedStatus, "unpublish_at": bson.M{"$gt": now}},
+			at:     func(v domain.Video) *time.Time { return v.Schedule.UnpublishAt },
+		},
+	}
+
+	var next *time.Time
+	for _, c := range candidates {
+		var video domain.Video
+		opts := options.FindOne().SetSort(bson.D{{Key: c.field, Value: 1}})
+		err := r.mongoVideoCollection.FindOne(ctx, c.filter, opts).Decode(&video)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if at := c.at(video); at != nil && (next == nil || at.Before(*next)) {
+			next = at
+		}
+	}
+	return next, nil
+}
+
+func (r *MongoRepository) findVideos(ctx context.Context, filter bson.M) ([]domain.Video, error) {
+	// Cursor is a MongoDB stream that we can iterate over
+	cursor, err := r.mongoVideoCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	videos := make([]domain.Video, 0)
+
+	if err := cursor.All(ctx, &videos); err != nil {
+		return nil, err
+	}
+	for i := range videos {
+		applyVideoDefaults(&videos[i])
+	}
+
+	return videos, nil
+}
+
+// scheduleFilter leaves out published videos that are not yet due or have
+// expired at now. The scheduler moves them soon after, but reads must not
+// depend on it having run.
+func scheduleFilter(now time.Time) bson.M {
+	return bson.M{"$nor": bson.A{
+		bson.M{"status": publishedStatus, "publish_at": bson.M{"$gt": now}},
+		bson.M{"status": publishedStatus, "unpublish_at": bson.M{"$lte": now}},
+	}}
+}
+
+// applyVideoDefaults fills in fields missing from videos stored before they
+// existed. Those videos were live, so they count as published.
+func applyVideoDefaults(video *domain.Video) {
+	if video.Status == "" {
+		video.Status = domain.StatusPublished
+	}
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoRevisionRepository struct {
+	mongoRevisionCollection *mongo.Collection
+}
+
+// revisionDocument keys each revision by "<video id>:<revision>", so two
+// writers cannot both record the same revision of a video.
+type revisionDocument struct {
+	ID                   string `bson:"_id"`
+	domain.VideoRevision `bson:",inline"`
+}
+
+var _ ports.VideoRevisionRepository = (*MongoRevisionRepository)(nil)
+
+func NewMongoRevisionRepository(mongodb *mongo.Client) *MongoRevisionRepository {
+	return &MongoRevisionRepository{
+		mongoRevisionCollection: mongodb.Database("media").Collection("video_revisions"),
+	}
+}
+
+func (r *MongoRevisionRepository) AddRevision(ctx context.Context, revision domain.VideoRevision) error {
+	_, err := r.mongoRevisionCollection.InsertOne(ctx, revisionDocument{
+		ID:            revisionID(revision.VideoID, revision.Revision),
+		VideoRevision: revision,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.ErrVideoConflict
+	}
+	return err
+}
+
+func (r *MongoRevisionRepository) ListRevisions(ctx context.Context, videoID string) ([]domain.VideoRevision, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "revision", Value: -1}})
+	cursor, err := r.mongoRevisionCollection.Find(ctx, bson.M{"video_id": videoID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	revisions := make([]domain.VideoRevision, 0)
+	for cursor.Next(ctx) {
+		var doc revisionDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, doc.VideoRevision)
+	}
+
+	return revisions, cursor.Err()
+}
+
+func (r *MongoRevisionRepository) GetRevision(ctx context.Context, videoID string, revision int) (*domain.VideoRevision, error) {
+	var doc revisionDocument
+
+	err := r.mongoRevisionCollection.FindOne(ctx, bson.M{"_id": revisionID(videoID, revision)}).Decode(&doc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrRevisionNotFound
+		}
+		return nil, err
+	}
+
+	return &doc.VideoRevision, nil
+}
+
+func revisionID(videoID string, revision int) string {
+	return videoID + ":" + strconv.Itoa(revision)
+}
@@ -0,0 +1,126 @@
+// Package breaker implements a consecutive-failure circuit breaker.
+package breaker
+
+import (
+	"sync"
+	"time"
+)
+
+type State int
+
+const (
+	Closed State = iota
+	HalfOpen
+	Open
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "open"
+	}
+}
+
+// Breaker opens after threshold consecutive failures and rejects calls until
+// cooldown has passed. It then lets a single trial call through (half-open): a
+// success closes it again, a failure re-opens it for another cooldown.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	onChange  func(from, to State)
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	trialAt  time.Time
+}
+
+func New(threshold int, cooldown time.Duration) *Breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &Breaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// OnStateChange registers fn to be called (under the breaker lock, so it must not
+// call back into the breaker) on every state transition.
+func (b *Breaker) OnStateChange(fn func(from, to State)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onChange = fn
+}
+
+// Allow reports whether a call may proceed. Callers that get true must report the
+// outcome with Success or Failure.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	switch b.state {
+	case Closed:
+		return true
+	case Open:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(HalfOpen)
+		b.trialAt = now
+		return true
+	default:
+		// A trial whose outcome was never reported must not wedge the breaker
+		if now.Sub(b.trialAt) < b.cooldown {
+			return false
+		}
+		b.trialAt = now
+		return true
+	}
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	if b.state != Closed {
+		b.setState(Closed)
+	}
+}
+
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == HalfOpen || (b.state == Closed && b.failures >= b.threshold) {
+		b.openedAt = b.now()
+		b.setState(Open)
+	}
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// OpenedAt returns when the breaker last opened; it is zero if it never has.
+func (b *Breaker) OpenedAt() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.openedAt
+}
+
+func (b *Breaker) setState(to State) {
+	from := b.state
+	b.state = to
+	if b.onChange != nil && from != to {
+		b.onChange(from, to)
+	}
+}
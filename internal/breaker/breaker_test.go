@@ -0,0 +1,180 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+)
+
+const cooldown = 10 * time.Second
+
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) now() time.Time          { return f.t }
+func (f *fakeClock) advance(d time.Duration) { f.t = f.t.Add(d) }
+
+type transition struct{ from, to State }
+
+func newTestBreaker(threshold int) (*Breaker, *fakeClock, *[]transition) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	b := New(threshold, cooldown)
+	b.now = clock.now
+	var transitions []transition
+	b.OnStateChange(func(from, to State) {
+		transitions = append(transitions, transition{from, to})
+	})
+	return b, clock, &transitions
+}
+
+// open drives a closed breaker to open with threshold failures.
+func open(t *testing.T, b *Breaker, threshold int) {
+	t.Helper()
+	for range threshold {
+		if !b.Allow() {
+			t.Fatal("closed breaker rejected a call")
+		}
+		b.Failure()
+	}
+	if b.State() != Open {
+		t.Fatalf("state = %v after %d failures, want open", b.State(), threshold)
+	}
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	b, clock, transitions := newTestBreaker(3)
+
+	b.Failure()
+	b.Failure()
+	if b.State() != Closed {
+		t.Fatalf("state = %v after 2 of 3 failures, want closed", b.State())
+	}
+
+	// A success resets the consecutive count
+	b.Success()
+	b.Failure()
+	b.Failure()
+	if b.State() != Closed {
+		t.Fatalf("state = %v after a success and 2 failures, want closed", b.State())
+	}
+
+	b.Failure()
+	if b.State() != Open {
+		t.Fatalf("state = %v after 3 consecutive failures, want open", b.State())
+	}
+	if !b.OpenedAt().Equal(clock.t) {
+		t.Errorf("OpenedAt() = %v, want %v", b.OpenedAt(), clock.t)
+	}
+	if b.Allow() {
+		t.Error("open breaker allowed a call")
+	}
+	if want := []transition{{Closed, Open}}; !equal(*transitions, want) {
+		t.Errorf("transitions = %v, want %v", *transitions, want)
+	}
+}
+
+func TestBreakerHalfOpenAfterCooldown(t *testing.T) {
+	b, clock, _ := newTestBreaker(1)
+	open(t, b, 1)
+
+	clock.advance(cooldown - time.Millisecond)
+	if b.Allow() {
+		t.Fatal("breaker allowed a call before the cooldown passed")
+	}
+
+	clock.advance(time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("breaker rejected the trial call after the cooldown")
+	}
+	if b.State() != HalfOpen {
+		t.Fatalf("state = %v, want half-open", b.State())
+	}
+	// Only one trial at a time
+	if b.Allow() {
+		t.Error("half-open breaker allowed a second call")
+	}
+}
+
+func TestBreakerHalfOpenOutcome(t *testing.T) {
+	tests := []struct {
+		name        string
+		trial       func(*Breaker)
+		want        State
+		transitions []transition
+	}{
+		{
+			name:        "success closes",
+			trial:       (*Breaker).Success,
+			want:        Closed,
+			transitions: []transition{{Closed, Open}, {Open, HalfOpen}, {HalfOpen, Closed}},
+		},
+		{
+			name:        "failure re-opens",
+			trial:       (*Breaker).Failure,
+			want:        Open,
+			transitions: []transition{{Closed, Open}, {Open, HalfOpen}, {HalfOpen, Open}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, clock, transitions := newTestBreaker(2)
+			open(t, b, 2)
+			clock.advance(cooldown)
+			if !b.Allow() {
+				t.Fatal("trial call rejected")
+			}
+
+			tt.trial(b)
+			if b.State() != tt.want {
+				t.Fatalf("state = %v, want %v", b.State(), tt.want)
+			}
+			if !equal(*transitions, tt.transitions) {
+				t.Errorf("transitions = %v, want %v", *transitions, tt.transitions)
+			}
+
+			switch tt.want {
+			case Closed:
+				if !b.Allow() {
+					t.Error("closed breaker rejected a call")
+				}
+				// The failure count starts over
+				b.Failure()
+				if b.State() != Closed {
+					t.Error("one failure after closing re-opened a threshold-2 breaker")
+				}
+			case Open:
+				if !b.OpenedAt().Equal(clock.t) {
+					t.Errorf("OpenedAt() = %v, want the trial failure time %v", b.OpenedAt(), clock.t)
+				}
+				if b.Allow() {
+					t.Error("re-opened breaker allowed a call before another cooldown")
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerUnreportedTrial(t *testing.T) {
+	b, clock, _ := newTestBreaker(1)
+	open(t, b, 1)
+	clock.advance(cooldown)
+	if !b.Allow() {
+		t.Fatal("trial call rejected")
+	}
+
+	// The trial's outcome is never reported; another trial is let through after a cooldown
+	clock.advance(cooldown)
+	if !b.Allow() {
+		t.Error("breaker stayed wedged in half-open")
+	}
+}
+
+func equal(a, b []transition) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,302 @@
+// Package cache provides a bounded, sharded LRU cache with per-entry expiry.
+package cache
+
+import (
+	"container/list"
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type EvictReason string
+
+const (
+	// EvictExpired: the entry's TTL passed (found on access or by Purge).
+	EvictExpired EvictReason = "expired"
+	// EvictCapacity: the least recently used entry made room for a new one.
+	EvictCapacity EvictReason = "capacity"
+	// EvictRemoved: the entry was explicitly deleted.
+	EvictRemoved EvictReason = "removed"
+)
+
+// Stats is a point-in-time snapshot of cache counters.
+type Stats struct {
+	Hits     uint64
+	Misses   uint64
+	Expired  uint64
+	Evicted  uint64
+	Removed  uint64
+	Entries  int
+	Capacity int
+}
+
+// HitRatio returns hits / (hits + misses), or 0 before the first lookup.
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// LRU is safe for concurrent use. Keys are spread over independently locked
+// shards so parallel requests for different tokens rarely contend.
+type LRU[V any] struct {
+	shards   []*shard[V]
+	seed     maphash.Seed
+	capacity int
+	onEvict  func(key string, reason EvictReason)
+	now      func() time.Time
+
+	hits, misses, expired, evicted, removed atomic.Uint64
+}
+
+type shard[V any] struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List // front = most recently used
+}
+
+type entry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+// New creates a cache holding at most capacity entries split across shards.
+// onEvict, if non-nil, is called (outside shard locks) for every entry that leaves the cache.
+func New[V any](capacity, shards int, onEvict func(key string, reason EvictReason)) *LRU[V] {
+	if shards < 1 {
+		shards = 1
+	}
+	if capacity < shards {
+		capacity = shards
+	}
+
+	c := &LRU[V]{
+		shards:   make([]*shard[V], shards),
+		seed:     maphash.MakeSeed(),
+		capacity: capacity,
+		onEvict:  onEvict,
+		now:      time.Now,
+	}
+	for i := range c.shards {
+		// Spread the remainder so the shard capacities add up to exactly capacity
+		perShard := capacity / shards
+		if i < capacity%shards {
+			perShard++
+		}
+		c.shards[i] = &shard[V]{
+			capacity: perShard,
+			items:    make(map[string]*list.Element),
+			order:    list.New(),
+		}
+	}
+	return c
+}
+
+func (c *LRU[V]) shardFor(key string) *shard[V] {
+	return c.shards[maphash.String(c.seed, key)%uint64(len(c.shards))]
+}
+
+// Get returns the value for key if present and not expired. Expired entries are
+// removed on access.
+func (c *LRU[V]) Get(key string) (V, bool) {
+	s := c.shardFor(key)
+	now := c.now()
+
+	s.mu.Lock()
+	el, ok := s.items[key]
+	if !ok {
+		s.mu.Unlock()
+		c.misses.Add(1)
+		var zero V
+		return zero, false
+	}
+	e := el.Value.(*entry[V])
+	if !now.Before(e.expiresAt) {
+		s.order.Remove(el)
+		delete(s.items, key)
+		s.mu.Unlock()
+		c.misses.Add(1)
+		c.evict(key, EvictExpired)
+		var zero V
+		return zero, false
+	}
+	s.order.MoveToFront(el)
+	value := e.value
+	s.mu.Unlock()
+
+	c.hits.Add(1)
+	return value, true
+}
+
+// Peek returns the value and expiry for key without touching recency or the
+// hit/miss counters.
+func (c *LRU[V]) Peek(key string) (V, time.Time, bool) {
+	s := c.shardFor(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[key]; ok {
+		if e := el.Value.(*entry[V]); c.now().Before(e.expiresAt) {
+			return e.value, e.expiresAt, true
+		}
+	}
+	var zero V
+	return zero, time.Time{}, false
+}
+
+// Set stores value until expiresAt, evicting the least recently used entry of the
+// shard if it is full.
+func (c *LRU[V]) Set(key string, value V, expiresAt time.Time) {
+	s := c.shardFor(key)
+
+	s.mu.Lock()
+	if el, ok := s.items[key]; ok {
+		e := el.Value.(*entry[V])
+		e.value = value
+		e.expiresAt = expiresAt
+		s.order.MoveToFront(el)
+		s.mu.Unlock()
+		return
+	}
+
+	s.items[key] = s.order.PushFront(&entry[V]{key: key, value: value, expiresAt: expiresAt})
+
+	var victim string
+	if s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		victim = oldest.Value.(*entry[V]).key
+		s.order.Remove(oldest)
+		delete(s.items, victim)
+	}
+	s.mu.Unlock()
+
+	if victim != "" {
+		c.evict(victim, EvictCapacity)
+	}
+}
+
+// Delete removes key and reports whether it was present.
+func (c *LRU[V]) Delete(key string) bool {
+	s := c.shardFor(key)
+
+	s.mu.Lock()
+	el, ok := s.items[key]
+	if ok {
+		s.order.Remove(el)
+		delete(s.items, key)
+	}
+	s.mu.Unlock()
+
+	if ok {
+		c.evict(key, EvictRemoved)
+	}
+	return ok
+}
+
+// PurgeExpired drops every expired entry and returns how many were removed.
+func (c *LRU[V]) PurgeExpired() int {
+	now := c.now()
+	purged := 0
+	for _, s := range c.shards {
+		var keys []string
+		s.mu.Lock()
+		for key, el := range s.items {
+			if !now.Before(el.Value.(*entry[V]).expiresAt) {
+				s.order.Remove(el)
+				delete(s.items, key)
+				keys = append(keys, key)
+			}
+		}
+		s.mu.Unlock()
+
+		for _, key := range keys {
+			c.evict(key, EvictExpired)
+		}
+		purged += len(keys)
+	}
+	return purged
+}
+
+// Clear removes every entry and returns how many were removed.
+func (c *LRU[V]) Clear() int {
+	cleared := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		keys := make([]string, 0, len(s.items))
+		for key := range s.items {
+			keys = append(keys, key)
+		}
+		s.items = make(map[string]*list.Element)
+		s.order.Init()
+		s.mu.Unlock()
+
+		for _, key := range keys {
+			c.evict(key, EvictRemoved)
+		}
+		cleared += len(keys)
+	}
+	return cleared
+}
+
+// Range calls fn for every live entry until fn returns false. Entries are
+// snapshotted per shard, so fn may safely call back into the cache.
+func (c *LRU[V]) Range(fn func(key string, value V, expiresAt time.Time) bool) {
+	now := c.now()
+	for _, s := range c.shards {
+		s.mu.Lock()
+		snapshot := make([]entry[V], 0, len(s.items))
+		for _, el := range s.items {
+			if e := el.Value.(*entry[V]); now.Before(e.expiresAt) {
+				snapshot = append(snapshot, *e)
+			}
+		}
+		s.mu.Unlock()
+
+		for _, e := range snapshot {
+			if !fn(e.key, e.value, e.expiresAt) {
+				return
+			}
+		}
+	}
+}
+
+func (c *LRU[V]) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		n += len(s.items)
+		s.mu.Unlock()
+	}
+	return n
+}
+
+func (c *LRU[V]) Stats() Stats {
+	return Stats{
+		Hits:     c.hits.Load(),
+		Misses:   c.misses.Load(),
+		Expired:  c.expired.Load(),
+		Evicted:  c.evicted.Load(),
+		Removed:  c.removed.Load(),
+		Entries:  c.Len(),
+		Capacity: c.capacity,
+	}
+}
+
+func (c *LRU[V]) evict(key string, reason EvictReason) {
+	switch reason {
+	case EvictExpired:
+		c.expired.Add(1)
+	case EvictCapacity:
+		c.evicted.Add(1)
+	case EvictRemoved:
+		c.removed.Add(1)
+	}
+	if c.onEvict != nil {
+		c.onEvict(key, reason)
+	}
+}
@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock replaces LRU.now so expiry can be tested without sleeping.
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) now() time.Time          { return f.t }
+func (f *fakeClock) advance(d time.Duration) { f.t = f.t.Add(d) }
+
+func newTestCache(capacity, shards int) (*LRU[string], *fakeClock, map[EvictReason][]string) {
+	evictions := make(map[EvictReason][]string)
+	c := New[string](capacity, shards, func(key string, reason EvictReason) {
+		evictions[reason] = append(evictions[reason], key)
+	})
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c.now = clock.now
+	return c, clock, evictions
+}
+
+func TestLRUCapacityEviction(t *testing.T) {
+	c, clock, evictions := newTestCache(3, 1)
+	expiresAt := clock.t.Add(time.Hour)
+
+	c.Set("a", "1", expiresAt)
+	c.Set("b", "2", expiresAt)
+	c.Set("c", "3", expiresAt)
+	// Touch a so b becomes the least recently used
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("Get(a) missed")
+	}
+	c.Set("d", "4", expiresAt)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("least recently used entry b was not evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("Get(%s) missed", key)
+		}
+	}
+	if got := evictions[EvictCapacity]; len(got) != 1 || got[0] != "b" {
+		t.Errorf("capacity evictions = %v, want [b]", got)
+	}
+	if c.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", c.Len())
+	}
+
+	// Overwriting an existing key does not evict
+	c.Set("a", "updated", expiresAt)
+	if v, _ := c.Get("a"); v != "updated" {
+		t.Errorf("Get(a) = %q, want updated", v)
+	}
+	if got := len(evictions[EvictCapacity]); got != 1 {
+		t.Errorf("capacity evictions = %d after overwrite, want 1", got)
+	}
+}
+
+func TestLRUShardCapacitiesAddUp(t *testing.T) {
+	c := New[int](10, 4, nil)
+	total := 0
+	for _, s := range c.shards {
+		total += s.capacity
+	}
+	if total != 10 {
+		t.Errorf("shard capacities add up to %d, want 10", total)
+	}
+}
+
+func TestLRUExpiryOnAccess(t *testing.T) {
+	c, clock, evictions := newTestCache(10, 2)
+
+	c.Set("short", "v", clock.t.Add(time.Minute))
+	c.Set("long", "v", clock.t.Add(time.Hour))
+
+	if _, _, ok := c.Peek("short"); !ok {
+		t.Fatal("Peek(short) missed before expiry")
+	}
+
+	clock.advance(time.Minute)
+	if _, _, ok := c.Peek("short"); ok {
+		t.Error("Peek returned an expired entry")
+	}
+	// Peek does not remove; Get does
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d before Get, want 2", c.Len())
+	}
+	if _, ok := c.Get("short"); ok {
+		t.Error("Get returned an expired entry")
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d after Get, want 1", c.Len())
+	}
+	if got := evictions[EvictExpired]; len(got) != 1 || got[0] != "short" {
+		t.Errorf("expired evictions = %v, want [short]", got)
+	}
+	if _, ok := c.Get("long"); !ok {
+		t.Error("Get(long) missed")
+	}
+
+	clock.advance(time.Hour)
+	if purged := c.PurgeExpired(); purged != 1 {
+		t.Errorf("PurgeExpired() = %d, want 1", purged)
+	}
+	if c.Len() != 0 {
+		t.Errorf("Len() = %d after purge, want 0", c.Len())
+	}
+}
+
+func TestLRUStats(t *testing.T) {
+	c, clock, _ := newTestCache(2, 1)
+	expiresAt := clock.t.Add(time.Minute)
+
+	c.Get("missing") // miss
+	c.Set("a", "1", expiresAt)
+	c.Get("a") // hit
+	c.Get("a") // hit
+	c.Set("b", "2", expiresAt)
+	c.Set("c", "3", expiresAt) // evicts a
+	c.Delete("b")              // removed
+	c.Delete("b")              // not present, not counted
+	clock.advance(time.Minute)
+	c.Get("c") // expired, also a miss
+	c.Set("d", "4", clock.t.Add(time.Minute))
+	c.Clear() // removed
+
+	want := Stats{Hits: 2, Misses: 2, Expired: 1, Evicted: 1, Removed: 2, Entries: 0, Capacity: 2}
+	got := c.Stats()
+	if got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+	if ratio := got.HitRatio(); ratio != 0.5 {
+		t.Errorf("HitRatio() = %v, want 0.5", ratio)
+	}
+	if ratio := (Stats{}).HitRatio(); ratio != 0 {
+		t.Errorf("HitRatio() before any lookup = %v, want 0", ratio)
+	}
+}
+
+// benchmarkShards compares a single lock with sharded locks under parallel load;
+// run with -cpu to see contention grow with the number of goroutines.
+var benchmarkShards = []int{1, 4, 16, 64}
+
+const benchmarkKeys = 1 << 14
+
+func benchmarkKeyNames() []string {
+	keys := make([]string, benchmarkKeys)
+	for i := range keys {
+		keys[i] = "jti-" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+func BenchmarkGet(b *testing.B) {
+	keys := benchmarkKeyNames()
+	for _, shards := range benchmarkShards {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			c := New[int](benchmarkKeys, shards, nil)
+			expiresAt := time.Now().Add(time.Hour)
+			for i, key := range keys {
+				c.Set(key, i, expiresAt)
+			}
+
+			var next atomic.Uint64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				// Each goroutine walks the keys from its own offset
+				i := next.Add(7919)
+				for pb.Next() {
+					c.Get(keys[i%benchmarkKeys])
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkSet(b *testing.B) {
+	keys := benchmarkKeyNames()
+	for _, shards := range benchmarkShards {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			// Half the key space fits, so Sets also exercise capacity eviction
+			c := New[int](benchmarkKeys/2, shards, nil)
+			expiresAt := time.Now().Add(time.Hour)
+
+			var next atomic.Uint64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := next.Add(7919)
+				for pb.Next() {
+					c.Set(keys[i%benchmarkKeys], int(i), expiresAt)
+					i++
+				}
+			})
+		})
+	}
+}
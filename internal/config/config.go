@@ -1,30 +1,103 @@
 package config
 
 import (
-	"crypto/rsa"
 	"os"
-
-	"github.com/golang-jwt/jwt/v4"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	JWTPublicKey  *rsa.PublicKey
+	AppEnv        string
+	AuthMode      string
+	LogLevel      string
+	PublicKeyPath string
+	JWKSURL       string
 	MongoURI      string
 	Port          string
 	RedisAddress  string
 	RedisPassword string
+
+	// Local development auth: AUTH_MODE=dev writes its ephemeral private key here
+	DevSigningKeyPath string
+
+	// HTTP server lifecycle
+	ReadHeaderTimeout   time.Duration
+	ReadTimeout         time.Duration
+	WriteTimeout        time.Duration
+	IdleTimeout         time.Duration
+	ShutdownGracePeriod time.Duration
+	ShutdownDrainDelay  time.Duration
+	MaxRequestBodyBytes int64
+
+	// TLS serving and client certificates
+	TLSCertFile           string
+	TLSKeyFile            string
+	TLSClientCAFile       string
+	ServiceIdentitiesFile string
+
+	// Token claims validation
+	JWTIssuers           []string
+	JWTAudience          string
+	JWTLeeway            time.Duration
+	JWTMaxAge            time.Duration
+	JWTAllowedAlgorithms []string
+
+	// Attribute-based access policy; the built-in role policy is used when empty
+	PolicyFile string
+
+	// X-Act-As support-staff impersonation
+	ImpersonationEnabled bool
+
+	// Redis stream the notification service delivers from
+	NotificationStream string
+
+	// Scheduled publishing and the Redis stream video events are published to
+	SchedulerEnabled  bool
+	SchedulerInterval time.Duration
+	EventStream       string
+
+	// L1 claims cache
+	AuthCacheCapacity int
+	AuthCacheShards   int
+
+	// Redis blacklist resilience
+	RevocationFailurePolicy    string
+	RevocationBreakerThreshold int
+	RevocationBreakerCooldown  time.Duration
+	RevocationNegativeCacheTTL time.Duration
+	RevocationChannel          string
+	RevocationWatermarkTTL     time.Duration
+	RevocationSkipCachedLookup bool
+
+	// PEM key hot reload
+	KeyReloadInterval  time.Duration
+	KeyRotationOverlap time.Duration
+
+	// JWKS key discovery
+	JWKSRefreshInterval time.Duration
+	JWKSKeyGracePeriod  time.Duration
+
+	// Health checks
+	HealthCheckInterval  time.Duration
+	HealthCheckTimeout   time.Duration
+	BlobStorageHealthURL string
+
+	// Tracing
+	ServiceName   string
+	TraceExporter string
+	OTLPEndpoint  string
 }
 
 func Load() *Config {
 
+	jwksURL := os.Getenv("JWKS_URL")
+
 	publicKeyPath := os.Getenv("PUBLIC_KEY_PATH")
 	if publicKeyPath == "" {
 		publicKeyPath = "/etc/certs/public.pem"
 	}
-	publicKey, err := loadPublicKey(publicKeyPath)
-	if err != nil {
-		panic("Failed to load public key: " + err.Error())
-	}
 
 	mongoURI := os.Getenv("MONGO_URI")
 	if mongoURI == "" {
@@ -41,29 +114,188 @@ func Load() *Config {
 		redisPassword = ""
 	}
 
+	authMode := os.Getenv("AUTH_MODE")
+	if authMode == "" {
+		authMode = "standard"
+	}
+
+	devSigningKeyPath := os.Getenv("DEV_SIGNING_KEY_PATH")
+	if devSigningKeyPath == "" {
+		devSigningKeyPath = DefaultDevSigningKeyPath()
+	}
+
+	appEnv := os.Getenv("APP_ENV")
+	if appEnv == "" {
+		appEnv = "production"
+	}
+
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = "media-service"
+	}
+
+	traceExporter := os.Getenv("TRACE_EXPORTER")
+	if traceExporter == "" {
+		traceExporter = "log"
+	}
+
+	otlpEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if otlpEndpoint == "" {
+		otlpEndpoint = "http://localhost:4318"
+	}
+
+	logLevel := os.Getenv("LOG_LEVEL")
+	if logLevel == "" {
+		logLevel = "info"
+	}
+
+	revocationFailurePolicy := os.Getenv("AUTH_REVOCATION_FAILURE_POLICY")
+	if revocationFailurePolicy == "" {
+		revocationFailurePolicy = "fail-open-reads"
+	}
+
+	revocationChannel := os.Getenv("AUTH_REVOCATION_CHANNEL")
+	if revocationChannel == "" {
+		revocationChannel = "auth:revocations"
+	}
+
+	notificationStream := os.Getenv("NOTIFICATION_STREAM")
+	if notificationStream == "" {
+		notificationStream = "media:notifications"
+	}
+
+	eventStream := os.Getenv("EVENT_STREAM")
+	if eventStream == "" {
+		eventStream = "media:events"
+	}
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8081"
 	}
 
 	return &Config{
-		JWTPublicKey:  publicKey,
+		AppEnv:        appEnv,
+		AuthMode:      authMode,
+		LogLevel:      logLevel,
+		PublicKeyPath: publicKeyPath,
+		JWKSURL:       jwksURL,
 		MongoURI:      mongoURI,
 		Port:          port,
 		RedisAddress:  redisAddress,
 		RedisPassword: redisPassword,
+
+		DevSigningKeyPath: devSigningKeyPath,
+
+		ReadHeaderTimeout:   getEnvDuration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		ReadTimeout:         getEnvDuration("HTTP_READ_TIMEOUT", 15*time.Second),
+		WriteTimeout:        getEnvDuration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:         getEnvDuration("HTTP_IDLE_TIMEOUT", 120*time.Second),
+		ShutdownGracePeriod: getEnvDuration("SHUTDOWN_GRACE_PERIOD", 20*time.Second),
+		ShutdownDrainDelay:  getEnvDuration("SHUTDOWN_DRAIN_DELAY", 5*time.Second),
+		MaxRequestBodyBytes: int64(getEnvInt("HTTP_MAX_BODY_BYTES", 1<<20)),
+
+		TLSCertFile:           os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:            os.Getenv("TLS_KEY_FILE"),
+		TLSClientCAFile:       os.Getenv("TLS_CLIENT_CA_FILE"),
+		ServiceIdentitiesFile: os.Getenv("SERVICE_IDENTITIES_FILE"),
+
+		JWTIssuers:           getEnvList("JWT_ISSUERS", nil),
+		JWTAudience:          os.Getenv("JWT_AUDIENCE"),
+		JWTLeeway:            getEnvDuration("JWT_LEEWAY", 30*time.Second),
+		JWTMaxAge:            getEnvDuration("JWT_MAX_AGE", 0),
+		JWTAllowedAlgorithms: getEnvList("JWT_ALLOWED_ALGORITHMS", []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}),
+
+		PolicyFile: os.Getenv("POLICY_FILE"),
+
+		ImpersonationEnabled: getEnvBool("AUTH_IMPERSONATION_ENABLED", false),
+
+		NotificationStream: notificationStream,
+
+		SchedulerEnabled:  getEnvBool("SCHEDULER_ENABLED", true),
+		SchedulerInterval: getEnvDuration("SCHEDULER_INTERVAL", time.Minute),
+		EventStream:       eventStream,
+
+		AuthCacheCapacity: getEnvInt("AUTH_CACHE_CAPACITY", 10000),
+		AuthCacheShards:   getEnvInt("AUTH_CACHE_SHARDS", 16),
+
+		RevocationFailurePolicy:    revocationFailurePolicy,
+		RevocationBreakerThreshold: getEnvInt("AUTH_REVOCATION_BREAKER_THRESHOLD", 5),
+		RevocationBreakerCooldown:  getEnvDuration("AUTH_REVOCATION_BREAKER_COOLDOWN", 30*time.Second),
+		RevocationNegativeCacheTTL: getEnvDuration("AUTH_REVOCATION_NEGATIVE_CACHE_TTL", 5*time.Second),
+		RevocationChannel:          revocationChannel,
+		RevocationWatermarkTTL:     getEnvDuration("AUTH_REVOCATION_WATERMARK_TTL", 24*time.Hour),
+		RevocationSkipCachedLookup: getEnvBool("AUTH_REVOCATION_SKIP_CACHED_LOOKUPS", false),
+
+		KeyReloadInterval:  getEnvDuration("PUBLIC_KEY_RELOAD_INTERVAL", 30*time.Second),
+		KeyRotationOverlap: getEnvDuration("PUBLIC_KEY_ROTATION_OVERLAP", 10*time.Minute),
+
+		JWKSRefreshInterval: getEnvDuration("JWKS_REFRESH_INTERVAL", 15*time.Minute),
+		JWKSKeyGracePeriod:  getEnvDuration("JWKS_KEY_GRACE_PERIOD", time.Hour),
+
+		HealthCheckInterval:  getEnvDuration("HEALTH_CHECK_INTERVAL", 10*time.Second),
+		HealthCheckTimeout:   getEnvDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+		BlobStorageHealthURL: os.Getenv("BLOB_STORAGE_HEALTH_URL"),
+
+		ServiceName:   serviceName,
+		TraceExporter: traceExporter,
+		OTLPEndpoint:  otlpEndpoint,
 	}
 }
 
-func loadPublicKey(path string) (*rsa.PublicKey, error) {
-	keyData, err := os.ReadFile(path)
+// DefaultDevSigningKeyPath is shared with cmd/mintjwt so both find the key
+// without any configuration.
+func DefaultDevSigningKeyPath() string {
+	return filepath.Join(os.TempDir(), "media-service", "dev-signing-key.pem")
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
 	if err != nil {
-		return nil, err
+		panic("Invalid duration for " + key + ": " + err.Error())
+	}
+	return d
+}
+
+func getEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		panic("Invalid positive integer for " + key + ": " + value)
 	}
+	return n
+}
 
-	publicKey, err := jwt.ParseRSAPublicKeyFromPEM(keyData)
+func getEnvBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
 	if err != nil {
-		return nil, err
+		panic("Invalid boolean for " + key + ": " + value)
+	}
+	return b
+}
+
+// getEnvList splits a comma-separated variable, dropping empty entries.
+func getEnvList(key string, fallback []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
 	}
-	return publicKey, nil
+	return list
 }
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// APIKeyScope limits what a machine client holding an API key may do.
+type APIKeyScope string
+
+const (
+	ScopeVideosRead APIKeyScope = "videos:read"
+)
+
+// APIKeyScopes lists every scope a key can be granted.
+var APIKeyScopes = []APIKeyScope{ScopeVideosRead}
+
+var (
+	ErrAPIKeyNotFound       = errors.New("api key not found")
+	ErrInvalidAPIKey        = errors.New("invalid api key")
+	ErrAPIKeyExpired        = errors.New("api key expired")
+	ErrInvalidAPIKeyRequest = errors.New("invalid api key request")
+)
+
+// APIKey is a machine credential. Only a salted hash of the secret is stored;
+// the secret itself is shown once when the key is created.
+type APIKey struct {
+	ID         string        `json:"id" bson:"_id"`
+	Name       string        `json:"name" bson:"name"`
+	Salt       []byte        `json:"-" bson:"salt"`
+	Hash       []byte        `json:"-" bson:"hash"`
+	Scopes     []APIKeyScope `json:"scopes" bson:"scopes"`
+	ExpiresAt  *time.Time    `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	CreatedAt  time.Time     `json:"created_at" bson:"created_at"`
+	CreatedBy  string        `json:"created_by" bson:"created_by"`
+	LastUsedAt *time.Time    `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+}
+
+func (k APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
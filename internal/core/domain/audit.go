@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "SUCCESS"
+	AuditFailure AuditOutcome = "FAILURE"
+)
+
+// AuditEvent records a security-relevant action taken by an authenticated actor.
+// Before and After are JSON snapshots of the resource around a change.
+type AuditEvent struct {
+	Time      time.Time         `json:"time" bson:"time"`
+	Actor     string            `json:"actor" bson:"actor"`
+	ActorRole string            `json:"actor_role" bson:"actor_role"`
+	ActingAs  string            `json:"acting_as,omitempty" bson:"acting_as,omitempty"`
+	Action    string            `json:"action" bson:"action"`
+	Target    string            `json:"target" bson:"target"`
+	Outcome   AuditOutcome      `json:"outcome" bson:"outcome"`
+	RequestID string            `json:"request_id,omitempty" bson:"request_id,omitempty"`
+	Details   map[string]string `json:"details,omitempty" bson:"details,omitempty"`
+	Before    json.RawMessage   `json:"before,omitempty" bson:"before,omitempty"`
+	After     json.RawMessage   `json:"after,omitempty" bson:"after,omitempty"`
+}
+
+// NewAuditEvent returns an event for an action taken by principal now. An
+// impersonated principal is recorded under ActingAs, with the real caller as actor.
+func NewAuditEvent(principal Principal, action, target string, outcome AuditOutcome, details map[string]string) AuditEvent {
+	actor := principal.Actor()
+	event := AuditEvent{
+		Time:      time.Now().UTC(),
+		Actor:     actor.ID,
+		ActorRole: actor.Role,
+		Action:    action,
+		Target:    target,
+		Outcome:   outcome,
+		Details:   details,
+	}
+	if principal.Impersonator != nil {
+		event.ActingAs = principal.ID
+	}
+	return event
+}
+
+// AuditSnapshot encodes resource for AuditEvent.Before or After; nil stays nil.
+func AuditSnapshot(resource any) json.RawMessage {
+	if resource == nil {
+		return nil
+	}
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// ErrAuditChainBroken is matched by every problem found when verifying the audit trail.
+var ErrAuditChainBroken = errors.New("audit chain broken")
+
+// AuditEntry is an AuditEvent in the tamper-evident audit trail. Entries are
+// numbered from 1 without gaps, and each one's Hash covers its event, its Seq
+// and the Hash of the entry before it, so changing, removing or reordering an
+// entry breaks the chain from that point on.
+type AuditEntry struct {
+	Seq        int64 `json:"seq" bson:"_id"`
+	AuditEvent `bson:",inline"`
+	PrevHash   string `json:"prev_hash" bson:"prev_hash"`
+	Hash       string `json:"hash" bson:"hash"`
+}
+
+// NewAuditEntry chains event onto prev, which is nil for the first entry.
+func NewAuditEntry(prev *AuditEntry, event AuditEvent) AuditEntry {
+	entry := AuditEntry{Seq: 1, AuditEvent: event}
+	if prev != nil {
+		entry.Seq = prev.Seq + 1
+		entry.PrevHash = prev.Hash
+	}
+	entry.Hash = entry.ComputeHash()
+	return entry
+}
+
+// ComputeHash returns the hex SHA-256 of the entry's canonical JSON encoding
+// without Hash. Event times must already be truncated to what the store keeps.
+func (e AuditEntry) ComputeHash() string {
+	data, _ := json.Marshal(struct {
+		Seq      int64      `json:"seq"`
+		PrevHash string     `json:"prev_hash"`
+		Event    AuditEvent `json:"event"`
+	}{e.Seq, e.PrevHash, e.AuditEvent})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks that e follows prev, which is nil for the first entry, and that
+// its content still matches its hash.
+func (e AuditEntry) Verify(prev *AuditEntry) error {
+	var wantSeq int64 = 1
+	var wantPrev string
+	if prev != nil {
+		wantSeq, wantPrev = prev.Seq+1, prev.Hash
+	}
+	if e.Seq != wantSeq {
+		return fmt.Errorf("%w: expected entry %d, found %d", ErrAuditChainBroken, wantSeq, e.Seq)
+	}
+	if e.PrevHash != wantPrev {
+		return fmt.Errorf("%w: entry %d does not link to entry %d", ErrAuditChainBroken, e.Seq, wantSeq-1)
+	}
+	if e.ComputeHash() != e.Hash {
+		return fmt.Errorf("%w: entry %d was modified", ErrAuditChainBroken, e.Seq)
+	}
+	return nil
+}
+
+// AuditFilter selects audit entries, newest first. Zero fields match anything;
+// BeforeSeq pages backwards from a previous result.
+type AuditFilter struct {
+	Actor     string
+	Action    string
+	Target    string
+	Outcome   AuditOutcome
+	RequestID string
+	From      time.Time
+	To        time.Time
+	BeforeSeq int64
+	Limit     int
+}
@@ -0,0 +1,195 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func testChain(n int) []AuditEntry {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	entries := make([]AuditEntry, 0, n)
+	var prev *AuditEntry
+	for i := range n {
+		entry := NewAuditEntry(prev, AuditEvent{
+			Time:    start.Add(time.Duration(i) * time.Minute),
+			Actor:   "admin-1",
+			Action:  "video.update",
+			Target:  "video-1",
+			Outcome: AuditSuccess,
+			Details: map[string]string{"revision": strconv.Itoa(i + 1)},
+		})
+		entries = append(entries, entry)
+		prev = &entries[len(entries)-1]
+	}
+	return entries
+}
+
+// verifyChain verifies entries in order like MongoAuditLog.Verify, returning the
+// first problem.
+func verifyChain(entries []AuditEntry) error {
+	var prev *AuditEntry
+	for i := range entries {
+		if err := entries[i].Verify(prev); err != nil {
+			return err
+		}
+		prev = &entries[i]
+	}
+	return nil
+}
+
+func TestAuditEntryChain(t *testing.T) {
+	entries := testChain(3)
+	for i, e := range entries {
+		if e.Seq != int64(i+1) {
+			t.Errorf("entry %d has Seq %d", i, e.Seq)
+		}
+	}
+	if entries[0].PrevHash != "" || entries[1].PrevHash != entries[0].Hash {
+		t.Error("entries are not linked by PrevHash")
+	}
+	if err := verifyChain(entries); err != nil {
+		t.Errorf("valid chain: %v", err)
+	}
+}
+
+func TestAuditEntryVerifyDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func([]AuditEntry) []AuditEntry
+		wantErr string
+	}{
+		{
+			name: "modified event",
+			tamper: func(e []AuditEntry) []AuditEntry {
+				e[1].Outcome = AuditFailure
+				return e
+			},
+			wantErr: "entry 2 was modified",
+		},
+		{
+			name: "modified details",
+			tamper: func(e []AuditEntry) []AuditEntry {
+				e[1].Details = map[string]string{"revision": "9"}
+				return e
+			},
+			wantErr: "entry 2 was modified",
+		},
+		{
+			name: "modified and rehashed",
+			tamper: func(e []AuditEntry) []AuditEntry {
+				e[1].Actor = "someone-else"
+				e[1].Hash = e[1].ComputeHash()
+				return e
+			},
+			wantErr: "entry 3 does not link to entry 2",
+		},
+		{
+			name: "gap",
+			tamper: func(e []AuditEntry) []AuditEntry {
+				return append(e[:1], e[2:]...)
+			},
+			wantErr: "expected entry 2, found 3",
+		},
+		{
+			name: "first entry removed",
+			tamper: func(e []AuditEntry) []AuditEntry {
+				return e[1:]
+			},
+			wantErr: "expected entry 1, found 2",
+		},
+		{
+			name: "reordered",
+			tamper: func(e []AuditEntry) []AuditEntry {
+				e[1], e[2] = e[2], e[1]
+				return e
+			},
+			wantErr: "expected entry 2, found 3",
+		},
+		{
+			name: "reordered and renumbered",
+			tamper: func(e []AuditEntry) []AuditEntry {
+				e[1], e[2] = e[2], e[1]
+				e[1].Seq, e[2].Seq = 2, 3
+				return e
+			},
+			wantErr: "entry 2 does not link to entry 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyChain(tt.tamper(testChain(3)))
+			if !errors.Is(err, ErrAuditChainBroken) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want ErrAuditChainBroken with %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuditEntryHashRoundTrip(t *testing.T) {
+	event := AuditEvent{
+		// MongoAuditLog.Record truncates to what MongoDB keeps before hashing
+		Time:    time.Date(2026, 3, 1, 9, 0, 0, 123456789, time.UTC).Truncate(time.Millisecond),
+		Actor:   "admin-1",
+		Action:  "video.update",
+		Target:  "video-1",
+		Outcome: AuditSuccess,
+		Before:  AuditSnapshot(map[string]string{"status": "DRAFT"}),
+		After:   AuditSnapshot(map[string]string{"status": "IN_REVIEW"}),
+	}
+	first := NewAuditEntry(nil, event)
+	second := NewAuditEntry(&first, event)
+
+	roundTrips := map[string]func(AuditEntry) (AuditEntry, error){
+		"bson": func(e AuditEntry) (AuditEntry, error) {
+			var decoded AuditEntry
+			data, err := bson.Marshal(e)
+			if err == nil {
+				err = bson.Unmarshal(data, &decoded)
+			}
+			return decoded, err
+		},
+		"json": func(e AuditEntry) (AuditEntry, error) {
+			var decoded AuditEntry
+			data, err := json.Marshal(e)
+			if err == nil {
+				err = json.Unmarshal(data, &decoded)
+			}
+			return decoded, err
+		},
+	}
+	for name, roundTrip := range roundTrips {
+		t.Run(name, func(t *testing.T) {
+			decodedFirst, err := roundTrip(first)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decodedSecond, err := roundTrip(second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := verifyChain([]AuditEntry{decodedFirst, decodedSecond}); err != nil {
+				t.Errorf("chain read back does not verify: %v", err)
+			}
+		})
+	}
+
+	t.Run("untruncated time", func(t *testing.T) {
+		untruncated := event
+		untruncated.Time = untruncated.Time.Add(456 * time.Microsecond)
+		entry := NewAuditEntry(nil, untruncated)
+
+		stored, err := roundTrips["bson"](entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := stored.Verify(nil); !errors.Is(err, ErrAuditChainBroken) {
+			t.Errorf("entry hashed before truncation verified after a BSON round trip (err = %v)", err)
+		}
+	})
+}
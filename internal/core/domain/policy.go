@@ -0,0 +1,111 @@
+package domain
+
+import "errors"
+
+// Action is an operation on a resource that the access policy decides on.
+type Action string
+
+const (
+	ActionVideoRead   Action = "videos:read"
+	ActionVideoCreate Action = "videos:create"
+	ActionVideoDelete Action = "videos:delete"
+	ActionVideoUpdate Action = "videos:update"
+	// ActionVideoPreview reads a video that is not published yet
+	ActionVideoPreview Action = "videos:preview"
+	ActionVideoSubmit  Action = "videos:submit"
+	ActionVideoReview  Action = "videos:review"
+	ActionVideoPublish Action = "videos:publish"
+)
+
+// Actions lists every action the service enforces.
+var Actions = []Action{
+	ActionVideoRead, ActionVideoCreate, ActionVideoUpdate, ActionVideoDelete,
+	ActionVideoPreview, ActionVideoSubmit, ActionVideoReview, ActionVideoPublish,
+}
+
+// AnyAction in a rule's actions matches every action.
+const AnyAction Action = "*"
+
+var ErrInvalidPolicy = errors.New("invalid policy")
+
+// Attributes are the named values a policy condition can refer to. Values are
+// strings, numbers, booleans or lists of those, as they appear in token claims.
+type Attributes map[string]any
+
+type PolicyEffect string
+
+const (
+	PolicyAllow PolicyEffect = "allow"
+	PolicyDeny  PolicyEffect = "deny"
+)
+
+type ConditionOperator string
+
+const (
+	// OperatorEquals holds when the attribute equals the value.
+	OperatorEquals ConditionOperator = "equals"
+	// OperatorNotEquals holds when the attribute is set and differs from the value.
+	OperatorNotEquals ConditionOperator = "not_equals"
+	// OperatorIn holds when every value of the attribute is one of the values.
+	OperatorIn ConditionOperator = "in"
+	// OperatorContains holds when the (list) attribute contains the value.
+	OperatorContains ConditionOperator = "contains"
+	// OperatorExists holds when the attribute is set and not empty.
+	OperatorExists ConditionOperator = "exists"
+)
+
+// PolicyCondition compares the attribute at Attribute ("subject.<name>" or
+// "resource.<name>") with a literal Value, a list of Values, or the attribute
+// at Ref. A condition on a missing attribute never holds.
+type PolicyCondition struct {
+	Attribute string            `json:"attribute"`
+	Operator  ConditionOperator `json:"operator"`
+	Value     string            `json:"value,omitempty"`
+	Values    []string          `json:"values,omitempty"`
+	Ref       string            `json:"ref,omitempty"`
+}
+
+// PolicyRule applies to a request when its action and the subject's role are
+// listed (an empty Roles list matches every role), and matches when all of its
+// conditions hold.
+type PolicyRule struct {
+	ID          string            `json:"id"`
+	Description string            `json:"description,omitempty"`
+	Effect      PolicyEffect      `json:"effect"`
+	Actions     []Action          `json:"actions"`
+	Roles       []string          `json:"roles,omitempty"`
+	Conditions  []PolicyCondition `json:"conditions,omitempty"`
+}
+
+// Policy is evaluated deny-overrides: a matching deny rule wins over any allow
+// rule, and a request no allow rule matches is denied.
+type Policy struct {
+	Rules []PolicyRule `json:"rules"`
+}
+
+// AccessRequest asks whether Subject may perform Action on Resource. The
+// subject's role is read from its "role" attribute.
+type AccessRequest struct {
+	Subject  Attributes
+	Action   Action
+	Resource Attributes
+}
+
+// RuleTrace explains how a single rule took part in a decision.
+type RuleTrace struct {
+	RuleID  string       `json:"rule_id"`
+	Effect  PolicyEffect `json:"effect"`
+	Applies bool         `json:"applies"`
+	Matched bool         `json:"matched"`
+	Detail  string       `json:"detail"`
+}
+
+// Decision is the outcome of an access request. Reason names the deciding rule,
+// or the condition that kept the closest allow rule from matching, without the
+// attribute values; Trace carries the values for every rule.
+type Decision struct {
+	Allowed bool        `json:"allowed"`
+	RuleID  string      `json:"rule_id,omitempty"`
+	Reason  string      `json:"reason"`
+	Trace   []RuleTrace `json:"trace"`
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+)
+
+// How a principal proved its identity.
+const (
+	AuthMethodToken       = "token"
+	AuthMethodCertificate = "certificate"
+	AuthMethodAPIKey      = "api_key"
+	// AuthMethodInternal is the service acting on its own, such as the scheduler
+	AuthMethodInternal = "internal"
+)
+
+var ErrUnauthenticated = errors.New("no authenticated principal")
+
+// ErrForbidden is matched by every AccessDeniedError.
+var ErrForbidden = errors.New("forbidden")
+
+// AccessDeniedError is returned by the core services when the access policy
+// denies an action; Decision explains why.
+type AccessDeniedError struct {
+	Action   Action
+	Decision Decision
+}
+
+func (e *AccessDeniedError) Error() string {
+	return "forbidden: " + e.Decision.Reason
+}
+
+func (e *AccessDeniedError) Is(target error) bool {
+	return target == ErrForbidden
+}
+
+// Principal is the authenticated caller. Inbound adapters authenticate the
+// request and store it in the context with ContextWithPrincipal; the core
+// services read it back to authorize the call and record who made it.
+type Principal struct {
+	ID         string
+	Role       string
+	AuthMethod string
+	// Scopes limit an API key principal to the matching actions; nil otherwise.
+	Scopes []APIKeyScope
+	// Attributes are extra facts about the principal, such as token claims.
+	Attributes Attributes
+	// Impersonator is the real caller when support staff act as this principal.
+	Impersonator *Principal
+}
+
+// Actor is who is really making the call: the impersonator if there is one.
+func (p Principal) Actor() Principal {
+	if p.Impersonator != nil {
+		return *p.Impersonator
+	}
+	return p
+}
+
+// HasScope reports whether the principal's credential covers action. Only API
+// keys are limited by scopes; every other principal is left to the policy.
+func (p Principal) HasScope(action Action) bool {
+	return p.AuthMethod != AuthMethodAPIKey || slices.Contains(p.Scopes, APIKeyScope(action))
+}
+
+// AccessAttributes describes the principal to the access policy as its
+// attributes plus id, role, auth_method and, when impersonated, impersonated_by.
+func (p Principal) AccessAttributes() Attributes {
+	attrs := make(Attributes, len(p.Attributes)+4)
+	maps.Copy(attrs, p.Attributes)
+	attrs["id"] = p.ID
+	attrs["role"] = p.Role
+	attrs["auth_method"] = p.AuthMethod
+	if p.Impersonator != nil {
+		attrs["impersonated_by"] = p.Impersonator.ID
+	}
+	return attrs
+}
+
+type principalKey struct{}
+
+func ContextWithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
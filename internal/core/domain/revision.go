@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// What a revision did to the video. Workflow transitions are recorded under
+// their VideoTransition name.
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionRestore = "restore"
+	RevisionDelete  = "delete"
+)
+
+// VideoRevision records one write to a video: who made it, when, the fields it
+// changed and the video as it was afterwards (before, for a delete).
+type VideoRevision struct {
+	VideoID      string        `json:"video_id" bson:"video_id"`
+	Revision     int           `json:"revision" bson:"revision"`
+	Operation    string        `json:"operation" bson:"operation"`
+	Author       string        `json:"author" bson:"author"`
+	CreatedAt    time.Time     `json:"created_at" bson:"created_at"`
+	Changes      []FieldChange `json:"changes" bson:"changes"`
+	RestoredFrom int           `json:"restored_from,omitempty" bson:"restored_from,omitempty"`
+	// Comment is the reviewer's explanation of a workflow transition
+	Comment string `json:"comment,omitempty" bson:"comment,omitempty"`
+	Video   Video  `json:"video" bson:"video"`
+}
+
+// FieldChange is one field that differs between two versions of a video.
+type FieldChange struct {
+	Field string `json:"field" bson:"field"`
+	From  string `json:"from" bson:"from"`
+	To    string `json:"to" bson:"to"`
+}
+
+// VideoUpdate changes the editable fields of a video; nil fields are kept.
+type VideoUpdate struct {
+	URL         *string
+	ContentType *ContentType
+	Description *string
+	ClinicID    *string
+	AgeGroup    *string
+	// Schedule replaces the whole schedule
+	Schedule *VideoSchedule
+}
+
+// Apply returns v with the update's fields set.
+func (u VideoUpdate) Apply(v Video) Video {
+	if u.URL != nil {
+		v.URL = *u.URL
+	}
+	if u.ContentType != nil {
+		v.ContentType = *u.ContentType
+	}
+	if u.Description != nil {
+		v.Description = *u.Description
+	}
+	if u.ClinicID != nil {
+		v.ClinicID = *u.ClinicID
+	}
+	if u.AgeGroup != nil {
+		v.AgeGroup = *u.AgeGroup
+	}
+	if u.Schedule != nil {
+		v.Schedule = *u.Schedule
+	}
+	return v
+}
+
+// UpdateFrom returns an update that sets every editable field to its value in v,
+// used to restore an earlier revision.
+func UpdateFrom(v Video) VideoUpdate {
+	return VideoUpdate{
+		URL:         &v.URL,
+		ContentType: &v.ContentType,
+		Description: &v.Description,
+		ClinicID:    &v.ClinicID,
+		AgeGroup:    &v.AgeGroup,
+		Schedule:    &v.Schedule,
+	}
+}
+
+// DiffVideos lists the editable fields and the status where before and after differ.
+func DiffVideos(before, after Video) []FieldChange {
+	fields := []struct {
+		name          string
+		before, after string
+	}{
+		{"url", before.URL, after.URL},
+		{"content_type", string(before.ContentType), string(after.ContentType)},
+		{"description", before.Description, after.Description},
+		{"clinic_id", before.ClinicID, after.ClinicID},
+		{"age_group", before.AgeGroup, after.AgeGroup},
+		{"publish_at", formatScheduleTime(before.Schedule.PublishAt), formatScheduleTime(after.Schedule.PublishAt)},
+		{"unpublish_at", formatScheduleTime(before.Schedule.UnpublishAt), formatScheduleTime(after.Schedule.UnpublishAt)},
+		{"timezone", before.Schedule.Timezone, after.Schedule.Timezone},
+		{"status", string(before.Status), string(after.Status)},
+	}
+
+	changes := make([]FieldChange, 0)
+	for _, f := range fields {
+		if f.before != f.after {
+			changes = append(changes, FieldChange{Field: f.name, From: f.before, To: f.after})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// VideoSchedule publishes an approved video at PublishAt and unpublishes it at
+// UnpublishAt; either may be unset. Times are kept in UTC. Timezone is the IANA
+// zone the editor entered them in, so they can be shown back the same way.
+type VideoSchedule struct {
+	PublishAt   *time.Time `json:"publish_at,omitempty" bson:"publish_at,omitempty"`
+	UnpublishAt *time.Time `json:"unpublish_at,omitempty" bson:"unpublish_at,omitempty"`
+	Timezone    string     `json:"timezone,omitempty" bson:"schedule_timezone,omitempty"`
+}
+
+// Validate checks that the timezone is known and that the video does not come
+// down before it goes up.
+func (s VideoSchedule) Validate() error {
+	// "Local" would depend on the server's zone
+	if _, err := time.LoadLocation(s.Timezone); err != nil || s.Timezone == "Local" {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidSchedule, s.Timezone)
+	}
+	if s.PublishAt != nil && s.UnpublishAt != nil && !s.UnpublishAt.After(*s.PublishAt) {
+		return fmt.Errorf("%w: unpublish_at must be after publish_at", ErrInvalidSchedule)
+	}
+	return nil
+}
+
+// Location is the schedule's timezone, UTC when unset or unknown.
+func (s VideoSchedule) Location() *time.Location {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Open reports whether now falls between PublishAt and UnpublishAt.
+func (s VideoSchedule) Open(now time.Time) bool {
+	if s.PublishAt != nil && now.Before(*s.PublishAt) {
+		return false
+	}
+	return s.UnpublishAt == nil || now.Before(*s.UnpublishAt)
+}
+
+// DueTransition returns the transition the scheduler should apply to v at now:
+// publish for an approved video whose publish time has come, unpublish for a
+// published video whose unpublish time has passed.
+func DueTransition(v Video, now time.Time) (VideoTransition, bool) {
+	var name string
+	switch {
+	case v.Status == StatusApproved && v.Schedule.PublishAt != nil && v.Schedule.Open(now):
+		name = "publish"
+	case v.Status == StatusPublished && v.Schedule.UnpublishAt != nil && !now.Before(*v.Schedule.UnpublishAt):
+		name = "unpublish"
+	default:
+		return VideoTransition{}, false
+	}
+	t, err := FindTransition(name)
+	return t, err == nil
+}
+
+// VideoEvent announces that a video went live or was taken down, for services
+// that cache or index published content.
+type VideoEvent struct {
+	Type     string `json:"type"`
+	VideoID  string `json:"video_id"`
+	Revision int    `json:"revision"`
+	// Scheduled is set when the scheduler made the change rather than an editor
+	Scheduled bool      `json:"scheduled"`
+	Actor     string    `json:"actor"`
+	Time      time.Time `json:"time"`
+}
+
+const (
+	VideoEventPublished   = "video.published"
+	VideoEventUnpublished = "video.unpublished"
+)
+
+func formatScheduleTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
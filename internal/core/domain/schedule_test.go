@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestDueTransition(t *testing.T) {
+	now := time.Date(2027, 7, 1, 6, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		status   VideoStatus
+		schedule VideoSchedule
+		want     string
+	}{
+		{"approved, publish time passed", StatusApproved, VideoSchedule{PublishAt: &past}, "publish"},
+		{"approved, publish time is now", StatusApproved, VideoSchedule{PublishAt: &now}, "publish"},
+		{"approved, publish time ahead", StatusApproved, VideoSchedule{PublishAt: &future}, ""},
+		{"approved, whole window passed", StatusApproved, VideoSchedule{PublishAt: &past, UnpublishAt: &now}, ""},
+		{"approved, no publish time", StatusApproved, VideoSchedule{UnpublishAt: &future}, ""},
+		{"draft, publish time passed", StatusDraft, VideoSchedule{PublishAt: &past}, ""},
+		{"in review, publish time passed", StatusInReview, VideoSchedule{PublishAt: &past}, ""},
+		{"published, unpublish time passed", StatusPublished, VideoSchedule{UnpublishAt: &past}, "unpublish"},
+		{"published, unpublish time is now", StatusPublished, VideoSchedule{PublishAt: &past, UnpublishAt: &now}, "unpublish"},
+		{"published, unpublish time ahead", StatusPublished, VideoSchedule{PublishAt: &past, UnpublishAt: &future}, ""},
+		{"published, no unpublish time", StatusPublished, VideoSchedule{PublishAt: &past}, ""},
+		{"draft, unpublish time passed", StatusDraft, VideoSchedule{UnpublishAt: &past}, ""},
+		{"no schedule", StatusApproved, VideoSchedule{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			video := Video{Status: tt.status, Schedule: tt.schedule}
+			got, ok := DueTransition(video, now)
+			if ok != (tt.want != "") || got.Name != tt.want {
+				t.Fatalf("DueTransition() = %q, %v, want %q", got.Name, ok, tt.want)
+			}
+			// What is due also passes the checks of a manual transition
+			if ok {
+				if err := got.Check(video, "", now); err != nil {
+					t.Errorf("due %s refused by Check: %v", got.Name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestVideoScheduleValidate(t *testing.T) {
+	at := time.Date(2027, 7, 1, 6, 0, 0, 0, time.UTC)
+	later := at.Add(time.Minute)
+
+	tests := []struct {
+		name     string
+		schedule VideoSchedule
+		wantErr  bool
+	}{
+		{"empty", VideoSchedule{}, false},
+		{"known zone", VideoSchedule{PublishAt: &at, Timezone: "Europe/Amsterdam"}, false},
+		{"window", VideoSchedule{PublishAt: &at, UnpublishAt: &later}, false},
+		{"unknown zone", VideoSchedule{Timezone: "Mars/Olympus_Mons"}, true},
+		{"server zone", VideoSchedule{Timezone: "Local"}, true},
+		{"empty window", VideoSchedule{PublishAt: &at, UnpublishAt: &at}, true},
+		{"reversed window", VideoSchedule{PublishAt: &later, UnpublishAt: &at}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.schedule.Validate()
+			if tt.wantErr != errors.Is(err, ErrInvalidSchedule) || (!tt.wantErr && err != nil) {
+				t.Errorf("Validate() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 type ContentType string
 
@@ -13,10 +16,58 @@ const (
 	Sleeping      ContentType = "SLEEPING"
 )
 
+var (
+	ErrVideoNotFound = errors.New("video not found")
+	// ErrVideoConflict means the video changed since it was read.
+	ErrVideoConflict = errors.New("video was modified concurrently")
+)
+
 type Video struct {
 	ID          string      `json:"id" bson:"_id"`
 	URL         string      `json:"url" bson:"url"`
 	ContentType ContentType `json:"content_type" bson:"content_type"`
 	Description string      `json:"description" bson:"description"`
 	CreatedAt   time.Time   `json:"created_at" bson:"created_at"`
+	Status      VideoStatus `json:"status" bson:"status"`
+	// Schedule limits when a published video is shown
+	Schedule VideoSchedule `json:"schedule" bson:",inline"`
+
+	// Revision is the number of the latest VideoRevision, 0 for videos stored
+	// before revisions were kept
+	Revision  int       `json:"revision" bson:"revision"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+	UpdatedBy string    `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+
+	// Audience and ownership, used by the access policy
+	ClinicID  string `json:"clinic_id,omitempty" bson:"clinic_id,omitempty"`
+	AgeGroup  string `json:"age_group,omitempty" bson:"age_group,omitempty"`
+	CreatedBy string `json:"created_by,omitempty" bson:"created_by,omitempty"`
+}
+
+// VideoQuery narrows a video listing in the repository.
+type VideoQuery struct {
+	// PublishedOnly leaves out every video that is not published or is outside
+	// its schedule, for callers who may not preview
+	PublishedOnly bool
+}
+
+// Attributes exposes the video to policy conditions as "resource.<name>".
+// Unset fields are left out so conditions on them do not hold.
+func (v Video) Attributes() Attributes {
+	attrs := Attributes{
+		"type":         "video",
+		"id":           v.ID,
+		"content_type": string(v.ContentType),
+		"status":       string(v.Status),
+	}
+	if v.ClinicID != "" {
+		attrs["clinic_id"] = v.ClinicID
+	}
+	if v.AgeGroup != "" {
+		attrs["age_group"] = v.AgeGroup
+	}
+	if v.CreatedBy != "" {
+		attrs["created_by"] = v.CreatedBy
+	}
+	return attrs
 }
@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// VideoStatus is where a video is in the editorial workflow. Only published
+// videos are shown to parents.
+type VideoStatus string
+
+const (
+	StatusDraft     VideoStatus = "DRAFT"
+	StatusInReview  VideoStatus = "IN_REVIEW"
+	StatusApproved  VideoStatus = "APPROVED"
+	StatusPublished VideoStatus = "PUBLISHED"
+)
+
+var (
+	ErrUnknownTransition = errors.New("unknown transition")
+	ErrInvalidTransition = errors.New("transition not allowed")
+	ErrCommentRequired   = errors.New("a comment is required")
+	// ErrVideoNotEditable is returned when the content of a video that is not
+	// a draft is changed; it must be unpublished or rejected first.
+	ErrVideoNotEditable = errors.New("only draft videos can be edited")
+)
+
+// VideoTransition moves a video from one status to another. Action is what the
+// access policy must allow on the video.
+type VideoTransition struct {
+	Name            string
+	From            VideoStatus
+	To              VideoStatus
+	Action          Action
+	CommentRequired bool
+}
+
+// VideoTransitions is the editorial workflow: an author submits a draft for
+// medical review, a reviewer approves it or rejects it back to draft with a
+// comment, and an approved video is published. Unpublishing returns it to draft.
+var VideoTransitions = []VideoTransition{
+	{Name: "submit", From: StatusDraft, To: StatusInReview, Action: ActionVideoSubmit},
+	{Name: "approve", From: StatusInReview, To: StatusApproved, Action: ActionVideoReview},
+	{Name: "reject", From: StatusInReview, To: StatusDraft, Action: ActionVideoReview, CommentRequired: true},
+	{Name: "publish", From: StatusApproved, To: StatusPublished, Action: ActionVideoPublish},
+	{Name: "unpublish", From: StatusPublished, To: StatusDraft, Action: ActionVideoPublish},
+}
+
+// FindTransition returns the transition called name.
+func FindTransition(name string) (VideoTransition, error) {
+	for _, t := range VideoTransitions {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return VideoTransition{}, fmt.Errorf("%w %q", ErrUnknownTransition, name)
+}
+
+// Check reports whether the transition may be applied to video at now. A video
+// is not published ahead of its schedule or after it has ended.
+func (t VideoTransition) Check(video Video, comment string, now time.Time) error {
+	if video.Status != t.From {
+		return fmt.Errorf("%w: cannot %s a video that is %s", ErrInvalidTransition, t.Name, video.Status)
+	}
+	if t.CommentRequired && comment == "" {
+		return fmt.Errorf("%w to %s a video", ErrCommentRequired, t.Name)
+	}
+	if t.To != StatusPublished {
+		return nil
+	}
+	if at := video.Schedule.PublishAt; at != nil && now.Before(*at) {
+		return fmt.Errorf("%w: the video is scheduled to publish at %s", ErrInvalidTransition, formatScheduleTime(at))
+	}
+	if at := video.Schedule.UnpublishAt; at != nil && !now.Before(*at) {
+		return fmt.Errorf("%w: the video's schedule ended at %s", ErrInvalidTransition, formatScheduleTime(at))
+	}
+	return nil
+}
+
+// Notification tells a user about something that happened to their content.
+type Notification struct {
+	Recipient  string      `json:"recipient"`
+	Type       string      `json:"type"`
+	VideoID    string      `json:"video_id"`
+	Transition string      `json:"transition,omitempty"`
+	From       VideoStatus `json:"from,omitempty"`
+	To         VideoStatus `json:"to,omitempty"`
+	Comment    string      `json:"comment,omitempty"`
+	Actor      string      `json:"actor"`
+	Time       time.Time   `json:"time"`
+}
+
+// NotificationVideoStatusChanged is sent to the author of a video when it moves
+// through the workflow.
+const NotificationVideoStatusChanged = "video.status_changed"
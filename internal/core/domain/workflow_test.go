@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var statuses = []VideoStatus{StatusDraft, StatusInReview, StatusApproved, StatusPublished}
+
+func TestVideoTransitionCheckStatus(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, tr := range VideoTransitions {
+		for _, status := range statuses {
+			t.Run(tr.Name+"/"+string(status), func(t *testing.T) {
+				err := tr.Check(Video{Status: status}, "needs a better intro", now)
+				if status == tr.From && err != nil {
+					t.Errorf("Check() error = %v, want nil", err)
+				}
+				if status != tr.From && !errors.Is(err, ErrInvalidTransition) {
+					t.Errorf("Check() error = %v, want ErrInvalidTransition", err)
+				}
+			})
+		}
+	}
+}
+
+func TestVideoTransitionCheckComment(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, tr := range VideoTransitions {
+		err := tr.Check(Video{Status: tr.From}, "", now)
+		if tr.Name == "reject" && !errors.Is(err, ErrCommentRequired) {
+			t.Errorf("%s without a comment: error = %v, want ErrCommentRequired", tr.Name, err)
+		}
+		if tr.Name != "reject" && err != nil {
+			t.Errorf("%s without a comment: error = %v, want nil", tr.Name, err)
+		}
+	}
+}
+
+func TestVideoTransitionCheckSchedule(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	before, after := now.Add(-time.Hour), now.Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		transition string
+		schedule   VideoSchedule
+		wantErr    error
+	}{
+		{"publish without schedule", "publish", VideoSchedule{}, nil},
+		{"publish inside schedule", "publish", VideoSchedule{PublishAt: &before, UnpublishAt: &after}, nil},
+		{"publish at publish_at", "publish", VideoSchedule{PublishAt: &now}, nil},
+		{"publish early", "publish", VideoSchedule{PublishAt: &after}, ErrInvalidTransition},
+		{"publish after schedule ended", "publish", VideoSchedule{UnpublishAt: &before}, ErrInvalidTransition},
+		{"publish at unpublish_at", "publish", VideoSchedule{UnpublishAt: &now}, ErrInvalidTransition},
+		{"unpublish early ignores schedule", "unpublish", VideoSchedule{UnpublishAt: &after}, nil},
+		{"submit ignores schedule", "submit", VideoSchedule{PublishAt: &after}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := FindTransition(tt.transition)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = tr.Check(Video{Status: tr.From, Schedule: tt.schedule}, "", now)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("Check() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFindTransition(t *testing.T) {
+	for _, tr := range VideoTransitions {
+		if got, err := FindTransition(tr.Name); err != nil || got != tr {
+			t.Errorf("FindTransition(%q) = %+v, %v", tr.Name, got, err)
+		}
+	}
+	if _, err := FindTransition("archive"); !errors.Is(err, ErrUnknownTransition) {
+		t.Errorf("FindTransition(\"archive\") error = %v, want ErrUnknownTransition", err)
+	}
+}
@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+)
+
+type AuditLog interface {
+	Record(ctx context.Context, event domain.AuditEvent) error
+}
+
+// AuditTrail is an append-only AuditLog that can be searched.
+type AuditTrail interface {
+	AuditLog
+	Query(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error)
+}
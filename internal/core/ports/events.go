@@ -0,0 +1,11 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+)
+
+type EventPublisher interface {
+	Publish(ctx context.Context, event domain.VideoEvent) error
+}
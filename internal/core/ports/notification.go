@@ -0,0 +1,11 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+)
+
+type Notifier interface {
+	Notify(ctx context.Context, notification domain.Notification) error
+}
@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+)
+
+type PolicyEngine interface {
+	Evaluate(ctx context.Context, req domain.AccessRequest) domain.Decision
+	// MayAllow reports whether any rule could allow action for subject,
+	// whatever the resource.
+	MayAllow(subject domain.Attributes, action domain.Action) bool
+}
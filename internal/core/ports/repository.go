@@ -2,13 +2,46 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
 )
 
+// VideoRepository reads for readers, GetVisibleVideoByID and GetVideos with
+// PublishedOnly, leave out published videos outside their schedule, so expired
+// content disappears on time even before the scheduler unpublishes it. Other
+// reads return every video, for editors and the scheduler.
 type VideoRepository interface {
-	GetVideos(ctx context.Context) ([]domain.Video, error)
+	GetVideos(ctx context.Context, query domain.VideoQuery) ([]domain.Video, error)
 	GetVideoByID(ctx context.Context, id string) (*domain.Video, error)
+	// GetVisibleVideoByID fails with domain.ErrVideoNotFound for a published
+	// video outside its schedule.
+	GetVisibleVideoByID(ctx context.Context, id string) (*domain.Video, error)
 	CreateVideo(ctx context.Context, video domain.Video) (*domain.Video, error)
+	// UpdateVideo replaces the stored video, failing with domain.ErrVideoConflict
+	// unless it is still at expectedRevision.
+	UpdateVideo(ctx context.Context, video domain.Video, expectedRevision int) error
 	DeleteVideo(ctx context.Context, id string) error
+	// GetDueVideos returns the videos that domain.DueTransition would move at now,
+	// whatever their schedule.
+	GetDueVideos(ctx context.Context, now time.Time) ([]domain.Video, error)
+	// NextScheduledTime returns the earliest scheduled time after now that will
+	// move a video, or nil if there is none.
+	NextScheduledTime(ctx context.Context, now time.Time) (*time.Time, error)
+}
+
+type VideoRevisionRepository interface {
+	// AddRevision fails with domain.ErrVideoConflict if the revision number is taken.
+	AddRevision(ctx context.Context, revision domain.VideoRevision) error
+	// ListRevisions returns the revisions of a video, newest first.
+	ListRevisions(ctx context.Context, videoID string) ([]domain.VideoRevision, error)
+	GetRevision(ctx context.Context, videoID string, revision int) (*domain.VideoRevision, error)
+}
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key domain.APIKey) error
+	GetAPIKey(ctx context.Context, id string) (*domain.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]domain.APIKey, error)
+	DeleteAPIKey(ctx context.Context, id string) error
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
 }
@@ -2,6 +2,7 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
 )
@@ -10,5 +11,17 @@ type VideoService interface {
 	GetVideos(ctx context.Context) ([]domain.Video, error)
 	GetVideoByID(ctx context.Context, id string) (*domain.Video, error)
 	CreateVideo(ctx context.Context, video domain.Video) (*domain.Video, error)
+	UpdateVideo(ctx context.Context, id string, update domain.VideoUpdate) (*domain.Video, error)
 	DeleteVideo(ctx context.Context, id string) error
+	ListRevisions(ctx context.Context, id string) ([]domain.VideoRevision, error)
+	RestoreRevision(ctx context.Context, id string, revision int) (*domain.Video, error)
+	TransitionVideo(ctx context.Context, id, transition, comment string) (*domain.Video, error)
+}
+
+type APIKeyService interface {
+	// CreateAPIKey returns the raw key, which is never stored or shown again.
+	CreateAPIKey(ctx context.Context, name string, scopes []domain.APIKeyScope, expiresAt *time.Time, createdBy string) (string, *domain.APIKey, error)
+	Authenticate(ctx context.Context, rawKey string) (*domain.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]domain.APIKey, error)
+	DeleteAPIKey(ctx context.Context, id string) error
 }
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/tracing"
+)
+
+// Raw keys look like "bkm_<id>_<secret>": the id selects the stored record and
+// the secret is checked against its salted hash.
+const apiKeyPrefix = "bkm_"
+
+// lastUsedResolution limits last-used writes to one per key per minute.
+const lastUsedResolution = time.Minute
+
+type APIKeyService struct {
+	repo ports.APIKeyRepository
+}
+
+var _ ports.APIKeyService = (*APIKeyService)(nil)
+
+func NewAPIKeyService(repo ports.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		repo: repo,
+	}
+}
+
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, name string, scopes []domain.APIKeyScope, expiresAt *time.Time, createdBy string) (string, *domain.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.CreateAPIKey", tracing.KindInternal)
+	defer span.End()
+
+	if name == "" {
+		return "", nil, fmt.Errorf("%w: name is required", domain.ErrInvalidAPIKeyRequest)
+	}
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("%w: at least one scope is required", domain.ErrInvalidAPIKeyRequest)
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(domain.APIKeyScopes, scope) {
+			return "", nil, fmt.Errorf("%w: unknown scope %q", domain.ErrInvalidAPIKeyRequest, scope)
+		}
+	}
+	now := time.Now().UTC()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return "", nil, fmt.Errorf("%w: expires_at must be in the future", domain.ErrInvalidAPIKeyRequest)
+	}
+
+	id, err := randomBytes(8)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomBytes(32)
+	if err != nil {
+		return "", nil, err
+	}
+	salt, err := randomBytes(16)
+	if err != nil {
+		return "", nil, err
+	}
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+
+	key := domain.APIKey{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Salt:      salt,
+		Hash:      hashAPIKeySecret(salt, encodedSecret),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+		CreatedBy: createdBy,
+	}
+	if err := s.repo.CreateAPIKey(ctx, key); err != nil {
+		span.RecordError(err)
+		return "", nil, err
+	}
+
+	return apiKeyPrefix + key.ID + "_" + encodedSecret, &key, nil
+}
+
+// Authenticate returns the key matching rawKey. Unknown ids and wrong secrets
+// both return domain.ErrInvalidAPIKey so callers cannot tell them apart.
+func (s *APIKeyService) Authenticate(ctx context.Context, rawKey string) (*domain.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.Authenticate", tracing.KindInternal)
+	defer span.End()
+
+	rest, ok := strings.CutPrefix(rawKey, apiKeyPrefix)
+	if !ok {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || id == "" || secret == "" {
+		return nil, domain.ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.GetAPIKey(ctx, id)
+	if errors.Is(err, domain.ErrAPIKeyNotFound) {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare(key.Hash, hashAPIKeySecret(key.Salt, secret)) != 1 {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	now := time.Now().UTC()
+	if key.Expired(now) {
+		return nil, domain.ErrAPIKeyExpired
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		// Last-used tracking is best effort and never fails the request
+		if err := s.repo.TouchAPIKey(ctx, key.ID, now); err != nil {
+			slog.WarnContext(ctx, "failed to record api key use", "api_key_id", key.ID, "error", err)
+		} else {
+			key.LastUsedAt = &now
+		}
+	}
+	return key, nil
+}
+
+func (s *APIKeyService) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.ListAPIKeys", tracing.KindInternal)
+	defer span.End()
+
+	keys, err := s.repo.ListAPIKeys(ctx)
+	span.RecordError(err)
+	return keys, err
+}
+
+func (s *APIKeyService) DeleteAPIKey(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "APIKeyService.DeleteAPIKey", tracing.KindInternal)
+	defer span.End()
+
+	err := s.repo.DeleteAPIKey(ctx, id)
+	span.RecordError(err)
+	return err
+}
+
+func hashAPIKeySecret(salt []byte, secret string) []byte {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(secret))
+	return h.Sum(nil)
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+)
+
+// memoryAPIKeys is an APIKeyRepository backed by a map that records last-used writes.
+type memoryAPIKeys struct {
+	mu       sync.Mutex
+	keys     map[string]domain.APIKey
+	touches  int
+	touchErr error
+	getErr   error
+}
+
+func newMemoryAPIKeys() *memoryAPIKeys {
+	return &memoryAPIKeys{keys: make(map[string]domain.APIKey)}
+}
+
+func (r *memoryAPIKeys) CreateAPIKey(ctx context.Context, key domain.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[key.ID] = key
+	return nil
+}
+
+func (r *memoryAPIKeys) GetAPIKey(ctx context.Context, id string) (*domain.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.getErr != nil {
+		return nil, r.getErr
+	}
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	return &key, nil
+}
+
+func (r *memoryAPIKeys) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var keys []domain.APIKey
+	for _, key := range r.keys {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (r *memoryAPIKeys) DeleteAPIKey(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.keys[id]; !ok {
+		return domain.ErrAPIKeyNotFound
+	}
+	delete(r.keys, id)
+	return nil
+}
+
+func (r *memoryAPIKeys) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.touches++
+	if r.touchErr != nil {
+		return r.touchErr
+	}
+	key := r.keys[id]
+	key.LastUsedAt = &usedAt
+	r.keys[id] = key
+	return nil
+}
+
+// update changes a stored key in place, e.g. to backdate it.
+func (r *memoryAPIKeys) update(id string, fn func(*domain.APIKey)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := r.keys[id]
+	fn(&key)
+	r.keys[id] = key
+}
+
+func createTestKey(t *testing.T, s *APIKeyService) (string, *domain.APIKey) {
+	t.Helper()
+	raw, key, err := s.CreateAPIKey(context.Background(), "analytics", []domain.APIKeyScope{domain.ScopeVideosRead}, nil, "admin-1")
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	return raw, key
+}
+
+func TestAPIKeyRoundTrip(t *testing.T) {
+	repo := newMemoryAPIKeys()
+	s := NewAPIKeyService(repo)
+	raw, created := createTestKey(t, s)
+
+	if !strings.HasPrefix(raw, apiKeyPrefix+created.ID+"_") {
+		t.Errorf("raw key %q does not start with %s<id>_", raw, apiKeyPrefix)
+	}
+	stored := repo.keys[created.ID]
+	if strings.Contains(string(stored.Hash), raw) || len(stored.Salt) == 0 {
+		t.Error("stored key keeps the secret or has no salt")
+	}
+
+	key, err := s.Authenticate(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if key.ID != created.ID || key.Name != "analytics" || key.CreatedBy != "admin-1" {
+		t.Errorf("Authenticate() = %+v, want the created key", key)
+	}
+	if len(key.Scopes) != 1 || key.Scopes[0] != domain.ScopeVideosRead {
+		t.Errorf("scopes = %v", key.Scopes)
+	}
+	if key.LastUsedAt == nil {
+		t.Error("LastUsedAt not set on first use")
+	}
+
+	// A second key has its own id, secret and salt
+	other, _ := createTestKey(t, s)
+	if other == raw {
+		t.Fatal("two keys share a raw value")
+	}
+	if _, err := s.Authenticate(context.Background(), other); err != nil {
+		t.Errorf("second key: %v", err)
+	}
+}
+
+func TestAPIKeyRejected(t *testing.T) {
+	repo := newMemoryAPIKeys()
+	s := NewAPIKeyService(repo)
+	raw, created := createTestKey(t, s)
+	_, other := createTestKey(t, s)
+	secret := strings.TrimPrefix(raw, apiKeyPrefix+created.ID+"_")
+
+	// Flip the last character to keep the length and alphabet
+	last := secret[len(secret)-1]
+	flipped := "A"
+	if last == 'A' {
+		flipped = "B"
+	}
+
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"wrong secret", apiKeyPrefix + created.ID + "_" + secret[:len(secret)-1] + flipped},
+		{"secret of another length", raw + "x"},
+		{"unknown id", apiKeyPrefix + "0000000000000000_" + secret},
+		{"secret of another key", apiKeyPrefix + other.ID + "_" + secret},
+		{"missing prefix", strings.TrimPrefix(raw, apiKeyPrefix)},
+		{"wrong prefix", "bkx_" + strings.TrimPrefix(raw, apiKeyPrefix)},
+		{"prefix only", apiKeyPrefix},
+		{"no separator", apiKeyPrefix + created.ID + secret},
+		{"empty id", apiKeyPrefix + "_" + secret},
+		{"empty secret", apiKeyPrefix + created.ID + "_"},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := s.Authenticate(context.Background(), tt.raw)
+			if !errors.Is(err, domain.ErrInvalidAPIKey) || key != nil {
+				t.Errorf("Authenticate(%q) = %v, %v, want ErrInvalidAPIKey", tt.raw, key, err)
+			}
+		})
+	}
+	if repo.touches != 0 {
+		t.Errorf("rejected keys recorded %d uses", repo.touches)
+	}
+}
+
+func TestAPIKeyExpired(t *testing.T) {
+	repo := newMemoryAPIKeys()
+	s := NewAPIKeyService(repo)
+
+	past := time.Now().Add(-time.Minute)
+	if _, _, err := s.CreateAPIKey(context.Background(), "old", []domain.APIKeyScope{domain.ScopeVideosRead}, &past, "admin-1"); !errors.Is(err, domain.ErrInvalidAPIKeyRequest) {
+		t.Fatalf("CreateAPIKey(expired) error = %v, want ErrInvalidAPIKeyRequest", err)
+	}
+
+	future := time.Now().Add(time.Hour)
+	raw, created, err := s.CreateAPIKey(context.Background(), "expiring", []domain.APIKeyScope{domain.ScopeVideosRead}, &future, "admin-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Authenticate(context.Background(), raw); err != nil {
+		t.Fatalf("Authenticate() before expiry = %v", err)
+	}
+
+	// Expiry is inclusive
+	repo.update(created.ID, func(k *domain.APIKey) {
+		at := time.Now().UTC()
+		k.ExpiresAt = &at
+	})
+	touches := repo.touches
+	if _, err := s.Authenticate(context.Background(), raw); !errors.Is(err, domain.ErrAPIKeyExpired) {
+		t.Errorf("Authenticate() after expiry = %v, want ErrAPIKeyExpired", err)
+	}
+	if repo.touches != touches {
+		t.Error("expired key recorded a use")
+	}
+
+	// A wrong secret for an expired key does not reveal the expiry
+	if _, err := s.Authenticate(context.Background(), raw+"x"); !errors.Is(err, domain.ErrInvalidAPIKey) {
+		t.Errorf("wrong secret for an expired key = %v, want ErrInvalidAPIKey", err)
+	}
+}
+
+func TestAPIKeyLastUsedThrottle(t *testing.T) {
+	repo := newMemoryAPIKeys()
+	s := NewAPIKeyService(repo)
+	raw, created := createTestKey(t, s)
+
+	for range 5 {
+		if _, err := s.Authenticate(context.Background(), raw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if repo.touches != 1 {
+		t.Fatalf("%d last-used writes within a minute, want 1", repo.touches)
+	}
+
+	repo.update(created.ID, func(k *domain.APIKey) {
+		at := time.Now().UTC().Add(-lastUsedResolution)
+		k.LastUsedAt = &at
+	})
+	if _, err := s.Authenticate(context.Background(), raw); err != nil {
+		t.Fatal(err)
+	}
+	if repo.touches != 2 {
+		t.Errorf("%d last-used writes, want a new one after %v", repo.touches, lastUsedResolution)
+	}
+
+	// Tracking failures never reject the key
+	repo.update(created.ID, func(k *domain.APIKey) { k.LastUsedAt = nil })
+	repo.touchErr = errors.New("write conflict")
+	key, err := s.Authenticate(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("Authenticate() with a failing last-used write = %v", err)
+	}
+	if key.LastUsedAt != nil {
+		t.Error("LastUsedAt reported although the write failed")
+	}
+}
+
+func TestAPIKeyLookupError(t *testing.T) {
+	repo := newMemoryAPIKeys()
+	s := NewAPIKeyService(repo)
+	raw, _ := createTestKey(t, s)
+
+	repo.getErr = errors.New("connection reset")
+	_, err := s.Authenticate(context.Background(), raw)
+	if err == nil || errors.Is(err, domain.ErrInvalidAPIKey) {
+		t.Errorf("Authenticate() with the store down = %v, want the store error so callers answer 503", err)
+	}
+}
+
+func TestCreateAPIKeyValidation(t *testing.T) {
+	s := NewAPIKeyService(newMemoryAPIKeys())
+	tests := []struct {
+		name   string
+		key    string
+		scopes []domain.APIKeyScope
+	}{
+		{"no name", "", []domain.APIKeyScope{domain.ScopeVideosRead}},
+		{"no scopes", "analytics", nil},
+		{"unknown scope", "analytics", []domain.APIKeyScope{"videos:write"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := s.CreateAPIKey(context.Background(), tt.key, tt.scopes, nil, "admin-1"); !errors.Is(err, domain.ErrInvalidAPIKeyRequest) {
+				t.Errorf("error = %v, want ErrInvalidAPIKeyRequest", err)
+			}
+		})
+	}
+}
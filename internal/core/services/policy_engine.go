@@ -0,0 +1,403 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/metrics"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/tracing"
+)
+
+var policyDecisions = metrics.NewCounterVec(
+	"policy_decisions_total",
+	"Access policy decisions by action and outcome (allow or deny).",
+	"action", "decision",
+)
+
+// PolicyEngine evaluates access requests against a fixed set of attribute-based
+// rules.
+type PolicyEngine struct {
+	rules []domain.PolicyRule
+}
+
+var _ ports.PolicyEngine = (*PolicyEngine)(nil)
+
+// NewPolicyEngine validates policy and returns an engine for it.
+func NewPolicyEngine(policy domain.Policy) (*PolicyEngine, error) {
+	if err := validatePolicy(policy); err != nil {
+		return nil, err
+	}
+	return &PolicyEngine{
+		rules: policy.Rules,
+	}, nil
+}
+
+// LoadPolicy reads a JSON policy file. Unknown fields are rejected so that a
+// misspelt "conditions" cannot silently widen an allow rule.
+func LoadPolicy(path string) (domain.Policy, error) {
+	var policy domain.Policy
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return policy, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&policy); err != nil {
+		return policy, fmt.Errorf("%w: %v", domain.ErrInvalidPolicy, err)
+	}
+	return policy, nil
+}
+
+// DefaultPolicy grants what the route roles alone granted: admins may do
+// anything, parents and API keys may read published videos. Reviewers may
+// preview and review videos, but nobody may review their own.
+func DefaultPolicy() domain.Policy {
+	return domain.Policy{
+		Rules: []domain.PolicyRule{
+			{
+				ID:          "admins",
+				Description: "Administrators manage all videos",
+				Effect:      domain.PolicyAllow,
+				Actions:     []domain.Action{domain.AnyAction},
+				Roles:       []string{"ADMIN"},
+			},
+			{
+				ID:          "readers",
+				Description: "Parents and API keys read all published videos",
+				Effect:      domain.PolicyAllow,
+				Actions:     []domain.Action{domain.ActionVideoRead},
+				Roles:       []string{"PARENT", "API_KEY"},
+			},
+			{
+				ID:          "reviewers",
+				Description: "Reviewers approve or reject videos submitted for medical review",
+				Effect:      domain.PolicyAllow,
+				Actions:     []domain.Action{domain.ActionVideoRead, domain.ActionVideoPreview, domain.ActionVideoReview},
+				Roles:       []string{"REVIEWER"},
+			},
+			{
+				ID:          "no-self-review",
+				Description: "Authors cannot review their own videos",
+				Effect:      domain.PolicyDeny,
+				Actions:     []domain.Action{domain.ActionVideoReview},
+				Conditions: []domain.PolicyCondition{
+					{Attribute: "resource.created_by", Operator: domain.OperatorEquals, Ref: "subject.id"},
+				},
+			},
+		},
+	}
+}
+
+// Evaluate returns the decision for req together with a trace of every rule.
+// Deny rules override allow rules, and anything not explicitly allowed is denied.
+func (e *PolicyEngine) Evaluate(ctx context.Context, req domain.AccessRequest) domain.Decision {
+	_, span := tracing.Start(ctx, "PolicyEngine.Evaluate", tracing.KindInternal,
+		slog.String("policy.action", string(req.Action)),
+	)
+	defer span.End()
+
+	role := firstValue(req.Subject["role"])
+	decision := domain.Decision{Trace: make([]domain.RuleTrace, 0, len(e.rules))}
+
+	var allowedBy, deniedBy *domain.PolicyRule
+	var closest string // first failed condition of an applicable allow rule
+	for i := range e.rules {
+		rule := &e.rules[i]
+		trace := domain.RuleTrace{RuleID: rule.ID, Effect: rule.Effect}
+
+		if !ruleApplies(rule, req.Action, role) {
+			trace.Detail = fmt.Sprintf("does not apply to %q for role %q", req.Action, role)
+			decision.Trace = append(decision.Trace, trace)
+			continue
+		}
+		trace.Applies = true
+
+		failed, detail := firstFailedCondition(rule.Conditions, req)
+		if failed == nil {
+			trace.Matched = true
+			trace.Detail = "all conditions hold"
+			if rule.Effect == domain.PolicyDeny && deniedBy == nil {
+				deniedBy = rule
+			}
+			if rule.Effect == domain.PolicyAllow && allowedBy == nil {
+				allowedBy = rule
+			}
+		} else {
+			trace.Detail = detail
+			if rule.Effect == domain.PolicyAllow && closest == "" {
+				closest = fmt.Sprintf("rule %q requires %s", rule.ID, describeCondition(*failed))
+			}
+		}
+		decision.Trace = append(decision.Trace, trace)
+	}
+
+	switch {
+	case deniedBy != nil:
+		decision.RuleID = deniedBy.ID
+		decision.Reason = fmt.Sprintf("denied by rule %q", deniedBy.ID)
+		if deniedBy.Description != "" {
+			decision.Reason += ": " + deniedBy.Description
+		}
+	case allowedBy != nil:
+		decision.Allowed = true
+		decision.RuleID = allowedBy.ID
+		decision.Reason = fmt.Sprintf("allowed by rule %q", allowedBy.ID)
+	case closest != "":
+		decision.Reason = closest
+	default:
+		decision.Reason = fmt.Sprintf("no rule allows %q for role %q", req.Action, role)
+	}
+
+	outcome := "deny"
+	if decision.Allowed {
+		outcome = "allow"
+	}
+	policyDecisions.WithLabelValues(string(req.Action), outcome).Inc()
+	span.SetAttributes(slog.String("policy.decision", outcome), slog.String("policy.rule", decision.RuleID))
+
+	return decision
+}
+
+// MayAllow reports whether an allow rule applies to action for the subject's
+// role. Conditions are not evaluated, so a principal it admits may still be
+// denied, but one it turns away is denied for every resource.
+func (e *PolicyEngine) MayAllow(subject domain.Attributes, action domain.Action) bool {
+	role := firstValue(subject["role"])
+	for i := range e.rules {
+		if e.rules[i].Effect == domain.PolicyAllow && ruleApplies(&e.rules[i], action, role) {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleApplies(rule *domain.PolicyRule, action domain.Action, role string) bool {
+	if !slices.Contains(rule.Actions, action) && !slices.Contains(rule.Actions, domain.AnyAction) {
+		return false
+	}
+	return len(rule.Roles) == 0 || slices.Contains(rule.Roles, role)
+}
+
+// firstFailedCondition returns the first condition of conditions that does not
+// hold for req, with an explanation that includes the compared values.
+func firstFailedCondition(conditions []domain.PolicyCondition, req domain.AccessRequest) (*domain.PolicyCondition, string) {
+	for i := range conditions {
+		c := &conditions[i]
+		if ok, detail := evaluateCondition(*c, req); !ok {
+			return c, detail
+		}
+	}
+	return nil, ""
+}
+
+func evaluateCondition(c domain.PolicyCondition, req domain.AccessRequest) (bool, string) {
+	actual, ok := resolveAttribute(req, c.Attribute)
+	if !ok {
+		return false, c.Attribute + " is not set"
+	}
+	if c.Operator == domain.OperatorExists {
+		return true, ""
+	}
+
+	expected, source := c.Values, fmt.Sprint(c.Values)
+	switch {
+	case c.Ref != "":
+		expected, ok = resolveAttribute(req, c.Ref)
+		if !ok {
+			return false, c.Ref + " is not set"
+		}
+		source = fmt.Sprintf("%s %v", c.Ref, expected)
+	case c.Operator != domain.OperatorIn:
+		expected, source = []string{c.Value}, strconv.Quote(c.Value)
+	}
+
+	var holds bool
+	switch c.Operator {
+	case domain.OperatorEquals:
+		holds = slices.Equal(actual, expected)
+	case domain.OperatorNotEquals:
+		holds = !slices.Equal(actual, expected)
+	case domain.OperatorIn:
+		holds = containsAll(expected, actual)
+	case domain.OperatorContains:
+		holds = containsAll(actual, expected)
+	}
+	if holds {
+		return true, ""
+	}
+	return false, fmt.Sprintf("%s %v does not satisfy %s %s", c.Attribute, actual, c.Operator, source)
+}
+
+// describeCondition renders c without attribute values, for deny reasons shown
+// to the caller.
+func describeCondition(c domain.PolicyCondition) string {
+	var operand string
+	switch {
+	case c.Ref != "":
+		operand = c.Ref
+	case c.Operator == domain.OperatorIn:
+		operand = "[" + strings.Join(c.Values, ", ") + "]"
+	default:
+		operand = strconv.Quote(c.Value)
+	}
+
+	switch c.Operator {
+	case domain.OperatorExists:
+		return c.Attribute + " to be set"
+	case domain.OperatorEquals:
+		return c.Attribute + " to equal " + operand
+	case domain.OperatorNotEquals:
+		return c.Attribute + " not to equal " + operand
+	case domain.OperatorIn:
+		return c.Attribute + " to be one of " + operand
+	default:
+		return c.Attribute + " to contain " + operand
+	}
+}
+
+// resolveAttribute looks up "subject.<name>" or "resource.<name>" and returns its
+// values as strings. Empty values count as not set.
+func resolveAttribute(req domain.AccessRequest, path string) ([]string, bool) {
+	scope, name, _ := strings.Cut(path, ".")
+	var attrs domain.Attributes
+	switch scope {
+	case "subject":
+		attrs = req.Subject
+	case "resource":
+		attrs = req.Resource
+	}
+
+	raw, ok := attrs[name]
+	if !ok {
+		return nil, false
+	}
+	var values []string
+	switch v := raw.(type) {
+	case []string:
+		values = v
+	case []any:
+		for _, item := range v {
+			if s := attributeString(item); s != "" {
+				values = append(values, s)
+			}
+		}
+	default:
+		if s := attributeString(v); s != "" {
+			values = []string{s}
+		}
+	}
+	return values, len(values) > 0
+}
+
+func attributeString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+func firstValue(raw any) string {
+	if s, ok := raw.(string); ok {
+		return s
+	}
+	return ""
+}
+
+func containsAll(set, values []string) bool {
+	for _, v := range values {
+		if !slices.Contains(set, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func validatePolicy(policy domain.Policy) error {
+	if len(policy.Rules) == 0 {
+		return fmt.Errorf("%w: no rules", domain.ErrInvalidPolicy)
+	}
+
+	seen := make(map[string]bool, len(policy.Rules))
+	for i, rule := range policy.Rules {
+		if rule.ID == "" {
+			return fmt.Errorf("%w: rule %d has no id", domain.ErrInvalidPolicy, i)
+		}
+		if seen[rule.ID] {
+			return fmt.Errorf("%w: duplicate rule id %q", domain.ErrInvalidPolicy, rule.ID)
+		}
+		seen[rule.ID] = true
+
+		if rule.Effect != domain.PolicyAllow && rule.Effect != domain.PolicyDeny {
+			return fmt.Errorf("%w: rule %q: effect must be allow or deny", domain.ErrInvalidPolicy, rule.ID)
+		}
+		if len(rule.Actions) == 0 {
+			return fmt.Errorf("%w: rule %q has no actions", domain.ErrInvalidPolicy, rule.ID)
+		}
+		for _, c := range rule.Conditions {
+			if err := validateCondition(c); err != nil {
+				return fmt.Errorf("%w: rule %q: %v", domain.ErrInvalidPolicy, rule.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateCondition(c domain.PolicyCondition) error {
+	if !validAttributePath(c.Attribute) {
+		return fmt.Errorf("attribute %q must start with subject. or resource.", c.Attribute)
+	}
+	if c.Ref != "" && !validAttributePath(c.Ref) {
+		return fmt.Errorf("ref %q must start with subject. or resource.", c.Ref)
+	}
+
+	operands := 0
+	if c.Value != "" {
+		operands++
+	}
+	if len(c.Values) > 0 {
+		operands++
+	}
+	if c.Ref != "" {
+		operands++
+	}
+
+	switch c.Operator {
+	case domain.OperatorExists:
+		if operands != 0 {
+			return fmt.Errorf("%s on %q takes no value", c.Operator, c.Attribute)
+		}
+	case domain.OperatorIn:
+		if operands != 1 || c.Value != "" {
+			return fmt.Errorf("%s on %q needs either values or ref", c.Operator, c.Attribute)
+		}
+	case domain.OperatorEquals, domain.OperatorNotEquals, domain.OperatorContains:
+		if operands != 1 || len(c.Values) > 0 {
+			return fmt.Errorf("%s on %q needs either value or ref", c.Operator, c.Attribute)
+		}
+	default:
+		return fmt.Errorf("unknown operator %q", c.Operator)
+	}
+	return nil
+}
+
+func validAttributePath(path string) bool {
+	scope, name, ok := strings.Cut(path, ".")
+	return ok && name != "" && (scope == "subject" || scope == "resource")
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+)
+
+func newDefaultEngine(t *testing.T) *PolicyEngine {
+	t.Helper()
+	engine, err := NewPolicyEngine(DefaultPolicy())
+	if err != nil {
+		t.Fatalf("NewPolicyEngine(DefaultPolicy()) error = %v", err)
+	}
+	return engine
+}
+
+func TestDefaultPolicyRoles(t *testing.T) {
+	engine := newDefaultEngine(t)
+
+	allowed := map[string][]domain.Action{
+		"ADMIN":    domain.Actions,
+		"PARENT":   {domain.ActionVideoRead},
+		"API_KEY":  {domain.ActionVideoRead},
+		"REVIEWER": {domain.ActionVideoRead, domain.ActionVideoPreview, domain.ActionVideoReview},
+		"SYSTEM":   nil,
+		"NURSE":    nil,
+		"":         nil,
+	}
+	for role, actions := range allowed {
+		for _, action := range domain.Actions {
+			want := slices.Contains(actions, action)
+			t.Run(role+"/"+string(action), func(t *testing.T) {
+				decision := engine.Evaluate(context.Background(), domain.AccessRequest{
+					Subject:  domain.Attributes{"role": role, "id": "user-1"},
+					Action:   action,
+					Resource: domain.Attributes{"created_by": "author-1"},
+				})
+				if decision.Allowed != want {
+					t.Errorf("Allowed = %v, want %v (%s)", decision.Allowed, want, decision.Reason)
+				}
+				if !want && decision.RuleID != "" {
+					t.Errorf("denied without a deny rule matching, but RuleID = %q", decision.RuleID)
+				}
+			})
+		}
+	}
+}
+
+func TestDefaultPolicyNoSelfReview(t *testing.T) {
+	engine := newDefaultEngine(t)
+
+	tests := []struct {
+		name      string
+		role      string
+		action    domain.Action
+		createdBy string
+		want      bool
+		wantRule  string
+	}{
+		{"reviewer reviews another's video", "REVIEWER", domain.ActionVideoReview, "author-1", true, "reviewers"},
+		{"reviewer reviews own video", "REVIEWER", domain.ActionVideoReview, "user-1", false, "no-self-review"},
+		{"deny overrides admin wildcard", "ADMIN", domain.ActionVideoReview, "user-1", false, "no-self-review"},
+		{"admin reviews another's video", "ADMIN", domain.ActionVideoReview, "author-1", true, "admins"},
+		{"reviewer previews own video", "REVIEWER", domain.ActionVideoPreview, "user-1", true, "reviewers"},
+		{"author unknown", "REVIEWER", domain.ActionVideoReview, "", true, "reviewers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := domain.Attributes{}
+			if tt.createdBy != "" {
+				resource["created_by"] = tt.createdBy
+			}
+			decision := engine.Evaluate(context.Background(), domain.AccessRequest{
+				Subject:  domain.Attributes{"role": tt.role, "id": "user-1"},
+				Action:   tt.action,
+				Resource: resource,
+			})
+			if decision.Allowed != tt.want || decision.RuleID != tt.wantRule {
+				t.Errorf("decision = (%v, %q), want (%v, %q): %s", decision.Allowed, decision.RuleID, tt.want, tt.wantRule, decision.Reason)
+			}
+		})
+	}
+}
+
+func TestPolicyEngineDefaultDeny(t *testing.T) {
+	engine, err := NewPolicyEngine(domain.Policy{Rules: []domain.PolicyRule{{
+		ID:      "sleep-only",
+		Effect:  domain.PolicyAllow,
+		Actions: []domain.Action{domain.ActionVideoRead},
+		Roles:   []string{"PARENT"},
+		Conditions: []domain.PolicyCondition{
+			{Attribute: "resource.content_type", Operator: domain.OperatorEquals, Value: "SLEEPING"},
+		},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		role       string
+		action     domain.Action
+		resource   domain.Attributes
+		want       bool
+		wantReason string
+	}{
+		{"all conditions hold", "PARENT", domain.ActionVideoRead, domain.Attributes{"content_type": "SLEEPING"}, true, `allowed by rule "sleep-only"`},
+		{"condition fails", "PARENT", domain.ActionVideoRead, domain.Attributes{"content_type": "FEEDING"}, false, `rule "sleep-only" requires resource.content_type to equal "SLEEPING"`},
+		{"attribute missing", "PARENT", domain.ActionVideoRead, nil, false, `rule "sleep-only" requires resource.content_type to equal "SLEEPING"`},
+		{"other action", "PARENT", domain.ActionVideoDelete, domain.Attributes{"content_type": "SLEEPING"}, false, `no rule allows "videos:delete" for role "PARENT"`},
+		{"other role", "ADMIN", domain.ActionVideoRead, domain.Attributes{"content_type": "SLEEPING"}, false, `no rule allows "videos:read" for role "ADMIN"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := engine.Evaluate(context.Background(), domain.AccessRequest{
+				Subject:  domain.Attributes{"role": tt.role},
+				Action:   tt.action,
+				Resource: tt.resource,
+			})
+			if decision.Allowed != tt.want || decision.Reason != tt.wantReason {
+				t.Errorf("decision = (%v, %q), want (%v, %q)", decision.Allowed, decision.Reason, tt.want, tt.wantReason)
+			}
+			if len(decision.Trace) != 1 {
+				t.Errorf("trace has %d entries, want one per rule", len(decision.Trace))
+			}
+		})
+	}
+}
+
+func TestPolicyConditionOperators(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition domain.PolicyCondition
+		subject   domain.Attributes
+		want      bool
+	}{
+		{"equals", domain.PolicyCondition{Attribute: "subject.ward", Operator: domain.OperatorEquals, Value: "maternity"}, domain.Attributes{"ward": "maternity"}, true},
+		{"equals number", domain.PolicyCondition{Attribute: "subject.level", Operator: domain.OperatorEquals, Value: "2"}, domain.Attributes{"level": float64(2)}, true},
+		{"not_equals", domain.PolicyCondition{Attribute: "subject.ward", Operator: domain.OperatorNotEquals, Value: "nicu"}, domain.Attributes{"ward": "maternity"}, true},
+		{"not_equals on missing attribute", domain.PolicyCondition{Attribute: "subject.ward", Operator: domain.OperatorNotEquals, Value: "nicu"}, domain.Attributes{}, false},
+		{"in", domain.PolicyCondition{Attribute: "subject.ward", Operator: domain.OperatorIn, Values: []string{"maternity", "nicu"}}, domain.Attributes{"ward": "nicu"}, true},
+		{"in with value outside", domain.PolicyCondition{Attribute: "subject.ward", Operator: domain.OperatorIn, Values: []string{"maternity"}}, domain.Attributes{"ward": []any{"maternity", "nicu"}}, false},
+		{"contains", domain.PolicyCondition{Attribute: "subject.groups", Operator: domain.OperatorContains, Value: "editors"}, domain.Attributes{"groups": []any{"staff", "editors"}}, true},
+		{"contains missing", domain.PolicyCondition{Attribute: "subject.groups", Operator: domain.OperatorContains, Value: "editors"}, domain.Attributes{"groups": []string{"staff"}}, false},
+		{"exists", domain.PolicyCondition{Attribute: "subject.ward", Operator: domain.OperatorExists}, domain.Attributes{"ward": "nicu"}, true},
+		{"exists on empty value", domain.PolicyCondition{Attribute: "subject.ward", Operator: domain.OperatorExists}, domain.Attributes{"ward": ""}, false},
+		{"ref", domain.PolicyCondition{Attribute: "subject.ward", Operator: domain.OperatorEquals, Ref: "resource.ward"}, domain.Attributes{"ward": "nicu"}, true},
+		{"ref to missing attribute", domain.PolicyCondition{Attribute: "subject.ward", Operator: domain.OperatorEquals, Ref: "resource.unit"}, domain.Attributes{"ward": "nicu"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewPolicyEngine(domain.Policy{Rules: []domain.PolicyRule{{
+				ID:         "rule",
+				Effect:     domain.PolicyAllow,
+				Actions:    []domain.Action{domain.AnyAction},
+				Conditions: []domain.PolicyCondition{tt.condition},
+			}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			decision := engine.Evaluate(context.Background(), domain.AccessRequest{
+				Subject:  tt.subject,
+				Action:   domain.ActionVideoRead,
+				Resource: domain.Attributes{"ward": "nicu"},
+			})
+			if decision.Allowed != tt.want {
+				t.Errorf("Allowed = %v, want %v (%s)", decision.Allowed, tt.want, decision.Trace[0].Detail)
+			}
+		})
+	}
+}
+
+func TestNewPolicyEngineRejectsInvalidPolicies(t *testing.T) {
+	rule := func(edit func(*domain.PolicyRule)) domain.PolicyRule {
+		r := domain.PolicyRule{ID: "r", Effect: domain.PolicyAllow, Actions: []domain.Action{domain.ActionVideoRead}}
+		edit(&r)
+		return r
+	}
+	condition := func(c domain.PolicyCondition) domain.PolicyRule {
+		return rule(func(r *domain.PolicyRule) { r.Conditions = []domain.PolicyCondition{c} })
+	}
+
+	tests := map[string][]domain.PolicyRule{
+		"no rules":           nil,
+		"missing id":         {rule(func(r *domain.PolicyRule) { r.ID = "" })},
+		"duplicate id":       {rule(func(*domain.PolicyRule) {}), rule(func(*domain.PolicyRule) {})},
+		"unknown effect":     {rule(func(r *domain.PolicyRule) { r.Effect = "permit" })},
+		"no actions":         {rule(func(r *domain.PolicyRule) { r.Actions = nil })},
+		"bad attribute path": {condition(domain.PolicyCondition{Attribute: "role", Operator: domain.OperatorExists})},
+		"bad ref path":       {condition(domain.PolicyCondition{Attribute: "subject.id", Operator: domain.OperatorEquals, Ref: "id"})},
+		"exists with value":  {condition(domain.PolicyCondition{Attribute: "subject.id", Operator: domain.OperatorExists, Value: "x"})},
+		"in with value":      {condition(domain.PolicyCondition{Attribute: "subject.id", Operator: domain.OperatorIn, Value: "x"})},
+		"equals with values": {condition(domain.PolicyCondition{Attribute: "subject.id", Operator: domain.OperatorEquals, Values: []string{"x"}})},
+		"two operands":       {condition(domain.PolicyCondition{Attribute: "subject.id", Operator: domain.OperatorEquals, Value: "x", Ref: "resource.id"})},
+		"unknown operator":   {condition(domain.PolicyCondition{Attribute: "subject.id", Operator: "matches", Value: "x"})},
+	}
+	for name, rules := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewPolicyEngine(domain.Policy{Rules: rules}); !errors.Is(err, domain.ErrInvalidPolicy) {
+				t.Errorf("error = %v, want ErrInvalidPolicy", err)
+			}
+		})
+	}
+}
+
+func TestLoadPolicyRejectsUnknownFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	policy := `{"rules": [{"id": "r", "effect": "allow", "actions": ["videos:read"], "condition": []}]}`
+	if err := os.WriteFile(path, []byte(policy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadPolicy(path)
+	if !errors.Is(err, domain.ErrInvalidPolicy) || !strings.Contains(err.Error(), "condition") {
+		t.Errorf("LoadPolicy() error = %v, want ErrInvalidPolicy naming the field", err)
+	}
+}
+
+func TestPolicyEngineMayAllow(t *testing.T) {
+	engine, err := NewPolicyEngine(domain.Policy{Rules: []domain.PolicyRule{
+		{
+			ID:      "clinic-preview",
+			Effect:  domain.PolicyAllow,
+			Actions: []domain.Action{domain.ActionVideoPreview},
+			Roles:   []string{"NURSE"},
+			Conditions: []domain.PolicyCondition{
+				{Attribute: "resource.clinic_id", Operator: domain.OperatorEquals, Ref: "subject.clinic_id"},
+			},
+		},
+		{
+			ID:      "no-preview",
+			Effect:  domain.PolicyDeny,
+			Actions: []domain.Action{domain.ActionVideoPreview},
+			Roles:   []string{"PARENT"},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		role   string
+		action domain.Action
+		want   bool
+	}{
+		// Conditions on the resource are not known yet, so the rule may allow it
+		{"NURSE", domain.ActionVideoPreview, true},
+		{"NURSE", domain.ActionVideoRead, false},
+		// Deny rules never allow
+		{"PARENT", domain.ActionVideoPreview, false},
+		{"", domain.ActionVideoPreview, false},
+	}
+	for _, tt := range tests {
+		if got := engine.MayAllow(domain.Attributes{"role": tt.role}, tt.action); got != tt.want {
+			t.Errorf("MayAllow(%q, %q) = %v, want %v", tt.role, tt.action, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/metrics"
+)
+
+var scheduledTransitions = metrics.NewCounterVec(
+	"video_scheduled_transitions_total",
+	"Videos published or unpublished by the scheduler by transition and outcome.",
+	"transition", "outcome",
+)
+
+// PublishScheduler runs VideoService.ApplySchedule when the next video falls
+// due, and at least every interval so schedules set since the last run, possibly
+// on another instance, are picked up.
+type PublishScheduler struct {
+	videos   *VideoService
+	interval time.Duration
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func NewPublishScheduler(videos *VideoService, interval time.Duration) *PublishScheduler {
+	return &PublishScheduler{
+		videos:   videos,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start first applies whatever fell due while the service was down, then keeps
+// running in the background until Close.
+func (s *PublishScheduler) Start(ctx context.Context) {
+	go func() {
+		defer close(s.done)
+		for {
+			timer := time.NewTimer(s.runOnce(ctx))
+			select {
+			case <-s.stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+// Close stops the scheduler and waits for a run in progress to finish.
+func (s *PublishScheduler) Close(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runOnce applies the due schedules and returns how long to wait for the next run.
+func (s *PublishScheduler) runOnce(ctx context.Context) time.Duration {
+	at := now()
+	moved, err := s.videos.ApplySchedule(ctx, at)
+	if err != nil {
+		slog.Error("applying video schedules failed", "error", err)
+	}
+	if moved > 0 {
+		slog.Info("applied video schedules", "moved", moved)
+	}
+
+	next, err := s.videos.NextScheduledTime(ctx, at)
+	if err != nil {
+		slog.Warn("looking up next video schedule failed", "error", err)
+		return s.interval
+	}
+	if next == nil {
+		return s.interval
+	}
+	return min(s.interval, max(time.Until(*next), 0))
+}
@@ -2,33 +2,549 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
 
 	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
 	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/ports"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/logging"
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/tracing"
 )
 
+// VideoService authorizes every call against the access policy for the
+// domain.Principal in the context, so all inbound adapters are held to the same
+// rules. Reads of a single video and all writes are audited with the real
+// caller as actor, writes with snapshots of the video. Every write is also kept
+// as a numbered revision of the video, which can be restored, and authors are
+// notified when their video moves through the editorial workflow. Publishing
+// and unpublishing are announced as events.
 type VideoService struct {
-	repo ports.VideoRepository
+	repo      ports.VideoRepository
+	revisions ports.VideoRevisionRepository
+	policy    ports.PolicyEngine
+	audit     ports.AuditLog
+	notifier  ports.Notifier
+	events    ports.EventPublisher
 }
 
-func NewVideoService(repo ports.VideoRepository) *VideoService {
+// schedulerPrincipal is the actor recorded for changes made by ApplySchedule.
+var schedulerPrincipal = domain.Principal{ID: "scheduler", Role: "SYSTEM", AuthMethod: domain.AuthMethodInternal}
+
+var _ ports.VideoService = (*VideoService)(nil)
+
+func NewVideoService(repo ports.VideoRepository, revisions ports.VideoRevisionRepository, policy ports.PolicyEngine, audit ports.AuditLog, notifier ports.Notifier, events ports.EventPublisher) *VideoService {
 	return &VideoService{
-		repo: repo,
+		repo:      repo,
+		revisions: revisions,
+		policy:    policy,
+		audit:     audit,
+		notifier:  notifier,
+		events:    events,
 	}
 }
 
+// GetVideos returns only the videos the principal may read.
 func (s *VideoService) GetVideos(ctx context.Context) ([]domain.Video, error) {
-	return s.repo.GetVideos(ctx)
+	ctx, span := tracing.Start(ctx, "VideoService.GetVideos", tracing.KindInternal)
+	defer span.End()
+
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, domain.ErrUnauthenticated
+	}
+
+	// Readers who can never preview get only published videos from the
+	// repository instead of having every draft filtered out here
+	query := domain.VideoQuery{PublishedOnly: !s.mayPreview(principal)}
+	videos, err := s.repo.GetVideos(ctx, query)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	total := len(videos)
+	at := now()
+	videos = slices.DeleteFunc(videos, func(v domain.Video) bool {
+		return s.authorize(ctx, principal, readAction(v, at), v) != nil
+	})
+	if hidden := total - len(videos); hidden > 0 {
+		slog.DebugContext(ctx, "videos hidden by policy", "count", hidden)
+	}
+	return videos, nil
 }
 
 func (s *VideoService) GetVideoByID(ctx context.Context, id string) (*domain.Video, error) {
-	return s.repo.GetVideoByID(ctx, id)
+	ctx, span := tracing.Start(ctx, "VideoService.GetVideoByID", tracing.KindInternal)
+	defer span.End()
+
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, domain.ErrUnauthenticated
+	}
+
+	// Readers get not found for a video outside its schedule, as if it were
+	// unpublished already; editors still see it
+	get := s.repo.GetVideoByID
+	if !s.mayPreview(principal) {
+		get = s.repo.GetVisibleVideoByID
+	}
+	video, err := get(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := s.authorize(ctx, principal, readAction(*video, now()), *video); err != nil {
+		s.logDenied(ctx, principal, err)
+		s.recordAudit(ctx, domain.NewAuditEvent(principal, "video.read", id, domain.AuditFailure, map[string]string{"error": err.Error()}))
+		return nil, err
+	}
+	s.recordAudit(ctx, domain.NewAuditEvent(principal, "video.read", id, domain.AuditSuccess, nil))
+	return video, nil
 }
 
+// CreateVideo stores the video as a draft. It records the principal as the
+// video's creator before authorizing, so ownership rules can refer to
+// resource.created_by.
 func (s *VideoService) CreateVideo(ctx context.Context, video domain.Video) (*domain.Video, error) {
-	return s.repo.CreateVideo(ctx, video)
+	ctx, span := tracing.Start(ctx, "VideoService.CreateVideo", tracing.KindInternal)
+	defer span.End()
+
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, domain.ErrUnauthenticated
+	}
+	createdAt := now()
+	video.Status = domain.StatusDraft
+	video.CreatedBy = principal.ID
+	video.CreatedAt = createdAt
+	video.UpdatedBy = principal.ID
+	video.UpdatedAt = createdAt
+	video.Revision = 1
+
+	if err := s.authorizeAudited(ctx, principal, domain.ActionVideoCreate, "video.create", video); err != nil {
+		return nil, err
+	}
+	if err := video.Schedule.Validate(); err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.CreateVideo(ctx, video)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	s.recordRevision(ctx, domain.VideoRevision{Operation: domain.RevisionCreate}, domain.Video{}, *created)
+
+	event := domain.NewAuditEvent(principal, "video.create", created.ID, domain.AuditSuccess, nil)
+	event.After = domain.AuditSnapshot(created)
+	s.recordAudit(ctx, event)
+	return created, nil
+}
+
+// UpdateVideo changes the content of a draft. A published video has to be
+// unpublished first, which takes it offline until the change has been through
+// review and is published again.
+func (s *VideoService) UpdateVideo(ctx context.Context, id string, update domain.VideoUpdate) (*domain.Video, error) {
+	ctx, span := tracing.Start(ctx, "VideoService.UpdateVideo", tracing.KindInternal)
+	defer span.End()
+
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, domain.ErrUnauthenticated
+	}
+
+	current, err := s.repo.GetVideoByID(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	next := update.Apply(*current)
+	if err := s.authorizeAudited(ctx, principal, domain.ActionVideoUpdate, "video.update", *current, next); err != nil {
+		return nil, err
+	}
+	if current.Status != domain.StatusDraft {
+		return nil, domain.ErrVideoNotEditable
+	}
+	if err := next.Schedule.Validate(); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.save(ctx, principal, *current, next, domain.VideoRevision{Operation: domain.RevisionUpdate})
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return updated, nil
 }
 
 func (s *VideoService) DeleteVideo(ctx context.Context, id string) error {
-	return s.repo.DeleteVideo(ctx, id)
+	ctx, span := tracing.Start(ctx, "VideoService.DeleteVideo", tracing.KindInternal)
+	defer span.End()
+
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return domain.ErrUnauthenticated
+	}
+
+	video, err := s.repo.GetVideoByID(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	if err := s.authorizeAudited(ctx, principal, domain.ActionVideoDelete, "video.delete", *video); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteVideo(ctx, id); err != nil {
+		span.RecordError(err)
+		return err
+	}
+	// The last revision keeps the content that was deleted
+	deleted := *video
+	deleted.Revision++
+	deleted.UpdatedBy = principal.ID
+	deleted.UpdatedAt = now()
+	s.recordRevision(ctx, domain.VideoRevision{Operation: domain.RevisionDelete}, deleted, domain.Video{})
+
+	event := domain.NewAuditEvent(principal, "video.delete", id, domain.AuditSuccess, nil)
+	event.Before = domain.AuditSnapshot(video)
+	s.recordAudit(ctx, event)
+	return nil
+}
+
+// ListRevisions returns the revisions of a video, newest first. The history is
+// shown to whoever may update the video.
+func (s *VideoService) ListRevisions(ctx context.Context, id string) ([]domain.VideoRevision, error) {
+	ctx, span := tracing.Start(ctx, "VideoService.ListRevisions", tracing.KindInternal)
+	defer span.End()
+
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, domain.ErrUnauthenticated
+	}
+
+	video, err := s.repo.GetVideoByID(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := s.authorizeAudited(ctx, principal, domain.ActionVideoUpdate, "video.revisions.list", *video); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.revisions.ListRevisions(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	s.recordAudit(ctx, domain.NewAuditEvent(principal, "video.revisions.list", id, domain.AuditSuccess, nil))
+	return revisions, nil
+}
+
+// RestoreRevision writes the content of an earlier revision back to a draft as
+// a new revision, so the restore itself stays in the history. The status is
+// left as it is; like UpdateVideo, it needs a draft.
+func (s *VideoService) RestoreRevision(ctx context.Context, id string, revision int) (*domain.Video, error) {
+	ctx, span := tracing.Start(ctx, "VideoService.RestoreRevision", tracing.KindInternal)
+	defer span.End()
+
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, domain.ErrUnauthenticated
+	}
+
+	current, err := s.repo.GetVideoByID(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	// Checked before reading the revision, so its existence is not disclosed
+	if err := s.authorizeAudited(ctx, principal, domain.ActionVideoUpdate, "video.restore", *current); err != nil {
+		return nil, err
+	}
+
+	target, err := s.revisions.GetRevision(ctx, id, revision)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	next := domain.UpdateFrom(target.Video).Apply(*current)
+	if err := s.authorizeAudited(ctx, principal, domain.ActionVideoUpdate, "video.restore", next); err != nil {
+		return nil, err
+	}
+	if current.Status != domain.StatusDraft {
+		return nil, domain.ErrVideoNotEditable
+	}
+
+	restored, err := s.save(ctx, principal, *current, next, domain.VideoRevision{Operation: domain.RevisionRestore, RestoredFrom: revision})
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return restored, nil
+}
+
+// TransitionVideo moves a video through the editorial workflow.
+func (s *VideoService) TransitionVideo(ctx context.Context, id, transition, comment string) (*domain.Video, error) {
+	ctx, span := tracing.Start(ctx, "VideoService.TransitionVideo", tracing.KindInternal)
+	defer span.End()
+
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, domain.ErrUnauthenticated
+	}
+
+	t, err := domain.FindTransition(transition)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.repo.GetVideoByID(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := s.authorizeAudited(ctx, principal, t.Action, "video."+t.Name, *current); err != nil {
+		return nil, err
+	}
+	if err := t.Check(*current, comment, now()); err != nil {
+		return nil, err
+	}
+
+	moved, err := s.transition(ctx, principal, *current, t, comment)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return moved, nil
+}
+
+// ApplySchedule publishes and unpublishes the videos whose scheduled time has
+// come by at, and returns how many it moved. It is run by the PublishScheduler
+// on every instance; a video another instance moved first is skipped.
+func (s *VideoService) ApplySchedule(ctx context.Context, at time.Time) (int, error) {
+	ctx, span := tracing.Start(ctx, "VideoService.ApplySchedule", tracing.KindInternal)
+	defer span.End()
+
+	due, err := s.repo.GetDueVideos(ctx, at)
+	if err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+
+	moved := 0
+	var errs []error
+	for _, video := range due {
+		t, ok := domain.DueTransition(video, at)
+		if !ok {
+			continue
+		}
+		_, err := s.transition(ctx, schedulerPrincipal, video, t, "")
+		switch {
+		case err == nil:
+			moved++
+			scheduledTransitions.WithLabelValues(t.Name, "success").Inc()
+		case errors.Is(err, domain.ErrVideoConflict), errors.Is(err, domain.ErrVideoNotFound):
+			scheduledTransitions.WithLabelValues(t.Name, "skipped").Inc()
+		default:
+			scheduledTransitions.WithLabelValues(t.Name, "error").Inc()
+			errs = append(errs, fmt.Errorf("%s video %s: %w", t.Name, video.ID, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		span.RecordError(err)
+		return moved, err
+	}
+	return moved, nil
+}
+
+// NextScheduledTime returns when ApplySchedule next has work to do, or nil.
+func (s *VideoService) NextScheduledTime(ctx context.Context, after time.Time) (*time.Time, error) {
+	return s.repo.NextScheduledTime(ctx, after)
+}
+
+// transition applies t to current once the caller has checked it, notifies the
+// author unless they made the change, and announces publishing and unpublishing.
+func (s *VideoService) transition(ctx context.Context, principal domain.Principal, current domain.Video, t domain.VideoTransition, comment string) (*domain.Video, error) {
+	next := current
+	next.Status = t.To
+	moved, err := s.save(ctx, principal, current, next, domain.VideoRevision{Operation: t.Name, Comment: comment})
+	if err != nil {
+		return nil, err
+	}
+
+	if moved.CreatedBy != "" && moved.CreatedBy != principal.ID {
+		s.notify(ctx, domain.Notification{
+			Recipient:  moved.CreatedBy,
+			Type:       domain.NotificationVideoStatusChanged,
+			VideoID:    moved.ID,
+			Transition: t.Name,
+			From:       current.Status,
+			To:         moved.Status,
+			Comment:    comment,
+			Actor:      principal.ID,
+			Time:       moved.UpdatedAt,
+		})
+	}
+
+	var eventType string
+	switch {
+	case t.To == domain.StatusPublished:
+		eventType = domain.VideoEventPublished
+	case t.From == domain.StatusPublished:
+		eventType = domain.VideoEventUnpublished
+	default:
+		return moved, nil
+	}
+	event := domain.VideoEvent{
+		Type:      eventType,
+		VideoID:   moved.ID,
+		Revision:  moved.Revision,
+		Scheduled: principal.AuthMethod == domain.AuthMethodInternal,
+		Actor:     principal.Actor().ID,
+		Time:      moved.UpdatedAt,
+	}
+	if err := s.events.Publish(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "failed to publish video event", "video_id", event.VideoID, "type", event.Type, "error", err)
+	}
+	return moved, nil
+}
+
+// authorizeAudited authorizes action on each of videos, and logs and audits a
+// denial under auditAction.
+func (s *VideoService) authorizeAudited(ctx context.Context, principal domain.Principal, action domain.Action, auditAction string, videos ...domain.Video) error {
+	for _, video := range videos {
+		if err := s.authorize(ctx, principal, action, video); err != nil {
+			s.logDenied(ctx, principal, err)
+			s.recordAudit(ctx, domain.NewAuditEvent(principal, auditAction, video.ID, domain.AuditFailure, map[string]string{"error": err.Error()}))
+			return err
+		}
+	}
+	return nil
+}
+
+// save writes next over current as the next revision, described by revision,
+// once the caller has authorized it. A write that changes nothing returns
+// current without recording a revision.
+func (s *VideoService) save(ctx context.Context, principal domain.Principal, current, next domain.Video, revision domain.VideoRevision) (*domain.Video, error) {
+	if len(domain.DiffVideos(current, next)) == 0 {
+		return &current, nil
+	}
+
+	next.Revision = current.Revision + 1
+	next.UpdatedBy = principal.ID
+	next.UpdatedAt = now()
+	if err := s.repo.UpdateVideo(ctx, next, current.Revision); err != nil {
+		return nil, err
+	}
+	s.recordRevision(ctx, revision, current, next)
+
+	details := map[string]string{"revision": strconv.Itoa(next.Revision)}
+	if revision.RestoredFrom > 0 {
+		details["restored_from"] = strconv.Itoa(revision.RestoredFrom)
+	}
+	if revision.Comment != "" {
+		details["comment"] = revision.Comment
+	}
+	event := domain.NewAuditEvent(principal, "video."+revision.Operation, current.ID, domain.AuditSuccess, details)
+	event.Before = domain.AuditSnapshot(current)
+	event.After = domain.AuditSnapshot(next)
+	s.recordAudit(ctx, event)
+	return &next, nil
+}
+
+// recordRevision completes revision with the changes from before to after and
+// stores it once the video itself is written. A delete passes the zero Video as
+// after, mirroring a create, and keeps before as the revision's video. A failure
+// is logged but not returned, as the write already happened; the audit event
+// for it still holds both snapshots.
+func (s *VideoService) recordRevision(ctx context.Context, revision domain.VideoRevision, before, after domain.Video) {
+	video := after
+	if revision.Operation == domain.RevisionDelete {
+		video = before
+	}
+	revision.VideoID = video.ID
+	revision.Revision = video.Revision
+	revision.Author = video.UpdatedBy
+	revision.CreatedAt = video.UpdatedAt
+	revision.Changes = domain.DiffVideos(before, after)
+	revision.Video = video
+	if err := s.revisions.AddRevision(ctx, revision); err != nil {
+		slog.ErrorContext(ctx, "failed to record video revision", "video_id", video.ID, "revision", video.Revision, "error", err)
+	}
+}
+
+// notify logs but does not return a failure to deliver, as the change is made.
+func (s *VideoService) notify(ctx context.Context, notification domain.Notification) {
+	if err := s.notifier.Notify(ctx, notification); err != nil {
+		slog.WarnContext(ctx, "failed to notify author", "video_id", notification.VideoID, "recipient", notification.Recipient, "error", err)
+	}
+}
+
+// readAction is the action needed to read video at the given time: videos that
+// are unpublished or outside their schedule need videos:preview, so a rule
+// granting videos:read never exposes a draft or expired content.
+func readAction(video domain.Video, at time.Time) domain.Action {
+	if video.Status == domain.StatusPublished && video.Schedule.Open(at) {
+		return domain.ActionVideoRead
+	}
+	return domain.ActionVideoPreview
+}
+
+// authorize returns an *domain.AccessDeniedError when principal may not perform
+// action on video. Impersonation is read-only, and API keys are limited to the
+// actions named by their scopes, before the policy is consulted.
+func (s *VideoService) authorize(ctx context.Context, principal domain.Principal, action domain.Action, video domain.Video) error {
+	if principal.Impersonator != nil && action != domain.ActionVideoRead && action != domain.ActionVideoPreview {
+		return &domain.AccessDeniedError{
+			Action:   action,
+			Decision: domain.Decision{Reason: "write operations are not allowed while impersonating"},
+		}
+	}
+	if !principal.HasScope(action) {
+		return &domain.AccessDeniedError{
+			Action:   action,
+			Decision: domain.Decision{Reason: "api key lacks scope " + string(action)},
+		}
+	}
+
+	decision := s.policy.Evaluate(ctx, domain.AccessRequest{
+		Subject:  principal.AccessAttributes(),
+		Action:   action,
+		Resource: video.Attributes(),
+	})
+	if !decision.Allowed {
+		return &domain.AccessDeniedError{Action: action, Decision: decision}
+	}
+	return nil
+}
+
+// mayPreview reports whether principal could be allowed to read unpublished
+// videos. It passes the checks authorize makes before the policy, then asks the
+// policy whether any rule could allow it.
+func (s *VideoService) mayPreview(principal domain.Principal) bool {
+	if !principal.HasScope(domain.ActionVideoPreview) {
+		return false
+	}
+	return s.policy.MayAllow(principal.AccessAttributes(), domain.ActionVideoPreview)
+}
+
+func (s *VideoService) logDenied(ctx context.Context, principal domain.Principal, err error) {
+	slog.InfoContext(ctx, "access denied by policy", "principal", principal.ID, "role", principal.Role, "actor", principal.Actor().ID, "error", err)
+}
+
+// recordAudit logs but does not return a failure to record, like the handlers do.
+func (s *VideoService) recordAudit(ctx context.Context, event domain.AuditEvent) {
+	event.RequestID = logging.RequestID(ctx)
+	if err := s.audit.Record(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "failed to record audit event", "action", event.Action, "error", err)
+	}
+}
+
+// now is truncated to what MongoDB stores, so returned videos match later reads.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
 }
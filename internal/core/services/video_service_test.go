@@ -0,0 +1,500 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AchilleasB/baby-kliniek/media-service/internal/core/domain"
+)
+
+// memoryVideos is a VideoRepository backed by a map. Like MongoRepository, it
+// checks the expected revision on update.
+type memoryVideos struct {
+	mu      sync.Mutex
+	videos  map[string]domain.Video
+	queries []domain.VideoQuery
+}
+
+func newMemoryVideos(videos ...domain.Video) *memoryVideos {
+	r := &memoryVideos{videos: make(map[string]domain.Video)}
+	for _, v := range videos {
+		r.videos[v.ID] = v
+	}
+	return r
+}
+
+func (r *memoryVideos) GetVideos(ctx context.Context, query domain.VideoQuery) ([]domain.Video, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queries = append(r.queries, query)
+	var videos []domain.Video
+	for _, v := range r.videos {
+		if !query.PublishedOnly || visible(v) {
+			videos = append(videos, v)
+		}
+	}
+	slices.SortFunc(videos, func(a, b domain.Video) int { return cmp.Compare(a.ID, b.ID) })
+	return videos, nil
+}
+
+func (r *memoryVideos) GetVideoByID(ctx context.Context, id string) (*domain.Video, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.videos[id]
+	if !ok {
+		return nil, domain.ErrVideoNotFound
+	}
+	return &v, nil
+}
+
+func (r *memoryVideos) GetVisibleVideoByID(ctx context.Context, id string) (*domain.Video, error) {
+	v, err := r.GetVideoByID(ctx, id)
+	if err == nil && v.Status == domain.StatusPublished && !v.Schedule.Open(time.Now()) {
+		return nil, domain.ErrVideoNotFound
+	}
+	return v, err
+}
+
+// visible mirrors the repository's reader filter.
+func visible(v domain.Video) bool {
+	return v.Status == domain.StatusPublished && v.Schedule.Open(time.Now())
+}
+
+func (r *memoryVideos) CreateVideo(ctx context.Context, video domain.Video) (*domain.Video, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.videos[video.ID] = video
+	return &video, nil
+}
+
+func (r *memoryVideos) UpdateVideo(ctx context.Context, video domain.Video, expectedRevision int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.videos[video.ID]
+	if !ok {
+		return domain.ErrVideoNotFound
+	}
+	if current.Revision != expectedRevision {
+		return domain.ErrVideoConflict
+	}
+	r.videos[video.ID] = video
+	return nil
+}
+
+func (r *memoryVideos) DeleteVideo(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.videos[id]; !ok {
+		return domain.ErrVideoNotFound
+	}
+	delete(r.videos, id)
+	return nil
+}
+
+func (r *memoryVideos) GetDueVideos(ctx context.Context, now time.Time) ([]domain.Video, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due []domain.Video
+	for _, v := range r.videos {
+		if _, ok := domain.DueTransition(v, now); ok {
+			due = append(due, v)
+		}
+	}
+	return due, nil
+}
+
+func (r *memoryVideos) NextScheduledTime(ctx context.Context, now time.Time) (*time.Time, error) {
+	return nil, nil
+}
+
+type memoryRevisions struct {
+	mu        sync.Mutex
+	revisions []domain.VideoRevision
+}
+
+func (r *memoryRevisions) AddRevision(ctx context.Context, revision domain.VideoRevision) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.revisions = append(r.revisions, revision)
+	return nil
+}
+
+func (r *memoryRevisions) ListRevisions(ctx context.Context, videoID string) ([]domain.VideoRevision, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var list []domain.VideoRevision
+	for _, rev := range slices.Backward(r.revisions) {
+		if rev.VideoID == videoID {
+			list = append(list, rev)
+		}
+	}
+	return list, nil
+}
+
+func (r *memoryRevisions) GetRevision(ctx context.Context, videoID string, revision int) (*domain.VideoRevision, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rev := range r.revisions {
+		if rev.VideoID == videoID && rev.Revision == revision {
+			return &rev, nil
+		}
+	}
+	return nil, domain.ErrRevisionNotFound
+}
+
+type discardAudit struct{}
+
+func (discardAudit) Record(ctx context.Context, event domain.AuditEvent) error { return nil }
+
+type discardNotifier struct{}
+
+func (discardNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	return nil
+}
+
+type recordedEvents struct {
+	mu     sync.Mutex
+	events []domain.VideoEvent
+}
+
+func (e *recordedEvents) Publish(ctx context.Context, event domain.VideoEvent) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, event)
+	return nil
+}
+
+type videoServiceFixture struct {
+	service   *VideoService
+	videos    *memoryVideos
+	revisions *memoryRevisions
+	events    *recordedEvents
+}
+
+func newVideoServiceFixture(t *testing.T, videos ...domain.Video) videoServiceFixture {
+	t.Helper()
+	f := videoServiceFixture{
+		videos:    newMemoryVideos(videos...),
+		revisions: &memoryRevisions{},
+		events:    &recordedEvents{},
+	}
+	f.service = NewVideoService(f.videos, f.revisions, newDefaultEngine(t), discardAudit{}, discardNotifier{}, f.events)
+	return f
+}
+
+func asPrincipal(id, role string) context.Context {
+	return domain.ContextWithPrincipal(context.Background(), domain.Principal{ID: id, Role: role, AuthMethod: domain.AuthMethodToken})
+}
+
+func TestGetVideosQuery(t *testing.T) {
+	videos := []domain.Video{
+		{ID: "approved", Status: domain.StatusApproved},
+		{ID: "draft", Status: domain.StatusDraft},
+		{ID: "published", Status: domain.StatusPublished},
+	}
+	apiKey := domain.Principal{ID: "apikey:1", Role: "API_KEY", AuthMethod: domain.AuthMethodAPIKey, Scopes: []domain.APIKeyScope{domain.ScopeVideosRead}}
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		publishedOnly bool
+		want          []string
+	}{
+		{"parent", asPrincipal("parent-1", "PARENT"), true, []string{"published"}},
+		{"api key", domain.ContextWithPrincipal(context.Background(), apiKey), true, []string{"published"}},
+		{"unknown role", asPrincipal("nurse-1", "NURSE"), true, nil},
+		{"reviewer", asPrincipal("reviewer-1", "REVIEWER"), false, []string{"approved", "draft", "published"}},
+		{"admin", asPrincipal("admin-1", "ADMIN"), false, []string{"approved", "draft", "published"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newVideoServiceFixture(t, videos...)
+
+			got, err := f.service.GetVideos(tt.ctx)
+			if err != nil {
+				t.Fatalf("GetVideos() error = %v", err)
+			}
+			if want := []domain.VideoQuery{{PublishedOnly: tt.publishedOnly}}; !slices.Equal(f.videos.queries, want) {
+				t.Errorf("repository queried with %+v, want %+v", f.videos.queries, want)
+			}
+			var ids []string
+			for _, v := range got {
+				ids = append(ids, v.ID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("GetVideos() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteVideoRevision(t *testing.T) {
+	video := domain.Video{
+		ID:          "video-1",
+		URL:         "https://cdn.example/v1.mp4",
+		ContentType: "SLEEPING",
+		Description: "Safe sleep",
+		Status:      domain.StatusDraft,
+		Revision:    3,
+		CreatedBy:   "author-1",
+	}
+	f := newVideoServiceFixture(t, video)
+
+	if err := f.service.DeleteVideo(asPrincipal("admin-1", "ADMIN"), video.ID); err != nil {
+		t.Fatalf("DeleteVideo() error = %v", err)
+	}
+	if len(f.revisions.revisions) != 1 {
+		t.Fatalf("recorded %d revisions, want 1", len(f.revisions.revisions))
+	}
+	rev := f.revisions.revisions[0]
+	if rev.Operation != domain.RevisionDelete || rev.Revision != 4 || rev.Author != "admin-1" {
+		t.Errorf("revision = %s %d by %q, want delete 4 by admin-1", rev.Operation, rev.Revision, rev.Author)
+	}
+	if rev.Video.URL != video.URL || rev.Video.Description != video.Description {
+		t.Errorf("revision keeps %+v, want the deleted content", rev.Video)
+	}
+	want := []domain.FieldChange{
+		{Field: "url", From: video.URL},
+		{Field: "content_type", From: "SLEEPING"},
+		{Field: "description", From: video.Description},
+		{Field: "status", From: string(domain.StatusDraft)},
+	}
+	if !slices.Equal(rev.Changes, want) {
+		t.Errorf("changes = %+v, want %+v", rev.Changes, want)
+	}
+}
+
+func TestTransitionVideoRoles(t *testing.T) {
+	apiKey := domain.ContextWithPrincipal(context.Background(), domain.Principal{
+		ID: "apikey:1", Role: "API_KEY", AuthMethod: domain.AuthMethodAPIKey, Scopes: []domain.APIKeyScope{domain.ScopeVideosRead},
+	})
+	callers := map[string]context.Context{
+		"admin":           asPrincipal("admin-1", "ADMIN"),
+		"admin author":    asPrincipal("author-1", "ADMIN"),
+		"reviewer":        asPrincipal("reviewer-1", "REVIEWER"),
+		"reviewer author": asPrincipal("author-1", "REVIEWER"),
+		"parent":          asPrincipal("parent-1", "PARENT"),
+		"api key":         apiKey,
+	}
+	// Callers allowed each transition under the default policy; no-self-review
+	// keeps authors, admins included, from approving or rejecting their own videos
+	allowed := map[string][]string{
+		"submit":    {"admin", "admin author"},
+		"approve":   {"admin", "reviewer"},
+		"reject":    {"admin", "reviewer"},
+		"publish":   {"admin", "admin author"},
+		"unpublish": {"admin", "admin author"},
+	}
+
+	for _, tr := range domain.VideoTransitions {
+		for caller, ctx := range callers {
+			t.Run(tr.Name+"/"+caller, func(t *testing.T) {
+				video := domain.Video{ID: "video-1", Status: tr.From, CreatedBy: "author-1", Revision: 1}
+				f := newVideoServiceFixture(t, video)
+
+				moved, err := f.service.TransitionVideo(ctx, video.ID, tr.Name, "see comments")
+				if !slices.Contains(allowed[tr.Name], caller) {
+					if !errors.Is(err, domain.ErrForbidden) {
+						t.Errorf("error = %v, want ErrForbidden", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("error = %v", err)
+				}
+				if moved.Status != tr.To || moved.Revision != 2 {
+					t.Errorf("video is %s at revision %d, want %s at 2", moved.Status, moved.Revision, tr.To)
+				}
+			})
+		}
+	}
+}
+
+func TestTransitionVideoFromWrongStatus(t *testing.T) {
+	for _, tr := range domain.VideoTransitions {
+		for _, status := range []domain.VideoStatus{domain.StatusDraft, domain.StatusInReview, domain.StatusApproved, domain.StatusPublished} {
+			if status == tr.From {
+				continue
+			}
+			t.Run(tr.Name+"/"+string(status), func(t *testing.T) {
+				f := newVideoServiceFixture(t, domain.Video{ID: "video-1", Status: status, CreatedBy: "author-1"})
+
+				_, err := f.service.TransitionVideo(asPrincipal("admin-1", "ADMIN"), "video-1", tr.Name, "see comments")
+				if !errors.Is(err, domain.ErrInvalidTransition) {
+					t.Errorf("error = %v, want ErrInvalidTransition", err)
+				}
+				if v, _ := f.videos.GetVideoByID(context.Background(), "video-1"); v.Status != status {
+					t.Errorf("status changed to %s", v.Status)
+				}
+			})
+		}
+	}
+}
+
+func TestTransitionVideoEvents(t *testing.T) {
+	tests := []struct {
+		transition string
+		from       domain.VideoStatus
+		want       string
+	}{
+		{"publish", domain.StatusApproved, domain.VideoEventPublished},
+		{"unpublish", domain.StatusPublished, domain.VideoEventUnpublished},
+		{"submit", domain.StatusDraft, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.transition, func(t *testing.T) {
+			f := newVideoServiceFixture(t, domain.Video{ID: "video-1", Status: tt.from, Revision: 1})
+
+			if _, err := f.service.TransitionVideo(asPrincipal("admin-1", "ADMIN"), "video-1", tt.transition, ""); err != nil {
+				t.Fatal(err)
+			}
+			var types []string
+			for _, e := range f.events.events {
+				types = append(types, e.Type)
+				if e.Scheduled || e.Actor != "admin-1" || e.Revision != 2 {
+					t.Errorf("event = %+v, want by admin-1 for revision 2, not scheduled", e)
+				}
+			}
+			var want []string
+			if tt.want != "" {
+				want = []string{tt.want}
+			}
+			if !slices.Equal(types, want) {
+				t.Errorf("events = %v, want %v", types, want)
+			}
+		})
+	}
+}
+
+func TestOnlyDraftsAreEditable(t *testing.T) {
+	description := "Updated description"
+	update := domain.VideoUpdate{Description: &description}
+
+	for _, status := range []domain.VideoStatus{domain.StatusDraft, domain.StatusInReview, domain.StatusApproved, domain.StatusPublished} {
+		t.Run(string(status), func(t *testing.T) {
+			video := domain.Video{ID: "video-1", Status: status, Description: "Original", Revision: 1}
+			f := newVideoServiceFixture(t, video)
+			f.revisions.revisions = append(f.revisions.revisions, domain.VideoRevision{
+				VideoID: video.ID, Revision: 1, Operation: domain.RevisionCreate, Video: domain.Video{ID: video.ID, Description: "First draft"},
+			})
+			ctx := asPrincipal("admin-1", "ADMIN")
+
+			_, updateErr := f.service.UpdateVideo(ctx, video.ID, update)
+			_, restoreErr := f.service.RestoreRevision(ctx, video.ID, 1)
+
+			if status == domain.StatusDraft {
+				if updateErr != nil || restoreErr != nil {
+					t.Errorf("draft: update error = %v, restore error = %v, want nil", updateErr, restoreErr)
+				}
+				return
+			}
+			if !errors.Is(updateErr, domain.ErrVideoNotEditable) || !errors.Is(restoreErr, domain.ErrVideoNotEditable) {
+				t.Errorf("update error = %v, restore error = %v, want ErrVideoNotEditable", updateErr, restoreErr)
+			}
+			if v, _ := f.videos.GetVideoByID(context.Background(), video.ID); v.Description != "Original" || v.Revision != 1 {
+				t.Errorf("video changed to %+v", v)
+			}
+		})
+	}
+}
+
+func TestEditPublishedVideoAfterUnpublish(t *testing.T) {
+	f := newVideoServiceFixture(t, domain.Video{ID: "video-1", Status: domain.StatusPublished, Description: "Original", Revision: 1})
+	ctx := asPrincipal("admin-1", "ADMIN")
+	description := "Corrected dosage"
+
+	if _, err := f.service.UpdateVideo(ctx, "video-1", domain.VideoUpdate{Description: &description}); !errors.Is(err, domain.ErrVideoNotEditable) {
+		t.Fatalf("update while published: error = %v, want ErrVideoNotEditable", err)
+	}
+	if _, err := f.service.TransitionVideo(ctx, "video-1", "unpublish", ""); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := f.service.UpdateVideo(ctx, "video-1", domain.VideoUpdate{Description: &description})
+	if err != nil {
+		t.Fatalf("update after unpublish: error = %v", err)
+	}
+	if updated.Status != domain.StatusDraft || updated.Description != description {
+		t.Errorf("video = %s %q, want an edited draft", updated.Status, updated.Description)
+	}
+}
+
+func TestScheduleVisibility(t *testing.T) {
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	videos := []domain.Video{
+		{ID: "expired", Status: domain.StatusPublished, Revision: 1, Schedule: domain.VideoSchedule{UnpublishAt: &past}},
+		{ID: "early", Status: domain.StatusPublished, Revision: 1, Schedule: domain.VideoSchedule{PublishAt: &future}},
+		{ID: "live", Status: domain.StatusPublished, Revision: 1, Schedule: domain.VideoSchedule{PublishAt: &past, UnpublishAt: &future}},
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		visible []string
+	}{
+		{"parent", asPrincipal("parent-1", "PARENT"), []string{"live"}},
+		{"reviewer", asPrincipal("reviewer-1", "REVIEWER"), []string{"early", "expired", "live"}},
+		{"admin", asPrincipal("admin-1", "ADMIN"), []string{"early", "expired", "live"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newVideoServiceFixture(t, videos...)
+
+			list, err := f.service.GetVideos(tt.ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, v := range list {
+				ids = append(ids, v.ID)
+			}
+			if !slices.Equal(ids, tt.visible) {
+				t.Errorf("GetVideos() = %v, want %v", ids, tt.visible)
+			}
+
+			for _, v := range videos {
+				_, err := f.service.GetVideoByID(tt.ctx, v.ID)
+				if slices.Contains(tt.visible, v.ID) && err != nil {
+					t.Errorf("GetVideoByID(%q) error = %v, want the video", v.ID, err)
+				}
+				if !slices.Contains(tt.visible, v.ID) && !errors.Is(err, domain.ErrVideoNotFound) {
+					t.Errorf("GetVideoByID(%q) error = %v, want ErrVideoNotFound", v.ID, err)
+				}
+			}
+		})
+	}
+}
+
+func TestExpiredVideoCanBeUnpublished(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	expired := domain.Video{ID: "video-1", Status: domain.StatusPublished, Revision: 1, Schedule: domain.VideoSchedule{UnpublishAt: &past}}
+
+	t.Run("by an admin", func(t *testing.T) {
+		f := newVideoServiceFixture(t, expired)
+		moved, err := f.service.TransitionVideo(asPrincipal("admin-1", "ADMIN"), expired.ID, "unpublish", "")
+		if err != nil {
+			t.Fatalf("TransitionVideo() error = %v", err)
+		}
+		if moved.Status != domain.StatusDraft {
+			t.Errorf("status = %s, want DRAFT", moved.Status)
+		}
+	})
+
+	t.Run("by the scheduler", func(t *testing.T) {
+		f := newVideoServiceFixture(t, expired)
+		moved, err := f.service.ApplySchedule(context.Background(), time.Now())
+		if err != nil || moved != 1 {
+			t.Fatalf("ApplySchedule() = %d, %v, want 1 video moved", moved, err)
+		}
+		if v, _ := f.videos.GetVideoByID(context.Background(), expired.ID); v.Status != domain.StatusDraft {
+			t.Errorf("status = %s, want DRAFT", v.Status)
+		}
+		if len(f.events.events) != 1 || !f.events.events[0].Scheduled || f.events.events[0].Actor != "scheduler" {
+			t.Errorf("events = %+v, want one scheduled unpublish by the scheduler", f.events.events)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func MongoCheck(client *mongo.Client) CheckFunc {
+	return func(ctx context.Context) (string, error) {
+		if err := client.Ping(ctx, nil); err != nil {
+			return "Cannot connect to database", err
+		}
+		return "", nil
+	}
+}
+
+func RedisCheck(client *redis.Client) CheckFunc {
+	return func(ctx context.Context) (string, error) {
+		if err := client.Ping(ctx).Err(); err != nil {
+			return "Cannot connect to redis", err
+		}
+		return "", nil
+	}
+}
+
+// HTTPCheck probes an HTTP dependency such as blob storage; any 2xx/3xx is healthy.
+func HTTPCheck(url string) CheckFunc {
+	return func(ctx context.Context) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "Cannot reach " + url, err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Sprintf("%s responded with %d", url, resp.StatusCode), fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return "", nil
+	}
+}
+
+// MemoryCheck is informational and never fails.
+func MemoryCheck() CheckFunc {
+	return func(ctx context.Context) (string, error) {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		return fmt.Sprintf("Allocated: %d MB", m.Alloc/1024/1024), nil
+	}
+}
@@ -0,0 +1,59 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"ok", http.StatusOK, false},
+		{"redirect", http.StatusNotModified, false},
+		{"not found", http.StatusNotFound, true},
+		{"server error", http.StatusServiceUnavailable, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			message, err := HTTPCheck(srv.URL)(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(message, "responded with") {
+				t.Errorf("message = %q, want the status in it", message)
+			}
+		})
+	}
+}
+
+func TestHTTPCheckUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	message, err := HTTPCheck(url)(context.Background())
+	if err == nil {
+		t.Fatal("unreachable URL reported healthy")
+	}
+	if message != "Cannot reach "+url {
+		t.Errorf("message = %q", message)
+	}
+}
+
+func TestMemoryCheck(t *testing.T) {
+	message, err := MemoryCheck()(context.Background())
+	if err != nil || !strings.HasPrefix(message, "Allocated: ") {
+		t.Errorf("MemoryCheck() = %q, %v", message, err)
+	}
+}
@@ -0,0 +1,208 @@
+// Package health runs dependency checks in the background and serves their cached
+// results to the probe endpoints, so probes never block on a slow dependency.
+package health
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusUp       Status = "UP"
+	StatusDown     Status = "DOWN"
+	StatusDegraded Status = "DEGRADED"
+)
+
+// CheckFunc probes a dependency. A non-nil error marks it DOWN; the message is
+// reported either way (e.g. memory usage, key fingerprint).
+type CheckFunc func(ctx context.Context) (message string, err error)
+
+// Result is the cached outcome of the latest run of a check.
+type Result struct {
+	Status      Status
+	Message     string
+	Critical    bool
+	LastChecked time.Time
+	Duration    time.Duration
+}
+
+type check struct {
+	name     string
+	critical bool
+	timeout  time.Duration
+	fn       CheckFunc
+}
+
+// Registry owns the registered checks and their latest results.
+type Registry struct {
+	interval time.Duration
+	checks   []check
+
+	mu      sync.RWMutex
+	results map[string]Result
+
+	started  atomic.Bool
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func NewRegistry(interval time.Duration) *Registry {
+	return &Registry{
+		interval: interval,
+		results:  make(map[string]Result),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Register adds a check. Critical checks gate readiness and startup; non-critical
+// ones only degrade the aggregated status. Register must be called before Start.
+func (r *Registry) Register(name string, critical bool, timeout time.Duration, fn CheckFunc) {
+	r.checks = append(r.checks, check{name: name, critical: critical, timeout: timeout, fn: fn})
+
+	r.mu.Lock()
+	r.results[name] = Result{Status: StatusDown, Message: "Not checked yet", Critical: critical}
+	r.mu.Unlock()
+}
+
+// Start runs every check immediately and then refreshes them on the configured interval.
+func (r *Registry) Start() {
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			r.refresh()
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop halts the background refresh and waits for an in-flight round to finish.
+func (r *Registry) Stop(ctx context.Context) error {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Results returns a snapshot of the latest result of every check.
+func (r *Registry) Results() map[string]Result {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snapshot := make(map[string]Result, len(r.results))
+	for name, result := range r.results {
+		snapshot[name] = result
+	}
+	return snapshot
+}
+
+// Status aggregates the results: DOWN if any critical check fails, DEGRADED if only
+// non-critical checks fail, UP otherwise.
+func (r *Registry) Status() Status {
+	status := StatusUp
+	for _, result := range r.Results() {
+		if result.Status == StatusUp {
+			continue
+		}
+		if result.Critical {
+			return StatusDown
+		}
+		status = StatusDegraded
+	}
+	return status
+}
+
+// Started reports whether every critical check has passed at least once.
+func (r *Registry) Started() bool {
+	return r.started.Load()
+}
+
+func (r *Registry) refresh() {
+	var wg sync.WaitGroup
+	for _, c := range r.checks {
+		wg.Add(1)
+		go func(c check) {
+			defer wg.Done()
+			result := r.run(c)
+
+			r.mu.Lock()
+			previous := r.results[c.name]
+			r.results[c.name] = result
+			r.mu.Unlock()
+
+			if previous.Status != result.Status && !previous.LastChecked.IsZero() {
+				slog.Warn("health check changed status", "check", c.name, "from", previous.Status, "to", result.Status, "message", result.Message)
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	if !r.started.Load() && r.criticalUp() {
+		r.started.Store(true)
+		slog.Info("all critical health checks passed, startup complete")
+	}
+}
+
+func (r *Registry) run(c check) Result {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	type outcome struct {
+		message string
+		err     error
+	}
+	ch := make(chan outcome, 1)
+	go func() {
+		message, err := c.fn(ctx)
+		ch <- outcome{message, err}
+	}()
+
+	result := Result{Critical: c.critical, LastChecked: start}
+	select {
+	case o := <-ch:
+		result.Status = StatusUp
+		result.Message = o.message
+		if o.err != nil {
+			result.Status = StatusDown
+			if result.Message == "" {
+				result.Message = o.err.Error()
+			}
+		}
+	case <-ctx.Done():
+		result.Status = StatusDown
+		result.Message = "Check timed out after " + c.timeout.String()
+	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+func (r *Registry) criticalUp() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, result := range r.results {
+		if result.Critical && result.Status != StatusUp {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,256 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func up(context.Context) (string, error) { return "", nil }
+
+func down(context.Context) (string, error) { return "", errors.New("connection refused") }
+
+// toggle returns a check that fails until ok is set.
+func toggle(ok *atomic.Bool) CheckFunc {
+	return func(ctx context.Context) (string, error) {
+		if ok.Load() {
+			return "", nil
+		}
+		return "Cannot connect", errors.New("connection refused")
+	}
+}
+
+func TestRegisterStartsDown(t *testing.T) {
+	r := NewRegistry(time.Hour)
+	r.Register("mongodb", true, time.Second, up)
+	r.Register("memory", false, time.Second, up)
+
+	results := r.Results()
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	for name, result := range results {
+		if result.Status != StatusDown || result.Message != "Not checked yet" || !result.LastChecked.IsZero() {
+			t.Errorf("%s before the first run = %+v, want DOWN/Not checked yet", name, result)
+		}
+	}
+	if !results["mongodb"].Critical || results["memory"].Critical {
+		t.Errorf("critical flags not kept: %+v", results)
+	}
+	if r.Started() {
+		t.Error("Started() before any check ran")
+	}
+}
+
+func TestRunResult(t *testing.T) {
+	r := NewRegistry(time.Hour)
+	r.Register("memory", false, time.Second, func(context.Context) (string, error) { return "Allocated: 3 MB", nil })
+	r.Register("redis", true, time.Second, down)
+	r.Register("mongodb", true, time.Second, func(context.Context) (string, error) {
+		return "Cannot connect to database", errors.New("server selection timeout")
+	})
+
+	before := time.Now()
+	r.refresh()
+	results := r.Results()
+
+	tests := []struct {
+		name    string
+		status  Status
+		message string
+	}{
+		{"memory", StatusUp, "Allocated: 3 MB"},
+		{"redis", StatusDown, "connection refused"},
+		{"mongodb", StatusDown, "Cannot connect to database"},
+	}
+	for _, tt := range tests {
+		got := results[tt.name]
+		if got.Status != tt.status || got.Message != tt.message {
+			t.Errorf("%s = %s %q, want %s %q", tt.name, got.Status, got.Message, tt.status, tt.message)
+		}
+		if got.LastChecked.Before(before) {
+			t.Errorf("%s LastChecked = %v, not updated by the run", tt.name, got.LastChecked)
+		}
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	var sawDeadline atomic.Bool
+	r := NewRegistry(time.Hour)
+	r.Register("blob-storage", true, 20*time.Millisecond, func(ctx context.Context) (string, error) {
+		_, ok := ctx.Deadline()
+		sawDeadline.Store(ok)
+		<-release // ignores ctx, like a client without deadline support
+		return "", nil
+	})
+	r.Register("memory", false, time.Second, up)
+
+	start := time.Now()
+	r.refresh()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("refresh took %v, a hung check must not block past its timeout", elapsed)
+	}
+
+	got := r.Results()["blob-storage"]
+	if got.Status != StatusDown || got.Message != "Check timed out after 20ms" {
+		t.Errorf("hung check = %s %q, want DOWN timed out", got.Status, got.Message)
+	}
+	if !sawDeadline.Load() {
+		t.Error("check context carries no deadline")
+	}
+	if got := r.Results()["memory"].Status; got != StatusUp {
+		t.Errorf("other check = %s, a slow check must not hold up the rest", got)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		critical    CheckFunc
+		nonCritical CheckFunc
+		want        Status
+	}{
+		{"all up", up, up, StatusUp},
+		{"non-critical down", up, down, StatusDegraded},
+		{"critical down", down, up, StatusDown},
+		{"both down", down, down, StatusDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry(time.Hour)
+			r.Register("mongodb", true, time.Second, tt.critical)
+			r.Register("memory", false, time.Second, tt.nonCritical)
+			r.refresh()
+
+			if got := r.Status(); got != tt.want {
+				t.Errorf("Status() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStarted(t *testing.T) {
+	var ok atomic.Bool
+	r := NewRegistry(time.Hour)
+	r.Register("mongodb", true, time.Second, toggle(&ok))
+	r.Register("blob-storage", false, time.Second, down)
+
+	r.refresh()
+	if r.Started() {
+		t.Fatal("Started() while a critical check is failing")
+	}
+
+	ok.Store(true)
+	r.refresh()
+	if !r.Started() {
+		t.Fatal("Started() = false once every critical check passed; non-critical failures must not block startup")
+	}
+
+	// Startup is latched: a later failure shows in readiness, not startup.
+	ok.Store(false)
+	r.refresh()
+	if !r.Started() {
+		t.Error("Started() reset after a later failure")
+	}
+	if got := r.Status(); got != StatusDown {
+		t.Errorf("Status() = %s after the critical check failed again, want DOWN", got)
+	}
+}
+
+func TestBackgroundRefresh(t *testing.T) {
+	var ok atomic.Bool
+	var calls atomic.Int32
+	r := NewRegistry(10 * time.Millisecond)
+	r.Register("redis", true, time.Second, func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		return toggle(&ok)(ctx)
+	})
+
+	r.Start()
+	defer r.Stop(context.Background())
+
+	waitFor(t, "first run", func() bool { return calls.Load() >= 1 })
+	waitFor(t, "status to reach DOWN", func() bool { return r.Status() == StatusDown && !r.Results()["redis"].LastChecked.IsZero() })
+
+	// Readers only see the cache; the ticker picks up the recovery.
+	ok.Store(true)
+	waitFor(t, "refresh to pick up recovery", func() bool { return r.Status() == StatusUp })
+	if !r.Started() {
+		t.Error("Started() = false after the background refresh passed")
+	}
+
+	n := calls.Load()
+	for range 50 {
+		r.Results()
+		r.Status()
+	}
+	if calls.Load() > n+1 {
+		t.Error("reading results ran the checks; probes must be served from the cache")
+	}
+}
+
+func TestStop(t *testing.T) {
+	var calls atomic.Int32
+	r := NewRegistry(5 * time.Millisecond)
+	r.Register("memory", false, time.Second, func(context.Context) (string, error) {
+		calls.Add(1)
+		return "", nil
+	})
+	r.Start()
+	waitFor(t, "first run", func() bool { return calls.Load() >= 1 })
+
+	if err := r.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if err := r.Stop(context.Background()); err != nil {
+		t.Fatalf("second Stop: %v", err)
+	}
+	n := calls.Load()
+	time.Sleep(30 * time.Millisecond)
+	if calls.Load() != n {
+		t.Error("checks kept running after Stop")
+	}
+}
+
+func TestStopWaitsForInFlightRound(t *testing.T) {
+	release := make(chan struct{})
+	running := make(chan struct{})
+	r := NewRegistry(time.Hour)
+	r.Register("mongodb", true, time.Minute, func(ctx context.Context) (string, error) {
+		close(running)
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return "", nil
+	})
+	r.Start()
+	<-running
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := r.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Stop during a run = %v, want the context deadline", err)
+	}
+
+	close(release)
+	if err := r.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop after the run finished: %v", err)
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,59 @@
+package keys
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const devKeyBits = 2048
+
+// GenerateDevKey creates an ephemeral RSA keypair for local development. The
+// private key is written to path (mode 0600) so cmd/mintjwt can sign tokens that
+// the returned provider accepts. A new key is generated on every start, which
+// invalidates tokens minted for the previous run.
+func GenerateDevKey(path string) (*StaticProvider, error) {
+	private, err := rsa.GenerateKey(rand.Reader, devKeyBits)
+	if err != nil {
+		return nil, fmt.Errorf("generate dev key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("encode dev key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("write dev key: %w", err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		return nil, fmt.Errorf("write dev key: %w", err)
+	}
+
+	return NewStaticProvider(&private.PublicKey), nil
+}
+
+// LoadDevSigningKey reads the private key written by GenerateDevKey.
+func LoadDevSigningKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("no PRIVATE KEY block found")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("dev signing key is not an RSA key")
+	}
+	return private, nil
+}
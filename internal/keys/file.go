@@ -0,0 +1,175 @@
+package keys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileProvider serves the key from a mounted PEM file and picks up changes when
+// OpenShift updates the secret in place. After a rotation the previous key stays
+// valid for the overlap window so tokens signed just before the switch still verify.
+type FileProvider struct {
+	path         string
+	pollInterval time.Duration
+	overlap      time.Duration
+
+	mu        sync.RWMutex
+	raw       []byte
+	current   []Key
+	previous  []Key
+	rotatedAt time.Time
+	onRotate  []func()
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewFileProvider loads the key at path; it fails if the initial key cannot be read.
+func NewFileProvider(path string, pollInterval, overlap time.Duration) (*FileProvider, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := ParsePEM(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("loaded public keys", "path", path, "fingerprints", fingerprints(parsed))
+	return &FileProvider{
+		path:         path,
+		pollInterval: pollInterval,
+		overlap:      overlap,
+		raw:          raw,
+		current:      parsed,
+		stop:         make(chan struct{}),
+	}, nil
+}
+
+// OnRotate registers fn to run after a new key has been activated.
+func (p *FileProvider) OnRotate(fn func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onRotate = append(p.onRotate, fn)
+}
+
+// Start polls the file for changes. Polling, rather than inotify, also catches the
+// atomic symlink swap Kubernetes uses when updating mounted secrets.
+func (p *FileProvider) Start() {
+	go func() {
+		ticker := time.NewTicker(p.pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				if err := p.Reload(); err != nil {
+					slog.Warn("public key reload failed, keeping current key", "path", p.path, "error", err)
+				}
+			}
+		}
+	}()
+}
+
+func (p *FileProvider) Close() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+}
+
+// Reload re-reads the file and activates the key if its contents changed. An
+// unreadable or unparsable file leaves the current key in place.
+func (p *FileProvider) Reload() error {
+	raw, err := os.ReadFile(p.path)
+	if err != nil {
+		return err
+	}
+
+	p.mu.RLock()
+	unchanged := bytes.Equal(raw, p.raw)
+	p.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	parsed, err := ParsePEM(raw)
+	if err != nil {
+		return fmt.Errorf("parse rotated key: %w", err)
+	}
+
+	p.mu.Lock()
+	previous := p.current
+	p.previous = previous
+	p.current = parsed
+	p.raw = raw
+	p.rotatedAt = time.Now()
+	callbacks := append([]func(){}, p.onRotate...)
+	p.mu.Unlock()
+
+	slog.Info("public key rotated",
+		"path", p.path,
+		"fingerprints", fingerprints(parsed),
+		"previous_fingerprints", fingerprints(previous),
+		"overlap", p.overlap,
+	)
+	for _, fn := range callbacks {
+		fn()
+	}
+	return nil
+}
+
+// Lookup returns the active keys and, during the overlap window, the previous ones.
+func (p *FileProvider) Lookup(ctx context.Context, kid string) ([]Key, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	candidates := append([]Key{}, p.current...)
+	if p.previous != nil && time.Since(p.rotatedAt) < p.overlap {
+		candidates = append(candidates, p.previous...)
+	}
+	return candidates, nil
+}
+
+// ActiveFingerprint identifies the keys currently used for verification.
+func (p *FileProvider) ActiveFingerprint() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return strings.Join(fingerprints(p.current), ", ")
+}
+
+// Check reports the active key fingerprint and fails if the mounted file disappeared.
+func (p *FileProvider) Check(ctx context.Context) (string, error) {
+	message := "Active key " + p.ActiveFingerprint()
+	if _, err := os.Stat(p.path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "Public key file not found (serving cached key " + p.ActiveFingerprint() + ")", err
+		}
+		return "Public key file not readable", err
+	}
+
+	p.mu.RLock()
+	inOverlap := p.previous != nil && time.Since(p.rotatedAt) < p.overlap
+	p.mu.RUnlock()
+	if inOverlap {
+		message += ", previous key accepted until " + p.rotatedAt.Add(p.overlap).UTC().Format(time.RFC3339)
+	}
+	return message, nil
+}
+
+var _ Provider = (*FileProvider)(nil)
+
+func fingerprints(keys []Key) []string {
+	out := make([]string, len(keys))
+	for i, k := range keys {
+		out[i] = Fingerprint(k.Public)
+	}
+	return out
+}
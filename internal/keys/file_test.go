@@ -0,0 +1,107 @@
+package keys
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writePublicKeyPEM(t *testing.T, path string) string {
+	t.Helper()
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return Fingerprint(&private.PublicKey)
+}
+
+func lookupFingerprints(t *testing.T, p *FileProvider) []string {
+	t.Helper()
+	candidates, err := p.Lookup(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	return fingerprints(candidates)
+}
+
+func TestFileProviderRotation(t *testing.T) {
+	const overlap = 100 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "public.pem")
+	first := writePublicKeyPEM(t, path)
+
+	p, err := NewFileProvider(path, time.Hour, overlap)
+	if err != nil {
+		t.Fatalf("NewFileProvider() error = %v", err)
+	}
+	rotations := 0
+	p.OnRotate(func() { rotations++ })
+
+	if got := lookupFingerprints(t, p); len(got) != 1 || got[0] != first {
+		t.Fatalf("initial keys = %v, want [%s]", got, first)
+	}
+
+	// An unchanged file is not a rotation
+	if err := p.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if rotations != 0 {
+		t.Errorf("rotations = %d after unchanged reload, want 0", rotations)
+	}
+
+	second := writePublicKeyPEM(t, path)
+	if err := p.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if rotations != 1 {
+		t.Errorf("rotations = %d, want 1", rotations)
+	}
+	if got := lookupFingerprints(t, p); len(got) != 2 || got[0] != second || got[1] != first {
+		t.Errorf("keys during overlap = %v, want [%s %s]", got, second, first)
+	}
+
+	time.Sleep(overlap)
+	if got := lookupFingerprints(t, p); len(got) != 1 || got[0] != second {
+		t.Errorf("keys after overlap = %v, want [%s]", got, second)
+	}
+}
+
+func TestFileProviderKeepsKeyOnBadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "public.pem")
+	first := writePublicKeyPEM(t, path)
+
+	p, err := NewFileProvider(path, time.Hour, time.Minute)
+	if err != nil {
+		t.Fatalf("NewFileProvider() error = %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Reload(); err == nil {
+		t.Error("Reload() of an unparsable file succeeded")
+	}
+	if got := lookupFingerprints(t, p); len(got) != 1 || got[0] != first {
+		t.Errorf("keys = %v, want the original key kept", got)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Check(context.Background()); err == nil {
+		t.Error("Check() with the file removed reported healthy")
+	}
+}
@@ -0,0 +1,379 @@
+package keys
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minForcedRefresh bounds how often an unknown kid can trigger a fetch, so a
+// stream of forged kids cannot be used to hammer the identity service.
+const minForcedRefresh = 30 * time.Second
+
+// maxJWKSSize caps the JWKS document; a real key set is a few KiB, so anything
+// larger is a misconfigured or hostile endpoint and is not buffered.
+const maxJWKSSize = 1 << 20
+
+// JWKSProvider fetches keys from a JWKS URL, refreshing periodically and on demand
+// when a token references an unknown kid. Keys that disappear from the document
+// stay usable for the grace period so tokens signed just before a rotation still verify.
+type JWKSProvider struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+	gracePeriod     time.Duration
+
+	mu          sync.RWMutex
+	keys        map[string]jwksEntry
+	lastFetch   time.Time
+	lastAttempt time.Time
+	lastErr     error
+
+	refreshMu sync.Mutex
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+type jwksEntry struct {
+	key Key
+	// retiredAt is set once the key is no longer published
+	retiredAt time.Time
+}
+
+func NewJWKSProvider(url string, refreshInterval, gracePeriod time.Duration) *JWKSProvider {
+	return &JWKSProvider{
+		url:             url,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		refreshInterval: refreshInterval,
+		gracePeriod:     gracePeriod,
+		keys:            make(map[string]jwksEntry),
+		stop:            make(chan struct{}),
+	}
+}
+
+// Start performs the initial fetch and keeps the key set fresh in the background.
+// A failed initial fetch is logged, not fatal: the next lookup retries.
+func (p *JWKSProvider) Start(ctx context.Context) {
+	if err := p.Refresh(ctx); err != nil {
+		slog.Error("initial JWKS fetch failed", "url", p.url, "error", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(p.refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				if err := p.Refresh(context.Background()); err != nil {
+					slog.Warn("JWKS refresh failed", "url", p.url, "error", err)
+				}
+			}
+		}
+	}()
+}
+
+func (p *JWKSProvider) Close() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+}
+
+func (p *JWKSProvider) Lookup(ctx context.Context, kid string) ([]Key, error) {
+	if key, err := p.find(kid); err == nil {
+		return []Key{key}, nil
+	}
+
+	// Unknown kid: the identity service may have rotated, refetch (rate limited)
+	if err := p.refreshOnDemand(ctx); err != nil {
+		slog.WarnContext(ctx, "JWKS refresh on unknown kid failed", "kid", kid, "error", err)
+	}
+	key, err := p.find(kid)
+	if err != nil {
+		return nil, err
+	}
+	return []Key{key}, nil
+}
+
+func (p *JWKSProvider) refreshOnDemand(ctx context.Context) error {
+	p.refreshMu.Lock()
+	defer p.refreshMu.Unlock()
+
+	p.mu.RLock()
+	recentlyAttempted := time.Since(p.lastAttempt) < minForcedRefresh
+	p.mu.RUnlock()
+	if recentlyAttempted {
+		return nil
+	}
+	return p.refreshLocked(ctx)
+}
+
+func (p *JWKSProvider) find(kid string) (Key, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	now := time.Now()
+	usable := func(e jwksEntry) bool {
+		return e.retiredAt.IsZero() || now.Sub(e.retiredAt) < p.gracePeriod
+	}
+
+	if kid == "" {
+		var only *Key
+		for _, e := range p.keys {
+			if !usable(e) {
+				continue
+			}
+			if only != nil {
+				return Key{}, fmt.Errorf("%w: token has no kid and multiple keys are published", ErrUnknownKey)
+			}
+			k := e.key
+			only = &k
+		}
+		if only == nil {
+			return Key{}, ErrNoKeys
+		}
+		return *only, nil
+	}
+
+	e, ok := p.keys[kid]
+	if !ok || !usable(e) {
+		return Key{}, fmt.Errorf("%w: kid %q", ErrUnknownKey, kid)
+	}
+	return e.key, nil
+}
+
+// Refresh fetches the JWKS document and merges it into the key set.
+func (p *JWKSProvider) Refresh(ctx context.Context) error {
+	p.refreshMu.Lock()
+	defer p.refreshMu.Unlock()
+	return p.refreshLocked(ctx)
+}
+
+func (p *JWKSProvider) refreshLocked(ctx context.Context) error {
+	p.mu.Lock()
+	p.lastAttempt = time.Now()
+	p.mu.Unlock()
+
+	fetched, err := p.fetch(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lastErr = err
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for kid, entry := range p.keys {
+		if _, stillPublished := fetched[kid]; stillPublished {
+			continue
+		}
+		if entry.retiredAt.IsZero() {
+			entry.retiredAt = now
+			p.keys[kid] = entry
+			slog.Info("signing key retired from JWKS, keeping during grace period", "kid", kid, "grace_period", p.gracePeriod)
+		} else if now.Sub(entry.retiredAt) >= p.gracePeriod {
+			delete(p.keys, kid)
+		}
+	}
+	for kid, key := range fetched {
+		if _, known := p.keys[kid]; !known {
+			slog.Info("loaded signing key from JWKS", "kid", kid, "alg", key.Algorithm)
+		}
+		p.keys[kid] = jwksEntry{key: key}
+	}
+	p.lastFetch = now
+	return nil
+}
+
+// Check reports whether the last fetch succeeded and keys are available.
+func (p *JWKSProvider) Check(ctx context.Context) (string, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if len(p.keys) == 0 {
+		if p.lastErr != nil {
+			return "No keys loaded: " + p.lastErr.Error(), ErrNoKeys
+		}
+		return "No keys loaded", ErrNoKeys
+	}
+	message := fmt.Sprintf("%d keys, last refreshed %s", len(p.keys), p.lastFetch.UTC().Format(time.RFC3339))
+	if p.lastErr != nil {
+		// Still serving cached keys, but surface the failing refresh
+		message += ", last refresh failed: " + p.lastErr.Error()
+	}
+	return message, nil
+}
+
+type jwksDocument struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC and OKP
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (p *JWKSProvider) fetch(ctx context.Context) (map[string]Key, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint responded with status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read JWKS: %w", err)
+	}
+	if len(body) > maxJWKSSize {
+		return nil, fmt.Errorf("JWKS document exceeds %d bytes", maxJWKSSize)
+	}
+
+	var doc jwksDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]Key)
+	for _, jwk := range doc.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.parse()
+		if err == nil && key.Algorithm != "" && !key.Permits(key.Algorithm) {
+			err = fmt.Errorf("alg %q does not match key type %q", key.Algorithm, jwk.Kty)
+		}
+		if err != nil {
+			slog.Warn("skipping unusable JWKS key", "kid", jwk.Kid, "error", err)
+			continue
+		}
+		keys[key.ID] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS document contains no usable signing keys")
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) parse() (Key, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid exponent: %w", err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 {
+			return Key{}, errors.New("invalid exponent")
+		}
+		return Key{
+			ID:        k.Kid,
+			Algorithm: k.Alg,
+			Public:    &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())},
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return Key{}, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid x coordinate: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid y coordinate: %w", err)
+		}
+		// Round-trip through the uncompressed point encoding so off-curve points are rejected
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) > size || len(y) > size {
+			return Key{}, errors.New("invalid point size")
+		}
+		point := make([]byte, 1+2*size)
+		point[0] = 4
+		copy(point[1+size-len(x):1+size], x)
+		copy(point[1+2*size-len(y):], y)
+		public, err := ecdsaPublicKey(curve, point)
+		if err != nil {
+			return Key{}, err
+		}
+		return Key{ID: k.Kid, Algorithm: k.Alg, Public: public}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return Key{}, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return Key{}, errors.New("invalid Ed25519 public key")
+		}
+		return Key{ID: k.Kid, Algorithm: k.Alg, Public: ed25519.PublicKey(x)}, nil
+	default:
+		return Key{}, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func ecdsaPublicKey(curve elliptic.Curve, point []byte) (*ecdsa.PublicKey, error) {
+	var ecdhCurve ecdh.Curve
+	switch curve {
+	case elliptic.P256():
+		ecdhCurve = ecdh.P256()
+	case elliptic.P384():
+		ecdhCurve = ecdh.P384()
+	default:
+		ecdhCurve = ecdh.P521()
+	}
+	if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+		return nil, fmt.Errorf("invalid EC point: %w", err)
+	}
+	size := (curve.Params().BitSize + 7) / 8
+	return &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(point[1 : 1+size]),
+		Y:     new(big.Int).SetBytes(point[1+size:]),
+	}, nil
+}
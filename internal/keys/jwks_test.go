@@ -0,0 +1,352 @@
+package keys
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksServer is a local JWKS endpoint whose published keys can be changed
+// between requests; it counts the fetches it serves.
+type jwksServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	keys     []jsonWebKey
+	requests int
+}
+
+func newJWKSServer(t *testing.T, keys ...jsonWebKey) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(jwksDocument{Keys: s.keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) publish(keys ...jsonWebKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func (s *jwksServer) fetches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func rsaJWK(t *testing.T, kid, alg string) (jsonWebKey, *rsa.PrivateKey) {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jsonWebKey{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		Alg: alg,
+		N:   base64.RawURLEncoding.EncodeToString(private.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(private.E)).Bytes()),
+	}, private
+}
+
+func ecJWK(t *testing.T, kid string) (jsonWebKey, *ecdsa.PrivateKey) {
+	t.Helper()
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jsonWebKey{
+		Kty: "EC",
+		Kid: kid,
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(private.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(private.Y.FillBytes(make([]byte, 32))),
+	}, private
+}
+
+// backdateAttempt lets the next unknown kid trigger a fetch without waiting
+// out minForcedRefresh.
+func backdateAttempt(p *JWKSProvider) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastAttempt = time.Now().Add(-2 * minForcedRefresh)
+}
+
+func TestJWKSProviderLookup(t *testing.T) {
+	k1, _ := rsaJWK(t, "k1", "RS256")
+	k2, _ := ecJWK(t, "k2")
+	server := newJWKSServer(t, k1, k2)
+
+	p := NewJWKSProvider(server.URL, time.Hour, time.Hour)
+	if err := p.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		kid     string
+		wantAlg string
+		wantErr error
+	}{
+		{name: "RSA key by kid", kid: "k1", wantAlg: "RS256"},
+		{name: "EC key by kid", kid: "k2"},
+		{name: "unknown kid", kid: "k3", wantErr: ErrUnknownKey},
+		{name: "no kid with several keys", kid: "", wantErr: ErrUnknownKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Lookup(context.Background(), tt.kid)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Lookup(%q) error = %v, want %v", tt.kid, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Lookup(%q) error = %v", tt.kid, err)
+			}
+			if len(got) != 1 || got[0].ID != tt.kid || got[0].Algorithm != tt.wantAlg {
+				t.Errorf("Lookup(%q) = %+v", tt.kid, got)
+			}
+		})
+	}
+}
+
+func TestJWKSProviderRefreshOnUnknownKid(t *testing.T) {
+	k1, _ := rsaJWK(t, "k1", "RS256")
+	k2, _ := rsaJWK(t, "k2", "RS256")
+	k3, _ := rsaJWK(t, "k3", "RS256")
+	server := newJWKSServer(t, k1)
+
+	p := NewJWKSProvider(server.URL, time.Hour, time.Hour)
+	if err := p.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	// The identity service rotates to k2
+	server.publish(k1, k2)
+	backdateAttempt(p)
+	if _, err := p.Lookup(context.Background(), "k2"); err != nil {
+		t.Fatalf("Lookup(k2) after rotation error = %v", err)
+	}
+	if got := server.fetches(); got != 2 {
+		t.Fatalf("fetches = %d, want 2 (initial + on unknown kid)", got)
+	}
+
+	// A known kid is served from memory
+	if _, err := p.Lookup(context.Background(), "k1"); err != nil {
+		t.Fatalf("Lookup(k1) error = %v", err)
+	}
+	if got := server.fetches(); got != 2 {
+		t.Errorf("fetches = %d after known kid, want 2", got)
+	}
+
+	// Unknown kids within minForcedRefresh of the last fetch do not refetch,
+	// even when the key has since been published
+	server.publish(k1, k2, k3)
+	for _, kid := range []string{"forged-1", "forged-2", "k3"} {
+		if _, err := p.Lookup(context.Background(), kid); !errors.Is(err, ErrUnknownKey) {
+			t.Errorf("Lookup(%q) error = %v, want ErrUnknownKey", kid, err)
+		}
+	}
+	if got := server.fetches(); got != 2 {
+		t.Errorf("fetches = %d after rate-limited lookups, want 2", got)
+	}
+
+	backdateAttempt(p)
+	if _, err := p.Lookup(context.Background(), "k3"); err != nil {
+		t.Errorf("Lookup(k3) once the rate limit passed error = %v", err)
+	}
+	if got := server.fetches(); got != 3 {
+		t.Errorf("fetches = %d, want 3", got)
+	}
+}
+
+func TestJWKSProviderGracePeriod(t *testing.T) {
+	const grace = 100 * time.Millisecond
+	k1, _ := rsaJWK(t, "k1", "RS256")
+	k2, _ := rsaJWK(t, "k2", "RS256")
+	server := newJWKSServer(t, k1)
+
+	p := NewJWKSProvider(server.URL, time.Hour, grace)
+	if err := p.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	// k1 is rotated out of the document
+	server.publish(k2)
+	if err := p.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if _, err := p.Lookup(context.Background(), "k1"); err != nil {
+		t.Errorf("retired key rejected within grace period: %v", err)
+	}
+	if _, err := p.Lookup(context.Background(), "k2"); err != nil {
+		t.Errorf("Lookup(k2) error = %v", err)
+	}
+
+	time.Sleep(grace)
+	if _, err := p.Lookup(context.Background(), "k1"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("retired key after grace period: error = %v, want ErrUnknownKey", err)
+	}
+
+	// The next refresh forgets it
+	if err := p.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	p.mu.RLock()
+	_, kept := p.keys["k1"]
+	p.mu.RUnlock()
+	if kept {
+		t.Error("retired key still held after grace period and refresh")
+	}
+}
+
+func TestJWKSProviderKeepsKeysWhenFetchFails(t *testing.T) {
+	k1, _ := rsaJWK(t, "k1", "RS256")
+	server := newJWKSServer(t, k1)
+
+	p := NewJWKSProvider(server.URL, time.Hour, time.Hour)
+	if err := p.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	server.publish()
+	if err := p.Refresh(context.Background()); err == nil {
+		t.Fatal("Refresh() of an empty document succeeded")
+	}
+	if _, err := p.Lookup(context.Background(), "k1"); err != nil {
+		t.Errorf("cached key lost after failed refresh: %v", err)
+	}
+	if _, err := p.Check(context.Background()); err != nil {
+		t.Errorf("Check() error = %v, want cached keys reported healthy", err)
+	}
+}
+
+func TestJWKSProviderRejectsOversizedDocument(t *testing.T) {
+	k1, _ := rsaJWK(t, "k1", "RS256")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A valid document padded past the cap, so only the size can reject it
+		doc, _ := json.Marshal(jwksDocument{Keys: []jsonWebKey{k1}})
+		padding := strings.Repeat(" ", maxJWKSSize)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, string(doc[:len(doc)-1])+padding+"}")
+	}))
+	t.Cleanup(server.Close)
+
+	p := NewJWKSProvider(server.URL, time.Hour, time.Hour)
+	err := p.Refresh(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("Refresh() error = %v, want the size limit", err)
+	}
+	if _, err := p.Lookup(context.Background(), "k1"); err == nil {
+		t.Error("key from an oversized document was installed")
+	}
+}
+
+// TestJWKSProviderAlgorithmMismatch verifies tokens the way the auth middleware
+// does: only candidates that Permit the token's alg may verify it.
+func TestJWKSProviderAlgorithmMismatch(t *testing.T) {
+	rs256, rsaKey := rsaJWK(t, "rsa-rs256", "RS256")
+	rs384, rsa384Key := rsaJWK(t, "rsa-rs384", "RS384")
+	noAlg, rsaNoAlgKey := rsaJWK(t, "rsa-any", "")
+	ec, ecKey := ecJWK(t, "ec")
+	server := newJWKSServer(t, rs256, rs384, noAlg, ec)
+
+	p := NewJWKSProvider(server.URL, time.Hour, time.Hour)
+	if err := p.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	errMismatch := errors.New("no key for algorithm")
+	keyFunc := func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		candidates, err := p.Lookup(context.Background(), kid)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range candidates {
+			if c.Permits(token.Method.Alg()) {
+				return c.Public, nil
+			}
+		}
+		return nil, errMismatch
+	}
+
+	tests := []struct {
+		name    string
+		kid     string
+		method  jwt.SigningMethod
+		key     any
+		wantErr bool
+	}{
+		{name: "RS256 with RS256 key", kid: "rsa-rs256", method: jwt.SigningMethodRS256, key: rsaKey},
+		{name: "RS384 with RS256 key", kid: "rsa-rs256", method: jwt.SigningMethodRS384, key: rsaKey, wantErr: true},
+		{name: "PS256 with RS256 key", kid: "rsa-rs256", method: jwt.SigningMethodPS256, key: rsaKey, wantErr: true},
+		{name: "RS384 with RS384 key", kid: "rsa-rs384", method: jwt.SigningMethodRS384, key: rsa384Key},
+		{name: "PS256 with RSA key without alg", kid: "rsa-any", method: jwt.SigningMethodPS256, key: rsaNoAlgKey},
+		{name: "ES256 with EC key", kid: "ec", method: jwt.SigningMethodES256, key: ecKey},
+		{name: "ES256 with RSA key", kid: "rsa-rs256", method: jwt.SigningMethodES256, key: ecKey, wantErr: true},
+		{name: "HS256 with RSA key", kid: "rsa-any", method: jwt.SigningMethodHS256, key: []byte("secret"), wantErr: true},
+		{name: "RS256 with EC key", kid: "ec", method: jwt.SigningMethodRS256, key: rsaKey, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := jwt.NewWithClaims(tt.method, jwt.MapClaims{"sub": "user"})
+			token.Header["kid"] = tt.kid
+			signed, err := token.SignedString(tt.key)
+			if err != nil {
+				t.Fatalf("sign: %v", err)
+			}
+
+			_, err = jwt.Parse(signed, keyFunc)
+			if tt.wantErr && !errors.Is(err, errMismatch) {
+				t.Errorf("error = %v, want rejection for algorithm mismatch", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("error = %v, want token accepted", err)
+			}
+		})
+	}
+}
+
+func TestJWKSProviderSkipsKeysWithMismatchedAlg(t *testing.T) {
+	good, _ := rsaJWK(t, "good", "RS256")
+	bad, _ := rsaJWK(t, "bad", "ES256")
+	server := newJWKSServer(t, good, bad)
+
+	p := NewJWKSProvider(server.URL, time.Hour, time.Hour)
+	if err := p.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if _, err := p.Lookup(context.Background(), "good"); err != nil {
+		t.Errorf("Lookup(good) error = %v", err)
+	}
+	if _, err := p.Lookup(context.Background(), "bad"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("RSA key published as ES256 was loaded: error = %v", err)
+	}
+}
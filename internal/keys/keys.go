@@ -0,0 +1,77 @@
+// Package keys resolves the public keys used to verify access tokens, either from
+// a mounted PEM file or from the identity service's JWKS endpoint.
+package keys
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrUnknownKey = errors.New("unknown signing key")
+	ErrNoKeys     = errors.New("no signing keys available")
+)
+
+// Key is a verification key together with the identifier and algorithm it was published with.
+type Key struct {
+	ID        string
+	Algorithm string
+	Public    crypto.PublicKey
+}
+
+// Permits reports whether a token signed with alg may be verified with this key.
+// The algorithm family must match the key type (and the curve, for ECDSA), which
+// prevents algorithm-confusion attacks across key types; a key published with an
+// explicit algorithm then only accepts that algorithm.
+func (k Key) Permits(alg string) bool {
+	if !k.typePermits(alg) {
+		return false
+	}
+	return k.Algorithm == "" || k.Algorithm == alg
+}
+
+func (k Key) typePermits(alg string) bool {
+	switch pub := k.Public.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			return alg == "ES256"
+		case elliptic.P384():
+			return alg == "ES384"
+		case elliptic.P521():
+			return alg == "ES512"
+		}
+	case ed25519.PublicKey:
+		return alg == "EdDSA"
+	}
+	return false
+}
+
+// Provider returns the candidate keys for the token's kid header. Most providers
+// return exactly one key; during a rotation overlap several may be returned and a
+// signature matching any of them is accepted.
+type Provider interface {
+	Lookup(ctx context.Context, kid string) ([]Key, error)
+}
+
+// StaticProvider serves a fixed key loaded at startup.
+type StaticProvider struct {
+	key Key
+}
+
+func NewStaticProvider(public crypto.PublicKey) *StaticProvider {
+	return &StaticProvider{key: Key{Public: public}}
+}
+
+// Lookup ignores kid: a single mounted key is used for every token.
+func (p *StaticProvider) Lookup(ctx context.Context, kid string) ([]Key, error) {
+	return []Key{p.key}, nil
+}
@@ -0,0 +1,60 @@
+package keys
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+)
+
+func TestKeyPermits(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		key  Key
+		alg  string
+		want bool
+	}{
+		{"RSA without alg accepts RS256", Key{Public: &rsaKey.PublicKey}, "RS256", true},
+		{"RSA without alg accepts PS512", Key{Public: &rsaKey.PublicKey}, "PS512", true},
+		{"RSA without alg rejects ES256", Key{Public: &rsaKey.PublicKey}, "ES256", false},
+		{"RSA without alg rejects HS256", Key{Public: &rsaKey.PublicKey}, "HS256", false},
+		{"RSA bound to RS256 accepts RS256", Key{Algorithm: "RS256", Public: &rsaKey.PublicKey}, "RS256", true},
+		{"RSA bound to RS256 rejects RS384", Key{Algorithm: "RS256", Public: &rsaKey.PublicKey}, "RS384", false},
+		{"RSA published as ES256 rejects ES256", Key{Algorithm: "ES256", Public: &rsaKey.PublicKey}, "ES256", false},
+		{"RSA published as HS256 rejects HS256", Key{Algorithm: "HS256", Public: &rsaKey.PublicKey}, "HS256", false},
+		{"P-256 accepts ES256", Key{Public: &p256.PublicKey}, "ES256", true},
+		{"P-256 rejects ES384", Key{Public: &p256.PublicKey}, "ES384", false},
+		{"P-256 published as ES384 rejects ES384", Key{Algorithm: "ES384", Public: &p256.PublicKey}, "ES384", false},
+		{"P-384 accepts ES384", Key{Public: &p384.PublicKey}, "ES384", true},
+		{"P-384 published as RS256 rejects RS256", Key{Algorithm: "RS256", Public: &p384.PublicKey}, "RS256", false},
+		{"Ed25519 accepts EdDSA", Key{Public: edKey}, "EdDSA", true},
+		{"Ed25519 rejects RS256", Key{Public: edKey}, "RS256", false},
+		{"Ed25519 published as RS256 rejects RS256", Key{Algorithm: "RS256", Public: edKey}, "RS256", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key.Permits(tt.alg); got != tt.want {
+				t.Errorf("Permits(%q) = %v, want %v", tt.alg, got, tt.want)
+			}
+		})
+	}
+}
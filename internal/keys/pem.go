@@ -0,0 +1,74 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// ParsePEM parses all PUBLIC KEY (PKIX) and RSA PUBLIC KEY (PKCS#1) blocks. RSA,
+// ECDSA and Ed25519 keys are supported; each key is bound to the algorithms its
+// type allows (see Key.Permits).
+func ParsePEM(keyData []byte) ([]Key, error) {
+	var parsed []Key
+	rest := keyData
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		var public crypto.PublicKey
+		var err error
+		switch block.Type {
+		case "PUBLIC KEY":
+			public, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse %s block: %w", block.Type, err)
+		}
+
+		key := Key{Public: public}
+		if err := key.validate(); err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, key)
+	}
+
+	if len(parsed) == 0 {
+		return nil, errors.New("no public keys found in PEM data")
+	}
+	return parsed, nil
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the key's DER-encoded
+// SubjectPublicKeyInfo, in the same form as `ssh-keygen -l`.
+func Fingerprint(public crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "unknown"
+	}
+	sum := sha256.Sum256(der)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+func (k Key) validate() error {
+	switch k.Public.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return nil
+	default:
+		return fmt.Errorf("unsupported public key type %T", k.Public)
+	}
+}
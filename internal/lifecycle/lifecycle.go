@@ -0,0 +1,119 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Hook is a named shutdown step for a background worker or client connection.
+type Hook struct {
+	Name string
+	Stop func(ctx context.Context) error
+}
+
+// Lifecycle owns the HTTP server and everything that must be released when the
+// process is asked to stop (SIGTERM from OpenShift during rollouts, or SIGINT locally).
+type Lifecycle struct {
+	server      *http.Server
+	gracePeriod time.Duration
+	drainDelay  time.Duration
+	onSignal    []func()
+	hooks       []Hook
+}
+
+func New(server *http.Server, gracePeriod, drainDelay time.Duration) *Lifecycle {
+	return &Lifecycle{
+		server:      server,
+		gracePeriod: gracePeriod,
+		drainDelay:  drainDelay,
+	}
+}
+
+// OnSignal registers fn to run as soon as a stop signal arrives, before the
+// server stops accepting connections (e.g. flipping readiness to DOWN).
+func (l *Lifecycle) OnSignal(fn func()) {
+	l.onSignal = append(l.onSignal, fn)
+}
+
+// Register adds a shutdown hook. Hooks run after the server has drained,
+// in reverse registration order, so dependencies opened first are closed last.
+// They share what is left of the grace period with the server drain.
+func (l *Lifecycle) Register(name string, stop func(ctx context.Context) error) {
+	l.hooks = append(l.hooks, Hook{Name: name, Stop: stop})
+}
+
+// Run serves until the server fails or a stop signal is received, then shuts down gracefully.
+func (l *Lifecycle) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		var err error
+		if l.server.TLSConfig != nil {
+			// Certificates are already loaded into the TLS config
+			slog.Info("starting server", "addr", l.server.Addr, "tls", true)
+			err = l.server.ListenAndServeTLS("", "")
+		} else {
+			slog.Info("starting server", "addr", l.server.Addr)
+			err = l.server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	var runErr error
+	select {
+	case err := <-serverErr:
+		runErr = err
+	case <-ctx.Done():
+		slog.Info("shutdown signal received")
+	}
+
+	return errors.Join(runErr, l.shutdown())
+}
+
+func (l *Lifecycle) shutdown() error {
+	for _, fn := range l.onSignal {
+		fn()
+	}
+
+	// Give the router time to observe the failing readiness probe before we stop accepting connections
+	if l.drainDelay > 0 {
+		slog.Info("waiting for load balancer to drain", "delay", l.drainDelay)
+		time.Sleep(l.drainDelay)
+	}
+
+	// One deadline covers the drain and every hook, so drain delay plus grace
+	// period is all the time shutdown takes and fits the pod's termination grace
+	ctx, cancel := context.WithTimeout(context.Background(), l.gracePeriod)
+	defer cancel()
+
+	var errs []error
+	if err := l.server.Shutdown(ctx); err != nil {
+		slog.Error("http server did not drain in time", "grace_period", l.gracePeriod, "error", err)
+		errs = append(errs, err)
+	} else {
+		slog.Info("http server drained")
+	}
+
+	for i := len(l.hooks) - 1; i >= 0; i-- {
+		hook := l.hooks[i]
+		if err := hook.Stop(ctx); err != nil {
+			slog.Error("failed to stop component", "component", hook.Name, "error", err)
+			errs = append(errs, err)
+		} else {
+			slog.Info("stopped component", "component", hook.Name)
+		}
+	}
+
+	return errors.Join(errs...)
+}
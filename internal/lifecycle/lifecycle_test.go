@@ -0,0 +1,65 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestShutdownSharesOneDeadline(t *testing.T) {
+	const grace = 200 * time.Millisecond
+	l := New(&http.Server{}, grace, 0)
+
+	var deadlines []time.Time
+	var order []string
+	for _, name := range []string{"mongo", "redis", "worker"} {
+		l.Register(name, func(ctx context.Context) error {
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				t.Errorf("hook %s has no deadline", name)
+			}
+			deadlines = append(deadlines, deadline)
+			order = append(order, name)
+			// A slow hook eats into the time left for the next ones
+			time.Sleep(grace / 4)
+			return nil
+		})
+	}
+
+	if err := l.shutdown(); err != nil {
+		t.Fatalf("shutdown() error = %v", err)
+	}
+
+	if want := []string{"worker", "redis", "mongo"}; !slices.Equal(order, want) {
+		t.Errorf("hooks ran in order %v, want %v", order, want)
+	}
+	if len(deadlines) == 0 || time.Until(deadlines[0]) > grace {
+		t.Fatalf("deadlines %v, want one within the grace period", deadlines)
+	}
+	for i, d := range deadlines {
+		if !d.Equal(deadlines[0]) {
+			t.Errorf("hook %d deadline %v, want the shared %v", i, d, deadlines[0])
+		}
+	}
+}
+
+func TestShutdownHookSeesExpiredDeadline(t *testing.T) {
+	const grace = 50 * time.Millisecond
+	l := New(&http.Server{}, grace, 0)
+
+	l.Register("fast", func(ctx context.Context) error {
+		return ctx.Err()
+	})
+	l.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	// The slow hook uses up the grace period; the next one must not get a fresh one
+	if err := l.shutdown(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("shutdown() error = %v, want %v from the hook after the deadline", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,389 @@
+// Package metrics is a minimal Prometheus text-format instrumentation library.
+// It covers the counters, histograms and gauges this service needs without
+// pulling in the full client_golang dependency tree.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefBuckets are latency buckets in seconds suitable for HTTP and database calls.
+var DefBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+// Default is the registry used by the package-level constructors and Handler.
+var Default = NewRegistry()
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.collectors[c.name()]; exists {
+		panic("metrics: duplicate registration of " + c.name())
+	}
+	r.collectors[c.name()] = c
+}
+
+// Expose renders every registered metric in the Prometheus text exposition format.
+func (r *Registry) Expose(w io.Writer) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	collectors := make([]collector, len(names))
+	for i, name := range names {
+		collectors[i] = r.collectors[name]
+	}
+	r.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	bw.Flush()
+}
+
+// Handler serves the Default registry.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		Default.Expose(w)
+	})
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	bits atomic.Uint64
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+func (c *Counter) Add(v float64) {
+	addFloat(&c.bits, v)
+}
+
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(c.bits.Load())
+}
+
+// CounterVec is a family of counters partitioned by label values.
+type CounterVec struct {
+	family
+	mu       sync.RWMutex
+	counters map[string]*Counter
+}
+
+func NewCounter(name, help string) *Counter {
+	return NewCounterVec(name, help).WithLabelValues()
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{
+		family:   family{metricName: name, help: help, kind: "counter", labels: labels},
+		counters: make(map[string]*Counter),
+	}
+	Default.register(v)
+	return v
+}
+
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	key := v.key(values)
+	v.mu.RLock()
+	c, ok := v.counters[key]
+	v.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if c, ok = v.counters[key]; !ok {
+		c = &Counter{}
+		v.counters[key] = c
+	}
+	return c
+}
+
+func (v *CounterVec) write(w io.Writer) {
+	v.header(w)
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for _, key := range sortedKeys(v.counters) {
+		fmt.Fprintf(w, "%s%s %s\n", v.metricName, v.labelString(key, ""), formatFloat(v.counters[key].Value()))
+	}
+}
+
+// Gauge is a value that can go up and down.
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+func (g *Gauge) Set(v float64) {
+	g.bits.Store(math.Float64bits(v))
+}
+
+func (g *Gauge) Add(v float64) {
+	addFloat(&g.bits, v)
+}
+
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+type GaugeVec struct {
+	family
+	mu     sync.RWMutex
+	gauges map[string]*Gauge
+}
+
+func NewGauge(name, help string) *Gauge {
+	return NewGaugeVec(name, help).WithLabelValues()
+}
+
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	v := &GaugeVec{
+		family: family{metricName: name, help: help, kind: "gauge", labels: labels},
+		gauges: make(map[string]*Gauge),
+	}
+	Default.register(v)
+	return v
+}
+
+func (v *GaugeVec) WithLabelValues(values ...string) *Gauge {
+	key := v.key(values)
+	v.mu.RLock()
+	g, ok := v.gauges[key]
+	v.mu.RUnlock()
+	if ok {
+		return g
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if g, ok = v.gauges[key]; !ok {
+		g = &Gauge{}
+		v.gauges[key] = g
+	}
+	return g
+}
+
+func (v *GaugeVec) write(w io.Writer) {
+	v.header(w)
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for _, key := range sortedKeys(v.gauges) {
+		fmt.Fprintf(w, "%s%s %s\n", v.metricName, v.labelString(key, ""), formatFloat(v.gauges[key].Value()))
+	}
+}
+
+// GaugeFunc reports the value returned by fn at scrape time.
+type GaugeFunc struct {
+	family
+	fn func() float64
+}
+
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{
+		family: family{metricName: name, help: help, kind: "gauge"},
+		fn:     fn,
+	}
+	Default.register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	g.header(w)
+	fmt.Fprintf(w, "%s %s\n", g.metricName, formatFloat(g.fn()))
+}
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	upperBounds []float64
+	buckets     []atomic.Uint64
+	count       atomic.Uint64
+	sumBits     atomic.Uint64
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		upperBounds: buckets,
+		buckets:     make([]atomic.Uint64, len(buckets)),
+	}
+}
+
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.upperBounds, v)
+	if i < len(h.buckets) {
+		h.buckets[i].Add(1)
+	}
+	h.count.Add(1)
+	addFloat(&h.sumBits, v)
+}
+
+type HistogramVec struct {
+	family
+	buckets    []float64
+	mu         sync.RWMutex
+	histograms map[string]*Histogram
+}
+
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	return NewHistogramVec(name, help, buckets).WithLabelValues()
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	v := &HistogramVec{
+		family:     family{metricName: name, help: help, kind: "histogram", labels: labels},
+		buckets:    sorted,
+		histograms: make(map[string]*Histogram),
+	}
+	Default.register(v)
+	return v
+}
+
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	key := v.key(values)
+	v.mu.RLock()
+	h, ok := v.histograms[key]
+	v.mu.RUnlock()
+	if ok {
+		return h
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if h, ok = v.histograms[key]; !ok {
+		h = newHistogram(v.buckets)
+		v.histograms[key] = h
+	}
+	return h
+}
+
+func (v *HistogramVec) write(w io.Writer) {
+	v.header(w)
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for _, key := range sortedKeys(v.histograms) {
+		h := v.histograms[key]
+		var cumulative uint64
+		for i, bound := range h.upperBounds {
+			cumulative += h.buckets[i].Load()
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.metricName, v.labelString(key, formatFloat(bound)), cumulative)
+		}
+		count := h.count.Load()
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.metricName, v.labelString(key, "+Inf"), count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.metricName, v.labelString(key, ""), formatFloat(math.Float64frombits(h.sumBits.Load())))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.metricName, v.labelString(key, ""), count)
+	}
+}
+
+// family holds the metadata shared by every metric type.
+type family struct {
+	metricName string
+	help       string
+	kind       string
+	labels     []string
+}
+
+func (f *family) name() string {
+	return f.metricName
+}
+
+func (f *family) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.metricName, escapeHelp(f.help), f.metricName, f.kind)
+}
+
+const labelSeparator = "\xff"
+
+func (f *family) key(values []string) string {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.metricName, len(f.labels), len(values)))
+	}
+	return strings.Join(values, labelSeparator)
+}
+
+// labelString renders {a="x",b="y"} for the stored key, appending le when set.
+func (f *family) labelString(key, le string) string {
+	pairs := make([]string, 0, len(f.labels)+1)
+	if len(f.labels) > 0 {
+		for i, value := range strings.Split(key, labelSeparator) {
+			pairs = append(pairs, f.labels[i]+`="`+escapeLabel(value)+`"`)
+		}
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func addFloat(bits *atomic.Uint64, delta float64) {
+	for {
+		old := bits.Load()
+		updated := math.Float64bits(math.Float64frombits(old) + delta)
+		if bits.CompareAndSwap(old, updated) {
+			return
+		}
+	}
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// useRegistry points the package-level constructors at a fresh registry for
+// the duration of the test.
+func useRegistry(t *testing.T) *Registry {
+	t.Helper()
+	previous := Default
+	Default = NewRegistry()
+	t.Cleanup(func() { Default = previous })
+	return Default
+}
+
+func expose(r *Registry) string {
+	var b strings.Builder
+	r.Expose(&b)
+	return b.String()
+}
+
+func assertExposition(t *testing.T, got, want string) {
+	t.Helper()
+	want = strings.TrimLeft(want, "\n")
+	if got != want {
+		t.Errorf("exposition mismatch\n--- got ---\n%s--- want ---\n%s", got, want)
+	}
+}
+
+func TestCounterExposition(t *testing.T) {
+	r := useRegistry(t)
+	requests := NewCounterVec("http_requests_total", "Requests served.", "method", "code")
+	requests.WithLabelValues("POST", "201").Inc()
+	requests.WithLabelValues("GET", "200").Add(2.5)
+	requests.WithLabelValues("GET", "200").Inc()
+	NewCounter("plain_total", "A counter without labels.").Inc()
+
+	assertExposition(t, expose(r), `
+# HELP http_requests_total Requests served.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",code="200"} 3.5
+http_requests_total{method="POST",code="201"} 1
+# HELP plain_total A counter without labels.
+# TYPE plain_total counter
+plain_total 1
+`)
+}
+
+func TestGaugeExposition(t *testing.T) {
+	r := useRegistry(t)
+	g := NewGauge("queue_depth", "Items waiting.")
+	g.Set(10)
+	g.Add(-2.5)
+	NewGaugeFunc("build_info", "Always 1.", func() float64 { return 1 })
+	NewGaugeVec("breaker_state", "Breaker state.", "name").WithLabelValues("redis").Set(math.Inf(1))
+
+	assertExposition(t, expose(r), `
+# HELP breaker_state Breaker state.
+# TYPE breaker_state gauge
+breaker_state{name="redis"} +Inf
+# HELP build_info Always 1.
+# TYPE build_info gauge
+build_info 1
+# HELP queue_depth Items waiting.
+# TYPE queue_depth gauge
+queue_depth 7.5
+`)
+}
+
+func TestHistogramExposition(t *testing.T) {
+	r := useRegistry(t)
+	// Buckets are sorted on registration
+	h := NewHistogramVec("op_duration_seconds", "Operation latency.", []float64{1, 0.1, 0.5}, "op")
+	find := h.WithLabelValues("find")
+	find.Observe(0.05)
+	find.Observe(0.1) // on a bound: counted in le="0.1"
+	find.Observe(0.3)
+	find.Observe(7) // above every bound: only in +Inf
+	h.WithLabelValues("insert")
+
+	assertExposition(t, expose(r), `
+# HELP op_duration_seconds Operation latency.
+# TYPE op_duration_seconds histogram
+op_duration_seconds_bucket{op="find",le="0.1"} 2
+op_duration_seconds_bucket{op="find",le="0.5"} 3
+op_duration_seconds_bucket{op="find",le="1"} 3
+op_duration_seconds_bucket{op="find",le="+Inf"} 4
+op_duration_seconds_sum{op="find"} 7.45
+op_duration_seconds_count{op="find"} 4
+op_duration_seconds_bucket{op="insert",le="0.1"} 0
+op_duration_seconds_bucket{op="insert",le="0.5"} 0
+op_duration_seconds_bucket{op="insert",le="1"} 0
+op_duration_seconds_bucket{op="insert",le="+Inf"} 0
+op_duration_seconds_sum{op="insert"} 0
+op_duration_seconds_count{op="insert"} 0
+`)
+}
+
+func TestUnlabelledHistogramExposition(t *testing.T) {
+	r := useRegistry(t)
+	NewHistogram("lookup_seconds", "Lookup latency.", []float64{0.01}).Observe(0.002)
+
+	assertExposition(t, expose(r), `
+# HELP lookup_seconds Lookup latency.
+# TYPE lookup_seconds histogram
+lookup_seconds_bucket{le="0.01"} 1
+lookup_seconds_bucket{le="+Inf"} 1
+lookup_seconds_sum 0.002
+lookup_seconds_count 1
+`)
+}
+
+func TestEscaping(t *testing.T) {
+	r := useRegistry(t)
+	c := NewCounterVec("escaped_total", "Help with a \\ backslash\nand a newline, \"quotes\" kept.", "path")
+	c.WithLabelValues(`C:\media "raw"` + "\nnext").Inc()
+	c.WithLabelValues("").Inc()
+
+	assertExposition(t, expose(r), `
+# HELP escaped_total Help with a \\ backslash\nand a newline, "quotes" kept.
+# TYPE escaped_total counter
+escaped_total{path=""} 1
+escaped_total{path="C:\\media \"raw\"\nnext"} 1
+`)
+}
+
+func TestLabelValueCount(t *testing.T) {
+	useRegistry(t)
+	c := NewCounterVec("labelled_total", "Labelled.", "a", "b")
+	defer func() {
+		if recover() == nil {
+			t.Error("WithLabelValues with too few values did not panic")
+		}
+	}()
+	c.WithLabelValues("only-a")
+}
+
+func TestDuplicateRegistration(t *testing.T) {
+	useRegistry(t)
+	NewCounter("dup_total", "First.")
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice did not panic")
+		}
+	}()
+	NewGauge("dup_total", "Second.")
+}
+
+func TestHandler(t *testing.T) {
+	useRegistry(t)
+	NewCounter("served_total", "Served.").Inc()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	assertExposition(t, rec.Body.String(), `
+# HELP served_total Served.
+# TYPE served_total counter
+served_total 1
+`)
+}
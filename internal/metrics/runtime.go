@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+)
+
+var processStart = time.Now()
+
+func init() {
+	Default.register(&runtimeCollector{})
+}
+
+// runtimeCollector reports Go runtime statistics, reading MemStats once per scrape.
+type runtimeCollector struct{}
+
+func (c *runtimeCollector) name() string {
+	return "go_runtime"
+}
+
+func (c *runtimeCollector) write(w io.Writer) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	gauge := func(name, help string, value float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", name, help, name, name, formatFloat(value))
+	}
+	counter := func(name, help string, value float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %s\n", name, help, name, name, formatFloat(value))
+	}
+
+	fmt.Fprintf(w, "# HELP go_info Information about the Go environment.\n# TYPE go_info gauge\ngo_info{version=%q} 1\n", runtime.Version())
+	gauge("go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine()))
+	gauge("go_sched_gomaxprocs_threads", "The current runtime.GOMAXPROCS setting.", float64(runtime.GOMAXPROCS(0)))
+	gauge("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", float64(m.Alloc))
+	gauge("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", float64(m.HeapInuse))
+	gauge("go_memstats_heap_objects", "Number of allocated objects.", float64(m.HeapObjects))
+	gauge("go_memstats_sys_bytes", "Number of bytes obtained from the system.", float64(m.Sys))
+	counter("go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.", float64(m.TotalAlloc))
+	counter("go_gc_cycles_total", "Number of completed GC cycles.", float64(m.NumGC))
+	counter("go_gc_pause_seconds_total", "Total GC stop-the-world pause time.", float64(m.PauseTotalNs)/1e9)
+	gauge("process_start_time_seconds", "Start time of the process since unix epoch in seconds.", float64(processStart.Unix()))
+}
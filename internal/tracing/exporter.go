@@ -0,0 +1,283 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogExporter writes each finished span as a structured log line.
+type LogExporter struct {
+	logger *slog.Logger
+}
+
+func NewLogExporter(logger *slog.Logger) *LogExporter {
+	return &LogExporter{logger: logger}
+}
+
+func (e *LogExporter) Export(span SpanData) {
+	attrs := []any{
+		"trace_id", span.SpanContext.TraceID.String(),
+		"span_id", span.SpanContext.SpanID.String(),
+		"duration_ms", float64(span.End.Sub(span.Start).Microseconds()) / 1000,
+	}
+	if span.ParentSpanID.IsValid() {
+		attrs = append(attrs, "parent_span_id", span.ParentSpanID.String())
+	}
+	if span.Err != nil {
+		attrs = append(attrs, "error", span.Err.Error())
+	}
+	if len(span.Attributes) > 0 {
+		group := make([]any, len(span.Attributes))
+		for i, a := range span.Attributes {
+			group[i] = a
+		}
+		attrs = append(attrs, slog.Group("attributes", group...))
+	}
+	e.logger.Debug("span "+span.Name, attrs...)
+}
+
+func (e *LogExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+const (
+	otlpBatchSize     = 512
+	otlpQueueSize     = 2048
+	otlpFlushInterval = 5 * time.Second
+)
+
+// OTLPExporter batches spans and sends them to an OTLP/HTTP collector using the
+// JSON encoding, so any OpenTelemetry collector (or a local stand-in) can receive them.
+type OTLPExporter struct {
+	endpoint    string
+	serviceName string
+	client      *http.Client
+	queue       chan SpanData
+	done        chan struct{}
+
+	// mu guards closed, so Export never sends on the queue once Shutdown has
+	// closed it; spans ending after shutdown are dropped.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewOTLPExporter starts the background sender. endpoint is the collector base URL,
+// e.g. http://localhost:4318; spans are posted to <endpoint>/v1/traces.
+func NewOTLPExporter(endpoint, serviceName string) *OTLPExporter {
+	e := &OTLPExporter{
+		endpoint:    strings.TrimRight(endpoint, "/") + "/v1/traces",
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		queue:       make(chan SpanData, otlpQueueSize),
+		done:        make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// Export never blocks the request path; spans are dropped if the queue is full
+// or the exporter has been shut down.
+func (e *OTLPExporter) Export(span SpanData) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed {
+		return
+	}
+	select {
+	case e.queue <- span:
+	default:
+		slog.Warn("trace export queue full, dropping span", "span", span.Name)
+	}
+}
+
+// Shutdown flushes queued spans and stops the sender.
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.queue)
+	}
+	e.mu.Unlock()
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *OTLPExporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, otlpBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil {
+			slog.Warn("failed to export spans", "count", len(batch), "error", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span, ok := <-e.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, span)
+			if len(batch) >= otlpBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (e *OTLPExporter) send(batch []SpanData) error {
+	spans := make([]otlpSpan, len(batch))
+	for i, s := range batch {
+		spans[i] = toOTLP(s)
+	}
+
+	payload := otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpKeyValue{
+			{Key: "service.name", Value: otlpValue{StringValue: &e.serviceName}},
+		}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: e.serviceName},
+			Spans: spans,
+		}},
+	}}}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// OTLP/JSON wire types (opentelemetry-proto, trace/v1). IDs are hex encoded.
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	TraceState        string         `json:"traceState,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+func toOTLP(s SpanData) otlpSpan {
+	span := otlpSpan{
+		TraceID:           s.SpanContext.TraceID.String(),
+		SpanID:            s.SpanContext.SpanID.String(),
+		TraceState:        s.SpanContext.TraceState,
+		Name:              s.Name,
+		Kind:              s.Kind,
+		StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+		Status:            otlpStatus{Code: otlpStatusOK},
+	}
+	if s.ParentSpanID.IsValid() {
+		span.ParentSpanID = s.ParentSpanID.String()
+	}
+	if s.Err != nil {
+		span.Status = otlpStatus{Code: otlpStatusError, Message: s.Err.Error()}
+	}
+	for _, a := range s.Attributes {
+		span.Attributes = append(span.Attributes, otlpKeyValue{Key: a.Key, Value: toOTLPValue(a.Value)})
+	}
+	return span
+}
+
+func toOTLPValue(v slog.Value) otlpValue {
+	switch v.Kind() {
+	case slog.KindInt64:
+		s := strconv.FormatInt(v.Int64(), 10)
+		return otlpValue{IntValue: &s}
+	case slog.KindUint64:
+		s := strconv.FormatUint(v.Uint64(), 10)
+		return otlpValue{IntValue: &s}
+	case slog.KindFloat64:
+		f := v.Float64()
+		return otlpValue{DoubleValue: &f}
+	case slog.KindBool:
+		b := v.Bool()
+		return otlpValue{BoolValue: &b}
+	default:
+		s := v.String()
+		return otlpValue{StringValue: &s}
+	}
+}
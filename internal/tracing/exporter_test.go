@@ -0,0 +1,108 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector is a stand-in OTLP/HTTP collector that counts the spans it receives.
+type collector struct {
+	mu    sync.Mutex
+	spans int
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req otlpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans += len(ss.Spans)
+		}
+	}
+}
+
+func (c *collector) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.spans
+}
+
+func testSpan(name string) SpanData {
+	now := time.Now()
+	return SpanData{
+		Name:        name,
+		Kind:        KindInternal,
+		SpanContext: SpanContext{TraceID: TraceID{1}, SpanID: SpanID{1}},
+		Start:       now,
+		End:         now,
+	}
+}
+
+func TestOTLPExporterShutdownFlushes(t *testing.T) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	e := NewOTLPExporter(server.URL, "media-service")
+	e.Export(testSpan("a"))
+	e.Export(testSpan("b"))
+
+	if err := e.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if got := c.count(); got != 2 {
+		t.Errorf("collector received %d spans, want 2", got)
+	}
+}
+
+func TestOTLPExporterExportAfterShutdown(t *testing.T) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	e := NewOTLPExporter(server.URL, "media-service")
+	if err := e.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	// Must not panic with "send on closed channel"
+	e.Export(testSpan("late"))
+
+	if err := e.Shutdown(context.Background()); err != nil {
+		t.Errorf("second Shutdown() error = %v", err)
+	}
+	if got := c.count(); got != 0 {
+		t.Errorf("collector received %d spans after shutdown, want 0", got)
+	}
+}
+
+func TestOTLPExporterExportDuringShutdown(t *testing.T) {
+	server := httptest.NewServer(&collector{})
+	defer server.Close()
+
+	e := NewOTLPExporter(server.URL, "media-service")
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				e.Export(testSpan("concurrent"))
+			}
+		}()
+	}
+	if err := e.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	wg.Wait()
+}
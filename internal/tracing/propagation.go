@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+
+	flagSampled = 0x01
+)
+
+type TraceID [16]byte
+
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (t TraceID) IsValid() bool  { return t != TraceID{} }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+func (s SpanID) IsValid() bool   { return s != SpanID{} }
+
+// SpanContext is the portion of a span that crosses process boundaries.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+	Remote     bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags&flagSampled != 0
+}
+
+// Traceparent formats sc as a W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{sc.Flags})
+}
+
+var errInvalidTraceparent = errors.New("invalid traceparent")
+
+// ParseTraceparent parses a W3C traceparent header. Unknown future versions are
+// accepted as long as the version 00 fields are well formed.
+func ParseTraceparent(value string) (SpanContext, error) {
+	value = strings.TrimSpace(value)
+	parts := strings.Split(value, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, errInvalidTraceparent
+	}
+
+	var sc SpanContext
+	if len(parts[1]) != 32 || !isLowerHex(parts[1]) {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	hex.Decode(sc.TraceID[:], []byte(parts[1]))
+
+	if len(parts[2]) != 16 || !isLowerHex(parts[2]) {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	hex.Decode(sc.SpanID[:], []byte(parts[2]))
+
+	if len(parts[3]) != 2 || !isLowerHex(parts[3]) {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	var flags [1]byte
+	hex.Decode(flags[:], []byte(parts[3]))
+	sc.Flags = flags[0]
+
+	if !sc.IsValid() {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	sc.Remote = true
+	return sc, nil
+}
+
+// Extract reads the trace context of an incoming request, if any.
+func Extract(h http.Header) (SpanContext, bool) {
+	sc, err := ParseTraceparent(h.Get(TraceparentHeader))
+	if err != nil {
+		return SpanContext{}, false
+	}
+	sc.TraceState = h.Get(TracestateHeader)
+	return sc, true
+}
+
+// Inject writes the trace context of the current span into outgoing request headers.
+func Inject(sc SpanContext, h http.Header) {
+	if !sc.IsValid() {
+		return
+	}
+	h.Set(TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		h.Set(TracestateHeader, sc.TraceState)
+	}
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
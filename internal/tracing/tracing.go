@@ -0,0 +1,154 @@
+// Package tracing implements W3C trace context propagation and lightweight spans
+// exported through a pluggable Exporter.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type SpanKind int
+
+// Values match the OTLP SpanKind enumeration.
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// SpanData is the immutable record handed to exporters when a span ends.
+type SpanData struct {
+	Name         string
+	Kind         SpanKind
+	SpanContext  SpanContext
+	ParentSpanID SpanID
+	Start        time.Time
+	End          time.Time
+	Attributes   []slog.Attr
+	Err          error
+}
+
+// Exporter receives finished, sampled spans.
+type Exporter interface {
+	Export(span SpanData)
+	Shutdown(ctx context.Context) error
+}
+
+type Span struct {
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+	record bool
+}
+
+type spanKey struct{}
+
+var exporter atomic.Pointer[exporterHolder]
+
+type exporterHolder struct {
+	Exporter
+}
+
+// SetExporter installs the process-wide exporter. A nil exporter disables export.
+func SetExporter(e Exporter) {
+	if e == nil {
+		exporter.Store(nil)
+		return
+	}
+	exporter.Store(&exporterHolder{e})
+}
+
+// Start creates a child of the span in ctx (or of a remote parent installed with
+// ContextWithRemoteParent) and returns a context carrying the new span.
+func Start(ctx context.Context, name string, kind SpanKind, attrs ...slog.Attr) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+
+	sc := SpanContext{Flags: flagSampled}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Flags = parent.Flags
+		sc.TraceState = parent.TraceState
+	} else {
+		rand.Read(sc.TraceID[:])
+	}
+	rand.Read(sc.SpanID[:])
+
+	span := &Span{
+		data: SpanData{
+			Name:         name,
+			Kind:         kind,
+			SpanContext:  sc,
+			ParentSpanID: parent.SpanID,
+			Start:        time.Now(),
+			Attributes:   attrs,
+		},
+		record: sc.IsSampled() && exporter.Load() != nil,
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// ContextWithRemoteParent makes sc the parent of spans started from the returned context.
+func ContextWithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanKey{}, &Span{data: SpanData{SpanContext: sc}, ended: true})
+}
+
+// SpanContextFromContext returns the span context of the current span, or a zero value.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span, ok := ctx.Value(spanKey{}).(*Span); ok {
+		return span.data.SpanContext
+	}
+	return SpanContext{}
+}
+
+func (s *Span) SpanContext() SpanContext {
+	return s.data.SpanContext
+}
+
+func (s *Span) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Name = name
+}
+
+func (s *Span) SetAttributes(attrs ...slog.Attr) {
+	if !s.record {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+// RecordError marks the span as failed. Nil errors are ignored.
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Err = err
+}
+
+// End finishes the span and hands it to the exporter. Calling End twice has no effect.
+func (s *Span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if !s.record {
+		return
+	}
+	if holder := exporter.Load(); holder != nil {
+		holder.Export(data)
+	}
+}